@@ -0,0 +1,799 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"maps"
+	"os"
+	"os/exec"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// aggregateNameSeparator joins a server name and one of its tool or prompt
+	// names, as in "<server>__<tool>".
+	aggregateNameSeparator = "__"
+
+	// extraServerTimeout bounds the initialize handshake and list requests to
+	// an additional server.
+	extraServerTimeout = 30 * time.Second
+	// extraServerStopTimeout is how long an additional server may take to exit
+	// after its stdin is closed.
+	extraServerStopTimeout = 5 * time.Second
+	// maxAggregateListPages bounds the pages read from one server for one list.
+	maxAggregateListPages = 100
+
+	methodPing                  = "ping"
+	methodPromptsList           = "prompts/list"
+	methodPromptsGet            = "prompts/get"
+	methodResourcesList         = "resources/list"
+	methodResourceTemplatesList = "resources/templates/list"
+	methodResourcesRead         = "resources/read"
+	methodResourcesSubscribe    = "resources/subscribe"
+	methodResourcesUnsubscribe  = "resources/unsubscribe"
+	methodCompletionComplete    = "completion/complete"
+
+	rpcCodeMethodNotFound = -32601
+)
+
+var (
+	// errExtraServerStopped is returned to requests an additional server
+	// stopped before answering.
+	errExtraServerStopped = errors.New("additional MCP server stopped")
+	// errExtraServerUnavailable is returned for requests routed to an
+	// additional server that did not complete its initialize handshake.
+	errExtraServerUnavailable = errors.New("additional MCP server is not initialized")
+)
+
+var extraServerNamePattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9-]*$`)
+
+// extraServerConfig is a stdio MCP server launched alongside the bundled one.
+type extraServerConfig struct {
+	// Name prefixes the server's tools and prompts, as in "<name>__<tool>".
+	Name    string   `json:"name"`
+	Command string   `json:"command"`
+	Args    []string `json:"args"`
+	// Env is added to the environment the bundled server also receives,
+	// without the GitHub credentials.
+	Env map[string]string `json:"env"`
+}
+
+func validateExtraServers(servers []extraServerConfig) error {
+	seen := make(map[string]bool, len(servers))
+	for i, server := range servers {
+		field := fmt.Sprintf("servers[%d]", i)
+		if !extraServerNamePattern.MatchString(server.Name) {
+			return fmt.Errorf("%s.name: %q must be letters, digits and hyphens", field, server.Name)
+		}
+		if seen[strings.ToLower(server.Name)] {
+			return fmt.Errorf("%s.name: duplicate server %q", field, server.Name)
+		}
+		seen[strings.ToLower(server.Name)] = true
+		if server.Command == "" {
+			return fmt.Errorf("%s.command: required", field)
+		}
+		for key := range server.Env {
+			if key == "" || strings.ContainsAny(key, "=\x00") {
+				return fmt.Errorf("%s.env: invalid variable name %q", field, key)
+			}
+		}
+	}
+
+	return nil
+}
+
+// aggregateList describes how a list method is merged across servers.
+type aggregateList struct {
+	// key holds the items in the list result.
+	key string
+	// capability is the server capability that provides the list.
+	capability string
+	// nameField is prefixed with the server name; empty leaves items as they are.
+	nameField string
+}
+
+var aggregateLists = map[string]aggregateList{
+	methodToolsList:             {key: "tools", capability: "tools", nameField: "name"},
+	methodPromptsList:           {key: "prompts", capability: "prompts", nameField: "name"},
+	methodResourcesList:         {key: "resources", capability: "resources"},
+	methodResourceTemplatesList: {key: "resourceTemplates", capability: "resources"},
+}
+
+// aggregateCapabilities are the server capabilities merged into the
+// initialize result.
+var aggregateCapabilities = []string{"tools", "prompts", "resources", "completions"}
+
+// extraServer is an MCP client for one additional stdio server.
+type extraServer struct {
+	name   string
+	cmd    *exec.Cmd
+	stdin  io.WriteCloser
+	out    *proxyOutput
+	notify func(msg *rpcMessage)
+
+	initOnce sync.Once
+	// ready is closed when the initialize handshake completes or fails.
+	ready  chan struct{}
+	exited chan struct{}
+
+	mu           sync.Mutex
+	pending      map[string]chan *rpcMessage
+	nextID       uint64
+	capabilities map[string]json.RawMessage
+}
+
+func startExtraServer(cfg extraServerConfig, notify func(msg *rpcMessage)) (*extraServer, error) {
+	env := make([]string, 0, len(cfg.Env))
+	for _, key := range slices.Sorted(maps.Keys(cfg.Env)) {
+		env = append(env, key+"="+cfg.Env[key])
+	}
+
+	// #nosec G204 -- additional servers are configured by the user running gh-mcp
+	cmd := exec.Command(cfg.Command, cfg.Args...)
+	cmd.Env = buildChildProcessEnv(env)
+	cmd.Stderr = os.Stderr
+	configureServerParentDeathSignal(cmd)
+
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create stdin pipe for %s: %w", cfg.Name, err)
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create stdout pipe for %s: %w", cfg.Name, err)
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("%w: servers: failed to start %q: %w", errInvalidSetting, cfg.Name, err)
+	}
+
+	s := &extraServer{
+		name:    cfg.Name,
+		cmd:     cmd,
+		stdin:   stdin,
+		out:     &proxyOutput{w: stdin},
+		notify:  notify,
+		ready:   make(chan struct{}),
+		exited:  make(chan struct{}),
+		pending: make(map[string]chan *rpcMessage),
+	}
+	go s.serve(stdout)
+
+	return s, nil
+}
+
+// serve handles the server's output until it closes stdout.
+func (s *extraServer) serve(stdout io.Reader) {
+	defer close(s.exited)
+
+	err := readFrames(stdout, defaultProxyMaxMessageSize, func(line []byte) error {
+		msgs, _, err := parseRPCLine(line)
+		if err != nil {
+			slog.Debug("Ignored output from additional MCP server that is not JSON-RPC", "server", s.name)
+			return nil
+		}
+		for _, msg := range msgs {
+			s.handle(msg)
+		}
+		return nil
+	}, func(_ []byte, last bool) error {
+		if last {
+			slog.Warn("Dropped a message larger than the proxy limit from additional MCP server", "server", s.name)
+		}
+		return nil
+	})
+	if err != nil {
+		slog.Debug("Stopped reading additional MCP server output", "server", s.name, "err", err)
+	}
+}
+
+func (s *extraServer) handle(msg *rpcMessage) {
+	switch {
+	case msg.isResponse():
+		s.mu.Lock()
+		ch, ok := s.pending[msg.idKey()]
+		delete(s.pending, msg.idKey())
+		s.mu.Unlock()
+		if ok {
+			ch <- msg
+		}
+	case msg.isRequest():
+		// The server's requests are not routed to the client, which may
+		// not support them under the capabilities gh-mcp declared.
+		resp := newRPCResponse(msg.ID, json.RawMessage(`{}`))
+		if msg.Method != methodPing {
+			resp, _ = newRPCErrorResponse(msg.ID, rpcCodeMethodNotFound,
+				"gh-mcp does not forward "+msg.Method+" from additional servers", nil)
+		}
+		if err := s.send(resp); err != nil {
+			slog.Debug("Failed to answer additional MCP server", "server", s.name, "method", msg.Method, "err", err)
+		}
+	case msg.Method != methodCancelled:
+		// Cancellations refer to request IDs only this connection knows.
+		s.notify(msg)
+	}
+}
+
+func (s *extraServer) send(msg *rpcMessage) error {
+	data, err := msg.bytes()
+	if err != nil {
+		return err
+	}
+
+	return s.out.write(data)
+}
+
+// request sends a request and waits for its response. When ctx ends first,
+// the server is told the request was cancelled.
+func (s *extraServer) request(ctx context.Context, method string, params any) (*rpcMessage, error) {
+	s.mu.Lock()
+	s.nextID++
+	id := json.RawMessage(strconv.Quote(proxyRequestIDPrefix + strconv.FormatUint(s.nextID, 10)))
+	ch := make(chan *rpcMessage, 1)
+	s.pending[string(id)] = ch
+	s.mu.Unlock()
+	defer func() {
+		s.mu.Lock()
+		delete(s.pending, string(id))
+		s.mu.Unlock()
+	}()
+
+	req, err := newRPCRequest(id, method, params)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.send(req); err != nil {
+		return nil, err
+	}
+
+	select {
+	case resp := <-ch:
+		return resp, nil
+	case <-s.exited:
+		return nil, fmt.Errorf("%w: %s", errExtraServerStopped, s.name)
+	case <-ctx.Done():
+		if cancel, err := newRPCRequest(nil, methodCancelled, map[string]any{"requestId": id}); err == nil {
+			_ = s.send(cancel)
+		}
+		return nil, fmt.Errorf("no response from %s to %s: %w", s.name, method, context.Cause(ctx))
+	}
+}
+
+// initialize performs the handshake once, with the client's initialize
+// params but no client capabilities, since the server's requests are not
+// routed to the client.
+func (s *extraServer) initialize(clientParams json.RawMessage) {
+	s.initOnce.Do(func() {
+		go func() {
+			defer close(s.ready)
+
+			var params map[string]json.RawMessage
+			if err := json.Unmarshal(clientParams, &params); err != nil || params == nil {
+				params = make(map[string]json.RawMessage)
+			}
+			params["capabilities"] = json.RawMessage(`{}`)
+
+			ctx, cancel := context.WithTimeout(context.Background(), extraServerTimeout)
+			defer cancel()
+			resp, err := s.request(ctx, methodInitialize, params)
+			if err == nil && resp.Error != nil {
+				err = fmt.Errorf("%w: %s", errExtraServerUnavailable, resp.Error.Message)
+			}
+			var result struct {
+				Capabilities map[string]json.RawMessage `json:"capabilities"`
+			}
+			if err == nil {
+				err = json.Unmarshal(resp.Result, &result)
+			}
+			if err != nil {
+				slog.Warn("Additional MCP server failed to initialize", "server", s.name, "err", err)
+				return
+			}
+
+			initialized, _ := newRPCRequest(nil, methodInitialized, nil)
+			if err := s.send(initialized); err != nil {
+				slog.Warn("Additional MCP server failed to initialize", "server", s.name, "err", err)
+				return
+			}
+
+			s.mu.Lock()
+			s.capabilities = result.Capabilities
+			if s.capabilities == nil {
+				s.capabilities = make(map[string]json.RawMessage)
+			}
+			s.mu.Unlock()
+			slog.Info("🔗 Additional MCP server ready", "server", s.name)
+		}()
+	})
+}
+
+// capabilitiesWhenReady waits for the handshake and returns the server's
+// capabilities, or nil when it did not initialize.
+func (s *extraServer) capabilitiesWhenReady(ctx context.Context) map[string]json.RawMessage {
+	select {
+	case <-s.ready:
+	case <-s.exited:
+	case <-ctx.Done():
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.capabilities
+}
+
+// stop closes the server's stdin and kills it if it does not exit in time.
+func (s *extraServer) stop() {
+	_ = s.stdin.Close()
+
+	select {
+	case <-s.exited:
+	case <-time.After(extraServerStopTimeout):
+		slog.Warn("Additional MCP server did not exit after stdin closed; killing it", "server", s.name)
+		_ = s.cmd.Process.Kill()
+	}
+	_ = s.cmd.Wait()
+}
+
+// serverAggregator presents additional stdio servers and the bundled server
+// to the client as one. Their tools and prompts are listed with the server
+// name as prefix, their resources as they are, and requests naming them are
+// routed to them instead of the bundled server. It is the innermost
+// middleware, so every policy applies to the additional servers as well.
+type serverAggregator struct {
+	servers []*extraServer
+	byName  map[string]*extraServer
+	proxy   atomic.Pointer[stdioProxy]
+
+	mu sync.Mutex
+	// resources and templates remember where listed resources come from.
+	resources map[string]*extraServer
+	templates []resourceTemplateRoute
+}
+
+// resourceTemplateRoute routes URIs starting with prefix, the literal part of
+// a URI template, to server.
+type resourceTemplateRoute struct {
+	prefix string
+	server *extraServer
+}
+
+// newServerAggregator starts every configured server.
+func newServerAggregator(configs []extraServerConfig) (*serverAggregator, error) {
+	a := &serverAggregator{
+		byName:    make(map[string]*extraServer, len(configs)),
+		resources: make(map[string]*extraServer),
+	}
+	for _, cfg := range configs {
+		server, err := startExtraServer(cfg, a.notifyClient)
+		if err != nil {
+			a.close()
+			return nil, err
+		}
+		a.servers = append(a.servers, server)
+		a.byName[cfg.Name] = server
+	}
+
+	return a, nil
+}
+
+func (a *serverAggregator) close() {
+	var wg sync.WaitGroup
+	for _, server := range a.servers {
+		wg.Go(server.stop)
+	}
+	wg.Wait()
+}
+
+// notifyClient forwards a notification from an additional server.
+func (a *serverAggregator) notifyClient(msg *rpcMessage) {
+	p := a.proxy.Load()
+	if p == nil {
+		return
+	}
+
+	if err := p.toClient(context.Background(), msg); err != nil {
+		slog.Debug("Failed to forward notification from additional MCP server", "method", msg.Method, "err", err)
+	}
+}
+
+func (a *serverAggregator) clientToServer(p *stdioProxy, next proxyHandler) proxyHandler {
+	a.proxy.Store(p)
+
+	return func(ctx context.Context, msg *rpcMessage) error {
+		if !msg.isRequest() {
+			return next(ctx, msg)
+		}
+		if msg.Method == methodInitialize {
+			for _, server := range a.servers {
+				server.initialize(msg.Params)
+			}
+			return next(ctx, msg)
+		}
+
+		server, params := a.target(msg)
+		if server == nil {
+			return next(ctx, msg)
+		}
+
+		// The answer may take a while; keep reading client input meanwhile.
+		go a.forward(ctx, p, msg, server, params)
+
+		return nil
+	}
+}
+
+func (a *serverAggregator) serverToClient(_ *stdioProxy, next proxyHandler) proxyHandler {
+	return func(ctx context.Context, msg *rpcMessage) error {
+		if !msg.isResponse() || msg.call == nil {
+			return next(ctx, msg)
+		}
+
+		method := msg.call.request.Method
+		if list, ok := aggregateLists[method]; ok && (lastListPage(msg) || isMethodNotFound(msg)) {
+			go a.mergeList(ctx, msg, list, next)
+			return nil
+		}
+		if method == methodInitialize && msg.Error == nil {
+			go a.mergeInitialize(ctx, msg, next)
+			return nil
+		}
+
+		return next(ctx, msg)
+	}
+}
+
+// target returns the additional server a client request is for, with the
+// params to send it, or nil when the request is for the bundled server.
+func (a *serverAggregator) target(msg *rpcMessage) (*extraServer, json.RawMessage) {
+	var params map[string]json.RawMessage
+	if err := json.Unmarshal(msg.Params, &params); err != nil {
+		return nil, nil
+	}
+
+	switch msg.Method {
+	case methodToolsCall, methodPromptsGet:
+		if server, ok := a.renameTarget(params, "name"); ok {
+			return server, a.encodeParams(params)
+		}
+	case methodResourcesRead, methodResourcesSubscribe, methodResourcesUnsubscribe:
+		var uri string
+		_ = json.Unmarshal(params["uri"], &uri)
+		if server := a.resourceServer(uri); server != nil {
+			return server, msg.Params
+		}
+	case methodCompletionComplete:
+		var ref map[string]json.RawMessage
+		if json.Unmarshal(params["ref"], &ref) != nil {
+			return nil, nil
+		}
+		var uri string
+		_ = json.Unmarshal(ref["uri"], &uri)
+		if server, ok := a.renameTarget(ref, "name"); ok {
+			params["ref"], _ = json.Marshal(ref)
+			return server, a.encodeParams(params)
+		}
+		if server := a.resourceServer(uri); server != nil {
+			return server, msg.Params
+		}
+	}
+
+	return nil, nil
+}
+
+// renameTarget strips the server prefix from fields[field] and returns the
+// server it names.
+func (a *serverAggregator) renameTarget(fields map[string]json.RawMessage, field string) (*extraServer, bool) {
+	var name string
+	if json.Unmarshal(fields[field], &name) != nil {
+		return nil, false
+	}
+	prefix, local, ok := strings.Cut(name, aggregateNameSeparator)
+	server := a.byName[prefix]
+	if !ok || server == nil {
+		return nil, false
+	}
+	fields[field], _ = json.Marshal(local)
+
+	return server, true
+}
+
+func (a *serverAggregator) encodeParams(params map[string]json.RawMessage) json.RawMessage {
+	data, _ := json.Marshal(params)
+	return data
+}
+
+func (a *serverAggregator) resourceServer(uri string) *extraServer {
+	if uri == "" {
+		return nil
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if server, ok := a.resources[uri]; ok {
+		return server
+	}
+	for _, route := range a.templates {
+		if strings.HasPrefix(uri, route.prefix) {
+			return route.server
+		}
+	}
+
+	return nil
+}
+
+// forward answers msg with the additional server's response.
+func (a *serverAggregator) forward(
+	ctx context.Context,
+	p *stdioProxy,
+	msg *rpcMessage,
+	server *extraServer,
+	params json.RawMessage,
+) {
+	callCtx, cancel := msg.call.context(ctx)
+	defer cancel()
+
+	readyCtx, cancelReady := context.WithTimeout(callCtx, extraServerTimeout)
+	defer cancelReady()
+
+	var resp *rpcMessage
+	err := errExtraServerUnavailable
+	if server.capabilitiesWhenReady(readyCtx) != nil {
+		resp, err = server.request(callCtx, msg.Method, params)
+	}
+	if err != nil {
+		slog.WarnContext(ctx, "Additional MCP server request failed", "server", server.name, "method", msg.Method, "err", err)
+		if err := p.replyError(ctx, msg, rpcCodeInternalError, err.Error(), nil); err != nil {
+			slog.DebugContext(ctx, "Failed to answer client request", "method", msg.Method, "err", err)
+		}
+		return
+	}
+
+	resp.ID = msg.ID
+	resp.modified = true
+	resp.call = msg.call
+	if err := p.toClient(ctx, resp); err != nil {
+		slog.DebugContext(ctx, "Failed to answer client request", "method", msg.Method, "err", err)
+	}
+}
+
+// lastListPage reports whether a list response is the last page, where
+// items the bundled server does not know about are added.
+func lastListPage(msg *rpcMessage) bool {
+	if msg.Error != nil {
+		return false
+	}
+
+	var page struct {
+		NextCursor string `json:"nextCursor"`
+	}
+
+	return json.Unmarshal(msg.Result, &page) == nil && page.NextCursor == ""
+}
+
+// isMethodNotFound reports whether the bundled server does not implement the
+// request msg answers, as when it has no prompts.
+func isMethodNotFound(msg *rpcMessage) bool {
+	return msg.Error != nil && msg.Error.Code == rpcCodeMethodNotFound
+}
+
+// mergeList appends the additional servers' items to the bundled server's
+// last page of a list.
+func (a *serverAggregator) mergeList(ctx context.Context, msg *rpcMessage, list aggregateList, next proxyHandler) {
+	callCtx, cancel := msg.call.context(ctx)
+	defer cancel()
+	callCtx, cancelTimeout := context.WithTimeout(callCtx, extraServerTimeout)
+	defer cancelTimeout()
+
+	var extra []json.RawMessage
+	for _, server := range a.servers {
+		if _, ok := server.capabilitiesWhenReady(callCtx)[list.capability]; !ok {
+			continue
+		}
+		items, err := a.fetchList(callCtx, server, msg.call.request.Method, list)
+		if err != nil {
+			slog.WarnContext(ctx, "Failed to list from additional MCP server",
+				"server", server.name, "method", msg.call.request.Method, "err", err)
+			continue
+		}
+		extra = append(extra, items...)
+	}
+
+	if len(extra) > 0 {
+		if err := appendListItems(msg, list.key, extra); err != nil {
+			slog.WarnContext(ctx, "Failed to merge additional MCP server lists", "method", msg.call.request.Method, "err", err)
+		}
+	}
+	if err := next(ctx, msg); err != nil {
+		slog.DebugContext(ctx, "Failed to forward list response", "method", msg.call.request.Method, "err", err)
+	}
+}
+
+// fetchList reads every page of a list from server, prefixing item names and
+// remembering resources for routing.
+func (a *serverAggregator) fetchList(
+	ctx context.Context,
+	server *extraServer,
+	method string,
+	list aggregateList,
+) ([]json.RawMessage, error) {
+	var (
+		items  []json.RawMessage
+		listed []map[string]json.RawMessage
+		cursor string
+	)
+	for range maxAggregateListPages {
+		var params any
+		if cursor != "" {
+			params = map[string]string{"cursor": cursor}
+		}
+		resp, err := server.request(ctx, method, params)
+		if err != nil {
+			return nil, err
+		}
+		if resp.Error != nil {
+			return nil, fmt.Errorf("%w: %s", errExtraServerUnavailable, resp.Error.Message)
+		}
+
+		var result map[string]json.RawMessage
+		var page []map[string]json.RawMessage
+		if err := json.Unmarshal(resp.Result, &result); err != nil {
+			return nil, fmt.Errorf("failed to decode %s result: %w", method, err)
+		}
+		if err := json.Unmarshal(result[list.key], &page); err != nil {
+			return nil, fmt.Errorf("failed to decode %s result: %w", method, err)
+		}
+		cursor = ""
+		_ = json.Unmarshal(result["nextCursor"], &cursor)
+
+		for _, item := range page {
+			listed = append(listed, item)
+			if list.nameField != "" {
+				var name string
+				_ = json.Unmarshal(item[list.nameField], &name)
+				item[list.nameField], _ = json.Marshal(server.name + aggregateNameSeparator + name)
+			}
+			data, err := json.Marshal(item)
+			if err != nil {
+				return nil, fmt.Errorf("failed to encode %s item: %w", method, err)
+			}
+			items = append(items, data)
+		}
+
+		if cursor == "" {
+			break
+		}
+	}
+	a.learnRoutes(server, method, listed)
+
+	return items, nil
+}
+
+// learnRoutes replaces the routes server had for the resources or resource
+// templates it listed, so ones it no longer lists stop being routed to it.
+// Other lists are ignored.
+func (a *serverAggregator) learnRoutes(server *extraServer, method string, items []map[string]json.RawMessage) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	switch method {
+	case methodResourcesList:
+		maps.DeleteFunc(a.resources, func(_ string, s *extraServer) bool { return s == server })
+		for _, item := range items {
+			var uri string
+			if json.Unmarshal(item["uri"], &uri) == nil && uri != "" {
+				a.resources[uri] = server
+			}
+		}
+	case methodResourceTemplatesList:
+		a.templates = slices.DeleteFunc(a.templates, func(route resourceTemplateRoute) bool {
+			return route.server == server
+		})
+		for _, item := range items {
+			var template string
+			_ = json.Unmarshal(item["uriTemplate"], &template)
+			if prefix, _, _ := strings.Cut(template, "{"); prefix != "" {
+				a.templates = append(a.templates, resourceTemplateRoute{prefix: prefix, server: server})
+			}
+		}
+	}
+}
+
+// appendListItems adds items to the list under key in msg's result. An error
+// response becomes a result holding just the items.
+func appendListItems(msg *rpcMessage, key string, items []json.RawMessage) error {
+	result := make(map[string]json.RawMessage)
+	if msg.Error == nil {
+		if err := json.Unmarshal(msg.Result, &result); err != nil {
+			return fmt.Errorf("failed to decode list result: %w", err)
+		}
+	}
+
+	var merged []json.RawMessage
+	if raw, ok := result[key]; ok {
+		if err := json.Unmarshal(raw, &merged); err != nil {
+			return fmt.Errorf("failed to decode list result: %w", err)
+		}
+	}
+	merged = append(merged, items...)
+
+	encoded, err := json.Marshal(merged)
+	if err != nil {
+		return fmt.Errorf("failed to encode list result: %w", err)
+	}
+	result[key] = encoded
+	msg.Error = nil
+
+	return msg.setResult(result)
+}
+
+// mergeInitialize adds the capabilities of the additional servers to the
+// bundled server's initialize result.
+func (a *serverAggregator) mergeInitialize(ctx context.Context, msg *rpcMessage, next proxyHandler) {
+	waitCtx, cancel := context.WithTimeout(ctx, extraServerTimeout)
+	defer cancel()
+
+	var result map[string]json.RawMessage
+	var capabilities map[string]json.RawMessage
+	err := json.Unmarshal(msg.Result, &result)
+	if err == nil {
+		_ = json.Unmarshal(result["capabilities"], &capabilities)
+		if capabilities == nil {
+			capabilities = make(map[string]json.RawMessage)
+		}
+		for _, server := range a.servers {
+			extra := server.capabilitiesWhenReady(waitCtx)
+			for _, name := range aggregateCapabilities {
+				if value, ok := extra[name]; ok {
+					capabilities[name] = mergeCapability(capabilities[name], value)
+				}
+			}
+		}
+		result["capabilities"], err = json.Marshal(capabilities)
+	}
+	if err == nil {
+		err = msg.setResult(result)
+	}
+	if err != nil {
+		slog.WarnContext(ctx, "Failed to merge additional MCP server capabilities", "err", err)
+	}
+
+	if err := next(ctx, msg); err != nil {
+		slog.DebugContext(ctx, "Failed to forward initialize response", "err", err)
+	}
+}
+
+// mergeCapability combines two capability objects. Flags such as
+// listChanged are set when either side sets them.
+func mergeCapability(base, extra json.RawMessage) json.RawMessage {
+	var merged, add map[string]json.RawMessage
+	_ = json.Unmarshal(base, &merged)
+	if json.Unmarshal(extra, &add) != nil {
+		return base
+	}
+	if merged == nil {
+		merged = make(map[string]json.RawMessage)
+	}
+	for key, value := range add {
+		if _, ok := merged[key]; !ok || string(value) == "true" {
+			merged[key] = value
+		}
+	}
+
+	data, err := json.Marshal(merged)
+	if err != nil {
+		return base
+	}
+
+	return data
+}
@@ -0,0 +1,191 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+)
+
+func newTestServerAggregator(t *testing.T) *serverAggregator {
+	t.Helper()
+
+	aggregator, err := newServerAggregator([]extraServerConfig{{
+		Name:    "helper",
+		Command: os.Args[0],
+		Args:    []string{"-test.run=^TestExtraServerHelper$"},
+		Env:     map[string]string{"GO_WANT_EXTRA_SERVER_HELPER": "1"},
+	}})
+	if err != nil {
+		t.Fatalf("newServerAggregator failed: %v", err)
+	}
+	t.Cleanup(aggregator.close)
+
+	return aggregator
+}
+
+func TestServerAggregatorMergesAndRoutes(t *testing.T) {
+	proxy, server, client := newTestProxy(t, defaultProxyMaxMessageSize, newTestServerAggregator(t))
+	ctx := context.Background()
+	answer := func(line string) {
+		t.Helper()
+		if err := proxy.handleServerLine(ctx, []byte(line+"\n")); err != nil {
+			t.Fatalf("handleServerLine failed: %v", err)
+		}
+	}
+
+	proxy.handleClientLine(ctx, []byte(`{"jsonrpc":"2.0","id":1,"method":"initialize","params":`+
+		`{"protocolVersion":"2025-06-18","capabilities":{"elicitation":{}},"clientInfo":{"name":"test","version":"1"}}}`))
+	answer(`{"jsonrpc":"2.0","id":1,"result":{"protocolVersion":"2025-06-18","capabilities":{"tools":{}},` +
+		`"serverInfo":{"name":"github-mcp-server","version":"1"}}}`)
+	waitForString(t, client.String, `"capabilities":{"resources":{"subscribe":true},"tools":{"listChanged":true}}`)
+	waitForString(t, client.String, `"method":"notifications/tools/list_changed"`)
+
+	proxy.handleClientLine(ctx, []byte(`{"jsonrpc":"2.0","id":2,"method":"tools/list"}`))
+	answer(`{"jsonrpc":"2.0","id":2,"result":{"tools":[{"name":"get_me"}]}}`)
+	waitForString(t, client.String, `"tools":[{"name":"get_me"},{"name":"helper__echo"},{"name":"helper__second_page"}]`)
+
+	proxy.handleClientLine(ctx, []byte(`{"jsonrpc":"2.0","id":3,"method":"tools/call",`+
+		`"params":{"name":"helper__echo","arguments":{"text":"hi"}}}`))
+	waitForString(t, client.String, `{"jsonrpc":"2.0","id":3,"result":{"content":[{"text":"echo:{\"text\":\"hi\"}","type":"text"}]}}`)
+
+	proxy.handleClientLine(ctx, []byte(`{"jsonrpc":"2.0","id":4,"method":"resources/list"}`))
+	answer(`{"jsonrpc":"2.0","id":4,"error":{"code":-32601,"message":"Method not found"}}`)
+	waitForString(t, client.String, `{"jsonrpc":"2.0","id":4,"result":{"resources":[{"name":"readme","uri":"helper://readme"}]}}`)
+
+	proxy.handleClientLine(ctx, []byte(`{"jsonrpc":"2.0","id":5,"method":"resources/read","params":{"uri":"helper://readme"}}`))
+	waitForString(t, client.String, `"id":5,"result":{"contents":[{"text":"hello","uri":"helper://readme"}]}`)
+
+	got, _ := server.snapshot()
+	for _, id := range []string{`"id":3`, `"id":5`} {
+		if strings.Contains(got, id) {
+			t.Fatalf("bundled server received a request for the additional server (%s):\n%s", id, got)
+		}
+	}
+}
+
+func TestServerAggregatorLeavesBundledRequestsAlone(t *testing.T) {
+	proxy, server, _ := newTestProxy(t, defaultProxyMaxMessageSize, newTestServerAggregator(t))
+
+	line := `{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"other__tool"}}` + "\n"
+	proxy.handleClientLine(context.Background(), []byte(line))
+
+	if got, _ := server.snapshot(); got != line {
+		t.Fatalf("bundled server received %q, want %q", got, line)
+	}
+}
+
+func TestServerAggregatorReplacesRoutesOnEachList(t *testing.T) {
+	wiki, docs := &extraServer{name: "wiki"}, &extraServer{name: "docs"}
+	a := &serverAggregator{resources: make(map[string]*extraServer)}
+	items := func(field string, values ...string) []map[string]json.RawMessage {
+		var out []map[string]json.RawMessage
+		for _, v := range values {
+			raw, _ := json.Marshal(v)
+			out = append(out, map[string]json.RawMessage{field: raw})
+		}
+		return out
+	}
+
+	a.learnRoutes(docs, methodResourceTemplatesList, items("uriTemplate", "docs://{page}"))
+	for range 3 {
+		a.learnRoutes(wiki, methodResourceTemplatesList, items("uriTemplate", "wiki://old/{page}"))
+	}
+	a.learnRoutes(wiki, methodResourceTemplatesList, items("uriTemplate", "wiki://new/{page}"))
+	a.learnRoutes(wiki, methodResourcesList, items("uri", "wiki://readme"))
+	a.learnRoutes(wiki, methodResourcesList, items("uri", "wiki://index"))
+
+	if len(a.templates) != 2 || len(a.resources) != 1 {
+		t.Fatalf("routes grew: %d templates, %d resources", len(a.templates), len(a.resources))
+	}
+	for uri, want := range map[string]*extraServer{
+		"wiki://new/home": wiki,
+		"wiki://old/home": nil,
+		"wiki://index":    wiki,
+		"wiki://readme":   nil,
+		"docs://intro":    docs,
+	} {
+		if got := a.resourceServer(uri); got != want {
+			t.Errorf("resourceServer(%q) = %v, want %v", uri, got, want)
+		}
+	}
+}
+
+func TestParseProxyConfigValidatesServers(t *testing.T) {
+	for _, servers := range []string{
+		`[{"name":"a_b","command":"x"}]`,
+		`[{"name":"a","command":"x"},{"name":"A","command":"y"}]`,
+		`[{"name":"a"}]`,
+		`[{"name":"a","command":"x","env":{"A=B":"c"}}]`,
+	} {
+		if _, err := parseProxyConfig([]byte(`{"servers":` + servers + `}`)); err == nil {
+			t.Errorf("parseProxyConfig accepted servers %s", servers)
+		}
+	}
+}
+
+// TestExtraServerHelper is a small stdio MCP server for the aggregator tests.
+func TestExtraServerHelper(*testing.T) {
+	if os.Getenv("GO_WANT_EXTRA_SERVER_HELPER") != "1" {
+		return
+	}
+
+	out := bufio.NewWriter(os.Stdout)
+	send := func(v any) {
+		data, _ := json.Marshal(v)
+		_, _ = out.Write(append(data, '\n'))
+		_ = out.Flush()
+	}
+	respond := func(id json.RawMessage, result any) {
+		send(map[string]any{"jsonrpc": jsonRPCVersion, "id": id, "result": result})
+	}
+
+	scanner := bufio.NewScanner(os.Stdin)
+	for scanner.Scan() {
+		var msg struct {
+			ID     json.RawMessage `json:"id"`
+			Method string          `json:"method"`
+			Params struct {
+				Capabilities map[string]any  `json:"capabilities"`
+				Cursor       string          `json:"cursor"`
+				Name         string          `json:"name"`
+				Arguments    json.RawMessage `json:"arguments"`
+				URI          string          `json:"uri"`
+			} `json:"params"`
+		}
+		if json.Unmarshal(scanner.Bytes(), &msg) != nil {
+			continue
+		}
+
+		switch msg.Method {
+		case methodInitialize:
+			if len(msg.Params.Capabilities) != 0 {
+				os.Exit(3)
+			}
+			respond(msg.ID, map[string]any{
+				"protocolVersion": "2025-06-18",
+				"capabilities":    map[string]any{"tools": map[string]any{"listChanged": true}, "resources": map[string]any{"subscribe": true}},
+				"serverInfo":      map[string]any{"name": "helper", "version": "1"},
+			})
+		case methodInitialized:
+			send(map[string]any{"jsonrpc": jsonRPCVersion, "method": "notifications/tools/list_changed"})
+		case methodToolsList:
+			if msg.Params.Cursor == "" {
+				respond(msg.ID, map[string]any{"tools": []any{map[string]any{"name": "echo"}}, "nextCursor": "2"})
+			} else {
+				respond(msg.ID, map[string]any{"tools": []any{map[string]any{"name": "second_page"}}})
+			}
+		case methodToolsCall:
+			text := fmt.Sprintf("%s:%s", msg.Params.Name, msg.Params.Arguments)
+			respond(msg.ID, map[string]any{"content": []any{map[string]any{"type": "text", "text": text}}})
+		case methodResourcesList:
+			respond(msg.ID, map[string]any{"resources": []any{map[string]any{"uri": "helper://readme", "name": "readme"}}})
+		case methodResourcesRead:
+			respond(msg.ID, map[string]any{"contents": []any{map[string]any{"uri": msg.Params.URI, "text": "hello"}}})
+		}
+	}
+	os.Exit(0)
+}
@@ -0,0 +1,325 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	auditFileName          = "audit.jsonl"
+	defaultAuditMaxSize    = 10 << 20 // 10 MiB
+	defaultAuditMaxBackups = 5
+
+	// auditMaxStringLength truncates long argument values, such as file contents.
+	auditMaxStringLength = 256
+	auditRedacted        = "[REDACTED]"
+
+	auditStatusOK        = "ok"
+	auditStatusToolError = "tool_error"
+	auditStatusError     = "error"
+	auditStatusDenied    = "denied"
+	auditStatusTimeout   = "timeout"
+	auditStatusCancelled = "cancelled"
+)
+
+// auditConfig enables the tool call audit log.
+type auditConfig struct {
+	Enabled bool `json:"enabled"`
+	// Path defaults to audit.jsonl in the gh-mcp cache directory. Setting it enables the log.
+	Path string `json:"path"`
+	// MaxSize is the size, like "10MiB", at which the log is rotated.
+	MaxSize string `json:"maxSize"`
+	// MaxBackups is how many rotated files are kept.
+	MaxBackups *int `json:"maxBackups"`
+}
+
+func (c auditConfig) enabled() bool {
+	return c.Enabled || c.Path != ""
+}
+
+func (c auditConfig) validate() error {
+	if c.MaxSize != "" {
+		if _, err := parseByteSize(c.MaxSize); err != nil {
+			return fmt.Errorf("audit.maxSize: %w", err)
+		}
+	}
+	if c.MaxBackups != nil && *c.MaxBackups < 0 {
+		return fmt.Errorf("audit.maxBackups: %d must not be negative", *c.MaxBackups)
+	}
+
+	return nil
+}
+
+// auditSensitiveKeys are substrings of argument names whose values are never logged.
+var auditSensitiveKeys = []string{"token", "secret", "password", "authorization", "credential", "api_key", "apikey", "private_key"}
+
+// auditRecord is one line of the audit log.
+type auditRecord struct {
+	Time      time.Time       `json:"time"`
+	Session   string          `json:"session"`
+	Host      string          `json:"host"`
+	Tool      string          `json:"tool"`
+	Arguments json.RawMessage `json:"arguments,omitempty"`
+	Status    string          `json:"status"`
+	ErrorCode int             `json:"errorCode,omitempty"`
+	LatencyMS int64           `json:"latencyMs"`
+}
+
+// auditLog appends a record for every answered tools/call to a JSONL file,
+// rotating it by size. Write failures are logged and never end the session.
+type auditLog struct {
+	passthroughMiddleware
+
+	session proxySession
+
+	mu         sync.Mutex
+	path       string
+	maxSize    int64
+	maxBackups int
+	file       *os.File
+	size       int64
+}
+
+func newAuditLog(cfg auditConfig, session proxySession) (*auditLog, error) {
+	path := cfg.Path
+	if path == "" {
+		dir, err := cacheDir()
+		if err != nil {
+			return nil, err
+		}
+		path = filepath.Join(dir, auditFileName)
+	}
+
+	maxSize := uint64(defaultAuditMaxSize)
+	if cfg.MaxSize != "" {
+		// Validated when the config was loaded.
+		maxSize, _ = parseByteSize(cfg.MaxSize)
+	}
+	maxBackups := defaultAuditMaxBackups
+	if cfg.MaxBackups != nil {
+		maxBackups = *cfg.MaxBackups
+	}
+
+	l := &auditLog{
+		session:    session,
+		path:       path,
+		maxSize:    int64(min(maxSize, uint64(1)<<62)),
+		maxBackups: maxBackups,
+	}
+	if err := l.open(); err != nil {
+		return nil, err
+	}
+
+	return l, nil
+}
+
+func (l *auditLog) open() error {
+	// #nosec G304 -- the audit path is chosen by the user running gh-mcp
+	file, err := os.OpenFile(l.path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o600)
+	if err != nil {
+		return fmt.Errorf("failed to open audit log: %w", err)
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		_ = file.Close()
+		return fmt.Errorf("failed to stat audit log: %w", err)
+	}
+
+	l.file = file
+	l.size = info.Size()
+
+	return nil
+}
+
+func (l *auditLog) close() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.file != nil {
+		_ = l.file.Close()
+		l.file = nil
+	}
+}
+
+// observeAnswer records the response that settled a tools/call, once per call.
+func (l *auditLog) observeAnswer(ctx context.Context, resp *rpcMessage) {
+	call, ok := resp.call.request.toolCall()
+	if !ok {
+		return
+	}
+	if err := l.write(l.record(call, resp)); err != nil {
+		slog.WarnContext(ctx, "Failed to write audit log", "path", l.path, "err", err)
+	}
+}
+
+func (l *auditLog) record(call toolCallParams, resp *rpcMessage) auditRecord {
+	rec := auditRecord{
+		Time:      time.Now().UTC(),
+		Session:   l.session.id,
+		Host:      l.session.host,
+		Tool:      call.Name,
+		Arguments: redactArguments(call.Arguments),
+		LatencyMS: time.Since(resp.call.started).Milliseconds(),
+	}
+	rec.Status, rec.ErrorCode = toolCallOutcome(resp)
+
+	return rec
+}
+
+// toolCallOutcome classifies the response to a tools/call request, returning
+// its status and JSON-RPC error code, if any.
+func toolCallOutcome(resp *rpcMessage) (string, int) {
+	switch {
+	case resp.call != nil && resp.call.withdrawn.Load():
+		return auditStatusCancelled, 0
+	case resp.Error != nil && resp.Error.Code == rpcCodeRequestTimeout:
+		return auditStatusTimeout, resp.Error.Code
+	case resp.Error != nil && resp.Error.Code == rpcCodePolicyDenied:
+		return auditStatusDenied, resp.Error.Code
+	case resp.Error != nil:
+		return auditStatusError, resp.Error.Code
+	case toolResultIsError(resp.Result):
+		return auditStatusToolError, 0
+	default:
+		return auditStatusOK, 0
+	}
+}
+
+func (l *auditLog) write(rec auditRecord) error {
+	line, err := json.Marshal(rec)
+	if err != nil {
+		return fmt.Errorf("failed to encode audit record: %w", err)
+	}
+	line = append(line, '\n')
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.file == nil {
+		return nil
+	}
+	if l.size > 0 && l.size+int64(len(line)) > l.maxSize {
+		if err := l.rotate(); err != nil {
+			return err
+		}
+	}
+
+	n, err := l.file.Write(line)
+	l.size += int64(n)
+	if err != nil {
+		return fmt.Errorf("failed to append audit record: %w", err)
+	}
+
+	return nil
+}
+
+// rotate renames audit.jsonl to audit.jsonl.1, shifting older backups up and
+// dropping those beyond maxBackups.
+func (l *auditLog) rotate() error {
+	_ = l.file.Close()
+	l.file = nil
+
+	if l.maxBackups == 0 {
+		if err := os.Remove(l.path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return fmt.Errorf("failed to rotate audit log: %w", err)
+		}
+		return l.open()
+	}
+
+	_ = os.Remove(l.backupPath(l.maxBackups))
+	for i := l.maxBackups - 1; i >= 1; i-- {
+		err := os.Rename(l.backupPath(i), l.backupPath(i+1))
+		if err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return fmt.Errorf("failed to rotate audit log: %w", err)
+		}
+	}
+	if err := os.Rename(l.path, l.backupPath(1)); err != nil {
+		return fmt.Errorf("failed to rotate audit log: %w", err)
+	}
+
+	return l.open()
+}
+
+func (l *auditLog) backupPath(n int) string {
+	return l.path + "." + strconv.Itoa(n)
+}
+
+// toolResultIsError reports whether a tools/call result has isError set.
+func toolResultIsError(result json.RawMessage) bool {
+	var r struct {
+		IsError bool `json:"isError"`
+	}
+	_ = json.Unmarshal(result, &r)
+
+	return r.IsError
+}
+
+// redactArguments returns tool arguments fit for the audit log: values of
+// sensitive keys and known secret formats are replaced and long strings are
+// truncated.
+func redactArguments(args json.RawMessage) json.RawMessage {
+	if len(args) == 0 {
+		return nil
+	}
+
+	var value any
+	if err := json.Unmarshal(args, &value); err != nil {
+		return nil
+	}
+
+	redacted, err := json.Marshal(redactValue(value))
+	if err != nil {
+		return nil
+	}
+
+	return redacted
+}
+
+func redactValue(value any) any {
+	switch v := value.(type) {
+	case map[string]any:
+		for key, item := range v {
+			if isSensitiveArgument(key) {
+				v[key] = auditRedacted
+				continue
+			}
+			v[key] = redactValue(item)
+		}
+		return v
+	case []any:
+		for i, item := range v {
+			v[i] = redactValue(item)
+		}
+		return v
+	case string:
+		v = builtinSecretScanner.redactString(v, nil)
+		if len(v) > auditMaxStringLength {
+			return strings.ToValidUTF8(v[:auditMaxStringLength], "") + "..."
+		}
+		return v
+	default:
+		return v
+	}
+}
+
+func isSensitiveArgument(name string) bool {
+	name = strings.ToLower(name)
+	for _, key := range auditSensitiveKeys {
+		if strings.Contains(name, key) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,180 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func readAuditRecords(t *testing.T, path string) []auditRecord {
+	t.Helper()
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("failed to read audit log: %v", err)
+	}
+
+	var records []auditRecord
+	scanner := bufio.NewScanner(strings.NewReader(string(data)))
+	for scanner.Scan() {
+		var rec auditRecord
+		if err := json.Unmarshal(scanner.Bytes(), &rec); err != nil {
+			t.Fatalf("invalid audit line %q: %v", scanner.Text(), err)
+		}
+		records = append(records, rec)
+	}
+
+	return records
+}
+
+func TestAuditLogRecordsToolCalls(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+	audit, err := newAuditLog(auditConfig{Path: path}, proxySession{id: "s1", host: "https://github.com"})
+	if err != nil {
+		t.Fatalf("newAuditLog failed: %v", err)
+	}
+	t.Cleanup(audit.close)
+
+	filter := newToolFilter(globListConfig{Deny: []string{"delete_*"}})
+	proxy, _, _ := newTestProxy(t, defaultProxyMaxMessageSize, audit, filter)
+
+	ctx := context.Background()
+	for _, line := range []string{
+		`{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"get_issue","arguments":{"owner":"o","token":"t"}}}`,
+		`{"jsonrpc":"2.0","id":2,"method":"tools/call","params":{"name":"create_issue"}}`,
+		`{"jsonrpc":"2.0","id":3,"method":"tools/call","params":{"name":"get_me"}}`,
+		`{"jsonrpc":"2.0","id":4,"method":"tools/call","params":{"name":"delete_repo"}}`,
+		`{"jsonrpc":"2.0","id":5,"method":"tools/list"}`,
+	} {
+		proxy.handleClientLine(ctx, []byte(line))
+	}
+	proxy.serveServer(ctx, strings.NewReader(
+		`{"jsonrpc":"2.0","id":1,"result":{"content":[]}}`+"\n"+
+			`{"jsonrpc":"2.0","id":2,"result":{"content":[],"isError":true}}`+"\n"+
+			`{"jsonrpc":"2.0","id":3,"error":{"code":-32602,"message":"bad"}}`+"\n"+
+			`{"jsonrpc":"2.0","id":5,"result":{"tools":[]}}`+"\n",
+	))
+
+	records := readAuditRecords(t, path)
+	if len(records) != 4 {
+		t.Fatalf("got %d audit records, want 4: %+v", len(records), records)
+	}
+
+	byTool := make(map[string]auditRecord)
+	for _, rec := range records {
+		if rec.Session != "s1" || rec.Host != "https://github.com" || rec.Time.IsZero() {
+			t.Fatalf("record is missing session details: %+v", rec)
+		}
+		byTool[rec.Tool] = rec
+	}
+
+	if got := byTool["get_issue"]; got.Status != auditStatusOK ||
+		string(got.Arguments) != `{"owner":"o","token":"[REDACTED]"}` {
+		t.Fatalf("unexpected get_issue record: %+v (arguments %s)", got, got.Arguments)
+	}
+	if got := byTool["create_issue"]; got.Status != auditStatusToolError {
+		t.Fatalf("unexpected create_issue record: %+v", got)
+	}
+	if got := byTool["get_me"]; got.Status != auditStatusError || got.ErrorCode != -32602 {
+		t.Fatalf("unexpected get_me record: %+v", got)
+	}
+	if got := byTool["delete_repo"]; got.Status != auditStatusDenied || got.ErrorCode != rpcCodePolicyDenied {
+		t.Fatalf("unexpected delete_repo record: %+v", got)
+	}
+}
+
+func TestAuditLogRecordsEachCallOnce(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+	audit, err := newAuditLog(auditConfig{Path: path}, proxySession{id: "s1"})
+	if err != nil {
+		t.Fatalf("newAuditLog failed: %v", err)
+	}
+	t.Cleanup(audit.close)
+
+	timeouts := newToolTimeouts(toolTimeoutConfig{Default: "20ms"})
+	proxy, _, client := newTestProxy(t, defaultProxyMaxMessageSize, audit, timeouts)
+
+	ctx := context.Background()
+	proxy.handleClientLine(ctx, testToolCallLine(1, "search_code"))
+	waitForString(t, client.String, `"code":-32003`)
+
+	// The server's late answer does not settle the call again.
+	if err := proxy.handleServerLine(ctx, []byte(`{"jsonrpc":"2.0","id":1,"result":{}}`)); err != nil {
+		t.Fatalf("handleServerLine failed: %v", err)
+	}
+
+	records := readAuditRecords(t, path)
+	if len(records) != 1 || records[0].Status != auditStatusTimeout {
+		t.Fatalf("got audit records %+v, want one timeout record", records)
+	}
+}
+
+func TestAuditLogRotatesBySize(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+	backups := 2
+	audit, err := newAuditLog(auditConfig{Path: path, MaxSize: "100", MaxBackups: &backups}, proxySession{id: "s"})
+	if err != nil {
+		t.Fatalf("newAuditLog failed: %v", err)
+	}
+	t.Cleanup(audit.close)
+
+	for range 5 {
+		if err := audit.write(auditRecord{Tool: "get_issue", Status: auditStatusOK}); err != nil {
+			t.Fatalf("write failed: %v", err)
+		}
+	}
+
+	for _, name := range []string{path, path + ".1", path + ".2"} {
+		if records := readAuditRecords(t, name); len(records) != 1 {
+			t.Fatalf("%s has %d records, want 1", name, len(records))
+		}
+	}
+	if _, err := os.Stat(path + ".3"); !os.IsNotExist(err) {
+		t.Fatalf("expected no third backup, stat err = %v", err)
+	}
+}
+
+func TestRedactArguments(t *testing.T) {
+	long := strings.Repeat("a", auditMaxStringLength+10)
+	got := redactArguments(json.RawMessage(
+		`{"Authorization":"Bearer x","nested":[{"client_secret":"s","body":"` + long + `"}],"count":3}`,
+	))
+
+	var args map[string]any
+	if err := json.Unmarshal(got, &args); err != nil {
+		t.Fatalf("redacted arguments are invalid: %s", got)
+	}
+	if args["Authorization"] != auditRedacted || args["count"] != float64(3) {
+		t.Fatalf("unexpected redaction: %s", got)
+	}
+	nested := args["nested"].([]any)[0].(map[string]any)
+	if nested["client_secret"] != auditRedacted {
+		t.Fatalf("nested secret was not redacted: %s", got)
+	}
+	if body := nested["body"].(string); len(body) != auditMaxStringLength+len("...") {
+		t.Fatalf("long string was not truncated: %d bytes", len(body))
+	}
+}
+
+func TestParseProxyConfigAudit(t *testing.T) {
+	cfg, err := parseProxyConfig([]byte(`{"audit":{"path":"/tmp/a.jsonl","maxSize":"1MiB","maxBackups":0}}`))
+	if err != nil {
+		t.Fatalf("parseProxyConfig failed: %v", err)
+	}
+	if !cfg.Audit.enabled() || cfg.Audit.MaxBackups == nil || *cfg.Audit.MaxBackups != 0 {
+		t.Fatalf("unexpected audit config: %+v", cfg.Audit)
+	}
+
+	for _, data := range []string{
+		`{"audit":{"enabled":true,"maxSize":"lots"}}`,
+		`{"audit":{"enabled":true,"maxBackups":-1}}`,
+	} {
+		if _, err := parseProxyConfig([]byte(data)); err == nil {
+			t.Fatalf("parseProxyConfig(%s) succeeded, want error", data)
+		}
+	}
+}
@@ -0,0 +1,426 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	defaultCacheTTL        = 5 * time.Minute
+	defaultCacheMaxEntries = 1000
+)
+
+// toolCacheConfig enables a read-through cache of read-only tool results.
+type toolCacheConfig struct {
+	Enabled bool `json:"enabled"`
+	// TTL, like "5m", applies to tools without a matching rule.
+	TTL string `json:"ttl"`
+	// MaxEntries bounds the cache; the entries closest to expiry are evicted first.
+	MaxEntries int `json:"maxEntries"`
+	// Persist keeps the cache in the gh-mcp cache directory between runs.
+	Persist bool `json:"persist"`
+	// Tools apply to tool names matching their pattern; the first match wins.
+	Tools []toolCacheRule `json:"tools"`
+}
+
+// toolCacheRule sets the TTL for some tools. "0s" disables caching for them.
+type toolCacheRule struct {
+	Name string `json:"name"`
+	TTL  string `json:"ttl"`
+}
+
+func (c toolCacheConfig) validate() error {
+	if _, err := parseTimeoutSetting("cache.ttl", c.TTL); err != nil {
+		return err
+	}
+	if c.MaxEntries < 0 {
+		return fmt.Errorf("cache.maxEntries: %d must not be negative", c.MaxEntries)
+	}
+	for i, rule := range c.Tools {
+		field := fmt.Sprintf("cache.tools[%d]", i)
+		if err := validateGlobs(field+".name", []string{rule.Name}); err != nil {
+			return err
+		}
+		if rule.TTL == "" {
+			return fmt.Errorf("%s.ttl: must not be empty", field)
+		}
+		if _, err := parseTimeoutSetting(field+".ttl", rule.TTL); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// cacheEntry is a cached tools/call result. Its fields are exported for the
+// on-disk cache.
+type cacheEntry struct {
+	Result  json.RawMessage `json:"result"`
+	Expires time.Time       `json:"expires"`
+	// Targets are the owners and repositories the call named; writes to them
+	// invalidate the entry.
+	Targets []cacheTarget `json:"targets,omitempty"`
+}
+
+type cacheTarget struct {
+	Owner string `json:"owner"`
+	Repo  string `json:"repo,omitempty"`
+}
+
+// cacheMiss is a read-only call on its way to the server whose result may be
+// cached.
+type cacheMiss struct {
+	key     string
+	ttl     time.Duration
+	targets []cacheTarget
+	// generation is the invalidation count when the call was sent; a write
+	// succeeding meanwhile makes its result unsafe to cache.
+	generation uint64
+}
+
+// toolCache answers repeated read-only tool calls from earlier results. A
+// successful call to any other tool invalidates the entries for the owners and
+// repositories it names, or every entry when it names none.
+type toolCache struct {
+	tools      toolClassifier
+	ttl        time.Duration
+	rules      []toolCacheRule
+	maxEntries int
+	// path is the on-disk cache, empty when the cache is in memory only.
+	path string
+	// scanner redacts results before they are stored, so neither memory nor
+	// disk holds secrets the client never received. Nil when redaction is off.
+	scanner *secretScanner
+	now     func() time.Time
+
+	mu         sync.Mutex
+	entries    map[string]cacheEntry
+	misses     map[*pendingCall]cacheMiss
+	generation uint64
+}
+
+func newToolCache(cfg toolCacheConfig, session proxySession, scanner *secretScanner) (*toolCache, error) {
+	ttl := defaultCacheTTL
+	if cfg.TTL != "" {
+		// Validated when the config was loaded.
+		ttl, _ = parseTimeoutSetting("", cfg.TTL)
+	}
+	maxEntries := cfg.MaxEntries
+	if maxEntries == 0 {
+		maxEntries = defaultCacheMaxEntries
+	}
+
+	c := &toolCache{
+		ttl:        ttl,
+		rules:      cfg.Tools,
+		maxEntries: maxEntries,
+		scanner:    scanner,
+		now:        time.Now,
+		entries:    make(map[string]cacheEntry),
+		misses:     make(map[*pendingCall]cacheMiss),
+	}
+
+	if cfg.Persist {
+		dir, err := cacheDir()
+		if err != nil {
+			return nil, err
+		}
+		// One file per account keeps results fetched with one token from being
+		// served to another.
+		c.path = filepath.Join(dir, "tool-cache-"+session.account+".json")
+		c.load()
+	}
+
+	return c, nil
+}
+
+func (c *toolCache) ttlFor(tool string) time.Duration {
+	for _, rule := range c.rules {
+		if matchesAnyGlob([]string{rule.Name}, tool) {
+			d, _ := parseTimeoutSetting("", rule.TTL)
+			return d
+		}
+	}
+
+	return c.ttl
+}
+
+func (c *toolCache) clientToServer(p *stdioProxy, next proxyHandler) proxyHandler {
+	return func(ctx context.Context, msg *rpcMessage) error {
+		call, ok := msg.toolCall()
+		if !ok || !c.tools.isReadOnly(call.Name) {
+			return next(ctx, msg)
+		}
+		ttl := c.ttlFor(call.Name)
+		if ttl == 0 {
+			return next(ctx, msg)
+		}
+		key, err := cacheKey(call)
+		if err != nil {
+			return next(ctx, msg)
+		}
+
+		if result, hit := c.lookup(key); hit {
+			slog.DebugContext(ctx, "Answered tool call from cache", "tool", call.Name)
+			return p.reply(ctx, msg, result)
+		}
+
+		c.mu.Lock()
+		c.misses[msg.call] = cacheMiss{key: key, ttl: ttl, targets: cacheTargets(call), generation: c.generation}
+		c.mu.Unlock()
+
+		if err := next(ctx, msg); err != nil {
+			c.mu.Lock()
+			delete(c.misses, msg.call)
+			c.mu.Unlock()
+			return err
+		}
+
+		return nil
+	}
+}
+
+func (c *toolCache) serverToClient(_ *stdioProxy, next proxyHandler) proxyHandler {
+	return func(ctx context.Context, msg *rpcMessage) error {
+		if isToolListResponse(msg) {
+			c.tools.learn(msg)
+		}
+		if msg.isResponse() && msg.call != nil {
+			c.observe(ctx, msg)
+		}
+
+		return next(ctx, msg)
+	}
+}
+
+// observe caches the result of a read-only call and invalidates entries when
+// another tool call succeeds.
+func (c *toolCache) observe(ctx context.Context, msg *rpcMessage) {
+	succeeded := msg.Error == nil && !toolResultIsError(msg.Result)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if miss, ok := c.misses[msg.call]; ok {
+		delete(c.misses, msg.call)
+		if succeeded && miss.generation == c.generation {
+			c.store(ctx, miss, msg.Result)
+		}
+		return
+	}
+
+	call, ok := msg.call.request.toolCall()
+	if !ok || !succeeded || c.tools.isReadOnly(call.Name) {
+		return
+	}
+	removed := c.invalidate(cacheTargets(call))
+	if removed > 0 {
+		slog.DebugContext(ctx, "Invalidated cached tool results", "tool", call.Name, "entries", removed)
+	}
+}
+
+func (c *toolCache) lookup(key string) (json.RawMessage, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+	if !c.now().Before(entry.Expires) {
+		delete(c.entries, key)
+		return nil, false
+	}
+
+	return entry.Result, true
+}
+
+// store adds an entry, evicting the entry closest to expiry when the cache is
+// full. c.mu must be held.
+func (c *toolCache) store(ctx context.Context, miss cacheMiss, result json.RawMessage) {
+	if c.scanner != nil {
+		redacted, _, err := c.scanner.redactJSON(result)
+		if err != nil {
+			slog.DebugContext(ctx, "Did not cache a result that could not be scanned for secrets", "err", err)
+			return
+		}
+		result = redacted
+	}
+
+	now := c.now()
+	if _, ok := c.entries[miss.key]; !ok && len(c.entries) >= c.maxEntries {
+		var oldest string
+		for key, entry := range c.entries {
+			if oldest == "" || entry.Expires.Before(c.entries[oldest].Expires) {
+				oldest = key
+			}
+		}
+		delete(c.entries, oldest)
+	}
+
+	c.entries[miss.key] = cacheEntry{
+		Result:  bytes.Clone(result),
+		Expires: now.Add(miss.ttl),
+		Targets: miss.targets,
+	}
+}
+
+// invalidate removes the entries a write to targets may have made stale and
+// returns how many were removed. c.mu must be held.
+func (c *toolCache) invalidate(targets []cacheTarget) int {
+	c.generation++
+
+	removed := 0
+	for key, entry := range c.entries {
+		if len(targets) == 0 || len(entry.Targets) == 0 || cacheTargetsOverlap(targets, entry.Targets) {
+			delete(c.entries, key)
+			removed++
+		}
+	}
+
+	return removed
+}
+
+func cacheTargetsOverlap(writes, reads []cacheTarget) bool {
+	for _, w := range writes {
+		for _, r := range reads {
+			if !strings.EqualFold(w.Owner, r.Owner) && w.Owner != "" && r.Owner != "" {
+				continue
+			}
+			if w.Repo == "" || r.Repo == "" || strings.EqualFold(w.Repo, r.Repo) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// cacheKey identifies a call by tool name and its arguments with object keys
+// sorted, so argument order does not matter.
+func cacheKey(call toolCallParams) (string, error) {
+	args, err := canonicalJSON(call.Arguments)
+	if err != nil {
+		return "", err
+	}
+
+	return call.Name + "\x00" + args, nil
+}
+
+// cacheTargets returns the owners and repositories a call names, including
+// the qualifiers of a search query.
+func cacheTargets(call toolCallParams) []cacheTarget {
+	var args map[string]json.RawMessage
+	_ = json.Unmarshal(call.Arguments, &args)
+
+	targets, err := scopeTargetsFromArguments(args)
+	if err != nil {
+		// Arguments that disagree target every owner they name.
+		owners, _ := stringArguments(args, scopeOwnerArguments)
+		repos, _ := stringArguments(args, scopeRepoArguments)
+		for _, repo := range repos {
+			if owner, _, ok := strings.Cut(repo, "/"); ok {
+				owners = append(owners, owner)
+			}
+		}
+		for _, owner := range owners {
+			targets = append(targets, scopeTarget{owner: owner})
+		}
+	}
+	if strings.HasPrefix(call.Name, searchToolPrefix) {
+		queries, _ := stringArguments(args, scopeQueryArguments)
+		for _, query := range queries {
+			targets = append(targets, scopeTargetsFromQuery(query)...)
+		}
+	}
+
+	out := make([]cacheTarget, 0, len(targets))
+	for _, t := range targets {
+		out = append(out, cacheTarget{Owner: t.owner, Repo: t.repo})
+	}
+
+	return out
+}
+
+// load reads the on-disk cache, skipping expired entries. A missing or
+// unreadable file leaves the cache empty.
+func (c *toolCache) load() {
+	// #nosec G304 -- the path is built from the gh-mcp cache directory
+	data, err := os.ReadFile(c.path)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			slog.Warn("Failed to read tool cache", "path", c.path, "err", err)
+		}
+		return
+	}
+
+	var entries map[string]cacheEntry
+	if err := json.Unmarshal(data, &entries); err != nil {
+		slog.Warn("Ignoring corrupt tool cache", "path", c.path, "err", err)
+		return
+	}
+
+	now := c.now()
+	for key, entry := range entries {
+		if now.Before(entry.Expires) && len(c.entries) < c.maxEntries {
+			c.entries[key] = entry
+		}
+	}
+}
+
+// close writes the unexpired entries to the on-disk cache, if any.
+func (c *toolCache) close() {
+	if c.path == "" {
+		return
+	}
+
+	c.mu.Lock()
+	now := c.now()
+	entries := make(map[string]cacheEntry, len(c.entries))
+	for key, entry := range c.entries {
+		if now.Before(entry.Expires) {
+			entries[key] = entry
+		}
+	}
+	c.mu.Unlock()
+
+	if err := writeFileAtomic(c.path, entries); err != nil {
+		slog.Warn("Failed to save tool cache", "path", c.path, "err", err)
+	}
+}
+
+// writeFileAtomic replaces path with the JSON encoding of v, readable only by
+// the owner.
+func writeFileAtomic(path string, v any) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("failed to encode %s: %w", path, err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to create temporary file: %w", err)
+	}
+	defer func() { _ = os.Remove(tmp.Name()) }()
+
+	if _, err := tmp.Write(data); err != nil {
+		_ = tmp.Close()
+		return fmt.Errorf("failed to write %s: %w", tmp.Name(), err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to close %s: %w", tmp.Name(), err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("failed to replace %s: %w", path, err)
+	}
+
+	return nil
+}
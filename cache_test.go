@@ -0,0 +1,224 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newTestToolCache(t *testing.T, cfg toolCacheConfig) *toolCache {
+	t.Helper()
+
+	cache, err := newToolCache(cfg, proxySession{}, nil)
+	if err != nil {
+		t.Fatalf("newToolCache failed: %v", err)
+	}
+	cache.tools.learn(&rpcMessage{Result: []byte(`{"tools":[` +
+		`{"name":"get_issue","annotations":{"readOnlyHint":true}},` +
+		`{"name":"list_issues","annotations":{"readOnlyHint":true}}]}`)})
+
+	return cache
+}
+
+func testToolCallWithArgs(id int, name, args string) []byte {
+	return fmt.Appendf(nil, `{"jsonrpc":"2.0","id":%d,"method":"tools/call","params":{"name":%q,"arguments":%s}}`,
+		id, name, args)
+}
+
+func TestToolCacheAnswersRepeatedReadOnlyCalls(t *testing.T) {
+	cache := newTestToolCache(t, toolCacheConfig{Enabled: true})
+	proxy, server, client := newTestProxy(t, defaultProxyMaxMessageSize, cache)
+
+	ctx := context.Background()
+	proxy.handleClientLine(ctx, testToolCallWithArgs(1, "get_issue", `{"owner":"o","repo":"r","issue_number":1}`))
+	result := `{"content":[{"type":"text","text":"issue 1"}]}`
+	if err := proxy.handleServerLine(ctx, []byte(`{"jsonrpc":"2.0","id":1,"result":`+result+"}\n")); err != nil {
+		t.Fatalf("handleServerLine failed: %v", err)
+	}
+
+	// Argument order does not matter.
+	proxy.handleClientLine(ctx, testToolCallWithArgs(2, "get_issue", `{"issue_number":1,"repo":"r","owner":"o"}`))
+
+	if got, _ := server.snapshot(); strings.Count(got, methodToolsCall) != 1 {
+		t.Fatalf("server received %q, want only the first call", got)
+	}
+	if want := `{"jsonrpc":"2.0","id":2,"result":` + result + "}\n"; !strings.HasSuffix(client.String(), want) {
+		t.Fatalf("client received %q, want cached answer %q", client.String(), want)
+	}
+}
+
+func TestToolCacheSkipsErrorsAndWriteTools(t *testing.T) {
+	cache := newTestToolCache(t, toolCacheConfig{Enabled: true})
+	proxy, server, _ := newTestProxy(t, defaultProxyMaxMessageSize, cache)
+
+	ctx := context.Background()
+	for id, line := range []string{
+		`{"jsonrpc":"2.0","id":%d,"result":{"content":[],"isError":true}}`,
+		`{"jsonrpc":"2.0","id":%d,"error":{"code":-32603,"message":"boom"}}`,
+		`{"jsonrpc":"2.0","id":%d,"result":{"content":[]}}`,
+	} {
+		proxy.handleClientLine(ctx, testToolCallWithArgs(id, "get_issue", `{"owner":"o","repo":"r"}`))
+		if err := proxy.handleServerLine(ctx, fmt.Appendf(nil, line+"\n", id)); err != nil {
+			t.Fatalf("handleServerLine failed: %v", err)
+		}
+	}
+	proxy.handleClientLine(ctx, testToolCallWithArgs(10, "create_issue", `{"owner":"o","repo":"r"}`))
+	proxy.handleClientLine(ctx, testToolCallWithArgs(11, "create_issue", `{"owner":"o","repo":"r"}`))
+
+	if got, _ := server.snapshot(); strings.Count(got, methodToolsCall) != 5 {
+		t.Fatalf("server received %q, want every call", got)
+	}
+}
+
+func TestToolCacheInvalidatesOnSuccessfulWrite(t *testing.T) {
+	cache := newTestToolCache(t, toolCacheConfig{Enabled: true})
+	proxy, server, _ := newTestProxy(t, defaultProxyMaxMessageSize, cache)
+
+	ctx := context.Background()
+	call := func(id int, name, args string, respond bool) {
+		t.Helper()
+		proxy.handleClientLine(ctx, testToolCallWithArgs(id, name, args))
+		if !respond {
+			return
+		}
+		if err := proxy.handleServerLine(ctx, fmt.Appendf(nil, `{"jsonrpc":"2.0","id":%d,"result":{"content":[]}}`+"\n", id)); err != nil {
+			t.Fatalf("handleServerLine failed: %v", err)
+		}
+	}
+	calls := func() int {
+		got, _ := server.snapshot()
+		return strings.Count(got, methodToolsCall)
+	}
+
+	call(1, "list_issues", `{"owner":"o","repo":"r"}`, true)
+	call(2, "list_issues", `{"owner":"o","repo":"other"}`, true)
+	call(3, "create_issue", `{"owner":"O","repo":"R"}`, true)
+
+	call(4, "list_issues", `{"owner":"o","repo":"r"}`, false)
+	if got := calls(); got != 4 {
+		t.Fatalf("server received %d calls, want the invalidated read forwarded", got)
+	}
+	call(5, "list_issues", `{"owner":"o","repo":"other"}`, false)
+	if got := calls(); got != 4 {
+		t.Fatalf("server received %d calls, want the unrelated read cached", got)
+	}
+}
+
+func TestToolCacheDropsResultsOfReadsRacingWrites(t *testing.T) {
+	cache := newTestToolCache(t, toolCacheConfig{Enabled: true})
+	proxy, server, _ := newTestProxy(t, defaultProxyMaxMessageSize, cache)
+
+	ctx := context.Background()
+	proxy.handleClientLine(ctx, testToolCallWithArgs(1, "get_issue", `{"owner":"o","repo":"r"}`))
+	proxy.handleClientLine(ctx, testToolCallWithArgs(2, "update_issue", `{"owner":"o","repo":"r"}`))
+	for _, id := range []int{2, 1} {
+		if err := proxy.handleServerLine(ctx, fmt.Appendf(nil, `{"jsonrpc":"2.0","id":%d,"result":{}}`+"\n", id)); err != nil {
+			t.Fatalf("handleServerLine failed: %v", err)
+		}
+	}
+
+	proxy.handleClientLine(ctx, testToolCallWithArgs(3, "get_issue", `{"owner":"o","repo":"r"}`))
+	if got, _ := server.snapshot(); strings.Count(got, methodToolsCall) != 3 {
+		t.Fatalf("server received %q, want the read answered before the write completed not cached", got)
+	}
+}
+
+func TestToolCacheExpiresEntriesAndEvictsWhenFull(t *testing.T) {
+	cache := newTestToolCache(t, toolCacheConfig{
+		Enabled:    true,
+		MaxEntries: 2,
+		Tools:      []toolCacheRule{{Name: "list_*", TTL: "1m"}, {Name: "get_*", TTL: "0s"}},
+	})
+	now := time.Unix(1000, 0)
+	cache.now = func() time.Time { return now }
+
+	if got := cache.ttlFor("get_issue"); got != 0 {
+		t.Fatalf("ttlFor(get_issue) = %s, want caching disabled", got)
+	}
+
+	for i, key := range []string{"a", "b", "c"} {
+		cache.store(context.Background(), cacheMiss{key: key, ttl: time.Duration(3-i) * time.Minute}, []byte(`{}`))
+	}
+	if _, ok := cache.lookup("c"); !ok {
+		t.Fatal("lookup(c) missed the newest entry")
+	}
+	if _, ok := cache.lookup("b"); ok {
+		t.Fatal("lookup(b) hit, want the entry closest to expiry evicted")
+	}
+
+	now = now.Add(time.Minute)
+	if _, ok := cache.lookup("c"); ok {
+		t.Fatal("lookup(c) hit an expired entry")
+	}
+}
+
+func TestToolCachePersistsEntries(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tool-cache.json")
+
+	cache := newTestToolCache(t, toolCacheConfig{Enabled: true})
+	cache.path = path
+	cache.store(context.Background(), cacheMiss{key: "live", ttl: time.Hour}, []byte(`{"content":[]}`))
+	cache.store(context.Background(), cacheMiss{key: "stale", ttl: time.Nanosecond}, []byte(`{}`))
+	time.Sleep(time.Millisecond)
+	cache.close()
+
+	reloaded := newTestToolCache(t, toolCacheConfig{Enabled: true})
+	reloaded.path = path
+	reloaded.load()
+	if got, ok := reloaded.lookup("live"); !ok || string(got) != `{"content":[]}` {
+		t.Fatalf("lookup(live) = %s, %v after reload", got, ok)
+	}
+	if _, ok := reloaded.lookup("stale"); ok {
+		t.Fatal("lookup(stale) hit an expired entry after reload")
+	}
+}
+
+func TestToolCacheStoresRedactedResults(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tool-cache.json")
+	cache := newTestToolCache(t, toolCacheConfig{Enabled: true})
+	cache.path = path
+	cache.scanner = builtinSecretScanner
+	proxy, _, _ := newTestProxy(t, defaultProxyMaxMessageSize, cache)
+
+	ctx := context.Background()
+	token := "ghp_" + strings.Repeat("a", 36)
+	proxy.handleClientLine(ctx, testToolCallWithArgs(1, "get_issue", `{"owner":"o","repo":"r"}`))
+	if err := proxy.handleServerLine(ctx, []byte(`{"jsonrpc":"2.0","id":1,"result":{"content":[{"type":"text","text":"`+token+`"}]}}`+"\n")); err != nil {
+		t.Fatalf("handleServerLine failed: %v", err)
+	}
+	cache.close()
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("ReadFile failed: %v", err)
+	}
+	if strings.Contains(string(data), token) || !strings.Contains(string(data), "[REDACTED:github-token]") {
+		t.Fatalf("persisted cache is not redacted: %s", data)
+	}
+}
+
+func TestParseProxyConfigCache(t *testing.T) {
+	cfg, err := parseProxyConfig([]byte(`{"cache":{"enabled":true,"ttl":"10m","maxEntries":50,` +
+		`"persist":true,"tools":[{"name":"get_file_contents","ttl":"1h"}]}}`))
+	if err != nil {
+		t.Fatalf("parseProxyConfig failed: %v", err)
+	}
+	if !cfg.Cache.Enabled || cfg.Cache.MaxEntries != 50 || len(cfg.Cache.Tools) != 1 {
+		t.Fatalf("unexpected cache config: %+v", cfg.Cache)
+	}
+
+	for _, data := range []string{
+		`{"cache":{"ttl":"soon"}}`,
+		`{"cache":{"maxEntries":-1}}`,
+		`{"cache":{"tools":[{"name":"get_*"}]}}`,
+		`{"cache":{"tools":[{"name":"[","ttl":"1m"}]}}`,
+	} {
+		if _, err := parseProxyConfig([]byte(data)); err == nil {
+			t.Fatalf("parseProxyConfig(%s) succeeded, want error", data)
+		}
+	}
+}
@@ -0,0 +1,298 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path"
+	"path/filepath"
+)
+
+const (
+	configEnv = "GH_MCP_CONFIG"
+	// configFileName is looked up under the user config directory when GH_MCP_CONFIG is unset.
+	configFileName = "config.json"
+	// configDirName is also the name of the gh-mcp cache directory.
+	configDirName = "gh-mcp"
+)
+
+// proxyConfig is the policy file for the MCP proxy. Every section is optional.
+type proxyConfig struct {
+	// Tools selects the tools clients may see and call.
+	Tools globListConfig    `json:"tools"`
+	Scope scopePolicyConfig `json:"scope"`
+	// ReadOnly enforces read-only tool calls even when GITHUB_READ_ONLY is unset.
+	ReadOnly bool        `json:"readOnly"`
+	Audit    auditConfig `json:"audit"`
+	// Confirm asks the user before forwarding tool calls that are not read-only.
+	Confirm   confirmConfig   `json:"confirm"`
+	RateLimit rateLimitConfig `json:"rateLimit"`
+	// Timeouts bound how long tool calls may take.
+	Timeouts   toolTimeoutConfig `json:"timeouts"`
+	ResultSize resultSizeConfig  `json:"resultSize"`
+	// RedactSecrets removes credentials from tool results.
+	RedactSecrets secretRedactionConfig `json:"redactSecrets"`
+	// Cache answers repeated read-only tool calls from earlier results.
+	Cache toolCacheConfig `json:"cache"`
+	// Servers are additional stdio MCP servers presented together with the
+	// bundled one.
+	Servers []extraServerConfig `json:"servers"`
+	// LocalTools adds gh_mcp_* tools answered by gh-mcp itself.
+	LocalTools localToolsConfig `json:"localTools"`
+	// DefaultRepo fills missing owner and repo arguments from the working
+	// directory's repository.
+	DefaultRepo defaultRepoConfig `json:"defaultRepo"`
+	// ToolOverrides changes tool names, descriptions and input schemas.
+	ToolOverrides toolOverridesConfig `json:"toolOverrides"`
+}
+
+// globListConfig is an allow and deny list of glob patterns. An empty allow
+// list permits everything not denied, and deny always wins.
+type globListConfig struct {
+	Allow []string `json:"allow"`
+	Deny  []string `json:"deny"`
+}
+
+func (c globListConfig) empty() bool {
+	return len(c.Allow) == 0 && len(c.Deny) == 0
+}
+
+func (c globListConfig) validate(field string) error {
+	if err := validateGlobs(field+".allow", c.Allow); err != nil {
+		return err
+	}
+
+	return validateGlobs(field+".deny", c.Deny)
+}
+
+func (c globListConfig) permits(name string) bool {
+	if matchesAnyGlob(c.Deny, name) {
+		return false
+	}
+
+	return len(c.Allow) == 0 || matchesAnyGlob(c.Allow, name)
+}
+
+// loadProxyConfig reads the file named by GH_MCP_CONFIG, or the default config
+// file if it exists. It returns an empty config when there is none.
+func loadProxyConfig() (*proxyConfig, error) {
+	path := os.Getenv(configEnv)
+	explicit := path != ""
+	if !explicit {
+		dir, err := os.UserConfigDir()
+		if err != nil {
+			return &proxyConfig{}, nil
+		}
+		path = filepath.Join(dir, configDirName, configFileName)
+	}
+
+	// #nosec G304 -- the config path is chosen by the user running gh-mcp
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if !explicit && errors.Is(err, fs.ErrNotExist) {
+			return &proxyConfig{}, nil
+		}
+		return nil, fmt.Errorf("%w: failed to read config %s: %w", errInvalidSetting, path, err)
+	}
+
+	cfg, err := parseProxyConfig(data)
+	if err != nil {
+		return nil, fmt.Errorf("%w: config %s: %w", errInvalidSetting, path, err)
+	}
+
+	return cfg, nil
+}
+
+func parseProxyConfig(data []byte) (*proxyConfig, error) {
+	var cfg proxyConfig
+
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&cfg); err != nil {
+		return nil, fmt.Errorf("invalid JSON: %w", err)
+	}
+
+	if err := cfg.Tools.validate("tools"); err != nil {
+		return nil, err
+	}
+	if err := cfg.Scope.validate(); err != nil {
+		return nil, err
+	}
+	if err := cfg.Audit.validate(); err != nil {
+		return nil, err
+	}
+	if err := cfg.Confirm.validate(); err != nil {
+		return nil, err
+	}
+	if err := cfg.RateLimit.validate(); err != nil {
+		return nil, err
+	}
+	if err := cfg.Timeouts.validate(); err != nil {
+		return nil, err
+	}
+	if err := cfg.ResultSize.validate(); err != nil {
+		return nil, err
+	}
+	if err := cfg.RedactSecrets.validate(); err != nil {
+		return nil, err
+	}
+	if err := cfg.Cache.validate(); err != nil {
+		return nil, err
+	}
+	if err := validateExtraServers(cfg.Servers); err != nil {
+		return nil, err
+	}
+	if err := cfg.ToolOverrides.validate(); err != nil {
+		return nil, err
+	}
+
+	return &cfg, nil
+}
+
+// proxyMiddlewaresFromConfig returns the middlewares cfg enables, outermost
+// first, and a function releasing the files and processes they hold.
+func proxyMiddlewaresFromConfig(
+	cfg *proxyConfig,
+	session proxySession,
+	maxMessageSize int,
+) ([]proxyMiddleware, func(), error) {
+	var (
+		middlewares []proxyMiddleware
+		closers     []func()
+	)
+	cleanup := func() {
+		for i := len(closers) - 1; i >= 0; i-- {
+			closers[i]()
+		}
+	}
+
+	// The audit log comes first so it records the response the client receives,
+	// including policy denials.
+	if cfg.Audit.enabled() {
+		audit, err := newAuditLog(cfg.Audit, session)
+		if err != nil {
+			return nil, nil, err
+		}
+		middlewares = append(middlewares, audit)
+		closers = append(closers, audit.close)
+	}
+	// Overrides follow the audit log so every other policy, and the log, see
+	// tools by their original names.
+	if overrides := newToolOverrides(cfg.ToolOverrides); overrides != nil {
+		middlewares = append(middlewares, overrides)
+	}
+	// Results are limited after every other middleware has seen them, so the
+	// size checked is the size the client receives.
+	if limiter := newResultLimiter(cfg.ResultSize, maxMessageSize); limiter != nil {
+		middlewares = append(middlewares, limiter)
+	}
+	// Secrets are redacted before truncation could cut one beyond recognition.
+	var scanner *secretScanner
+	if redactor := newSecretRedactor(cfg.RedactSecrets); redactor != nil {
+		middlewares = append(middlewares, redactor)
+		scanner = redactor.scanner
+	}
+	if filter := newToolFilter(cfg.Tools); filter != nil {
+		middlewares = append(middlewares, filter)
+	}
+	// Default arguments are filled before the scope, read-only and
+	// confirmation checks so they see the repository the call targets.
+	if cfg.DefaultRepo.Enabled {
+		if repo, ok := detectDefaultRepo(context.Background(), hostName(session.host)); ok {
+			middlewares = append(middlewares, newRepoDefaults(repo))
+		} else {
+			slog.Warn("No GitHub repository in the working directory; owner and repo arguments are not filled")
+		}
+	}
+	if readOnlyRequested(cfg) {
+		middlewares = append(middlewares, newReadOnlyGuard())
+	}
+	if guard := newScopeGuard(cfg.Scope); guard != nil {
+		middlewares = append(middlewares, guard)
+	}
+	// Confirmation follows the access policies so the user is only asked about
+	// calls they allow.
+	if guard := newConfirmGuard(cfg.Confirm); guard != nil {
+		middlewares = append(middlewares, guard)
+	}
+	// Local tools follow the access policies too but never reach the server,
+	// the cache or the rate limits.
+	if cfg.LocalTools.Enabled {
+		middlewares = append(middlewares, newLocalTools(cfg, session))
+	}
+	// Cached results are served after the access policies and confirmation
+	// but spend no rate limit. They are stored redacted like the client
+	// receives them.
+	if cfg.Cache.Enabled {
+		cache, err := newToolCache(cfg.Cache, session, scanner)
+		if err != nil {
+			cleanup()
+			return nil, nil, err
+		}
+		middlewares = append(middlewares, cache)
+		closers = append(closers, cache.close)
+	}
+	// Rate limits apply to calls about to reach the server, after confirmation.
+	if limiter := newRateLimiter(cfg.RateLimit); limiter != nil {
+		middlewares = append(middlewares, limiter)
+	}
+	if timeouts := newToolTimeouts(cfg.Timeouts); timeouts != nil {
+		middlewares = append(middlewares, timeouts)
+	}
+	// Additional servers are reached last, so every policy applies to them.
+	if len(cfg.Servers) > 0 {
+		aggregator, err := newServerAggregator(cfg.Servers)
+		if err != nil {
+			cleanup()
+			return nil, nil, err
+		}
+		middlewares = append(middlewares, aggregator)
+		closers = append(closers, aggregator.close)
+	}
+
+	return middlewares, cleanup, nil
+}
+
+// cacheDir returns the gh-mcp cache directory, created with owner-only permissions.
+func cacheDir() (string, error) {
+	userCacheDir, err := os.UserCacheDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to locate cache directory: %w", err)
+	}
+
+	dir := filepath.Join(userCacheDir, configDirName)
+	state, err := ensureSecureTempParentDir(dir)
+	if err != nil {
+		return "", err
+	}
+	state.close()
+
+	return dir, nil
+}
+
+func validateGlobs(field string, patterns []string) error {
+	for _, pattern := range patterns {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("%s: invalid pattern %q: %w", field, pattern, err)
+		}
+	}
+
+	return nil
+}
+
+// matchesAnyGlob reports whether name matches one of patterns. Patterns were
+// checked by validateGlobs, so match errors cannot occur.
+func matchesAnyGlob(patterns []string, name string) bool {
+	for _, pattern := range patterns {
+		if ok, _ := path.Match(pattern, name); ok {
+			return true
+		}
+	}
+
+	return false
+}
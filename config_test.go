@@ -0,0 +1,53 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLoadProxyConfig(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.json")
+	if err := os.WriteFile(path, []byte(`{"tools":{"allow":["get_*"],"deny":["get_secret"]}}`), 0o600); err != nil {
+		t.Fatalf("failed to write config: %v", err)
+	}
+	t.Setenv(configEnv, path)
+
+	cfg, err := loadProxyConfig()
+	if err != nil {
+		t.Fatalf("loadProxyConfig returned error: %v", err)
+	}
+	if len(cfg.Tools.Allow) != 1 || cfg.Tools.Deny[0] != "get_secret" {
+		t.Fatalf("unexpected config: %+v", cfg)
+	}
+}
+
+func TestLoadProxyConfigRejectsInvalidFiles(t *testing.T) {
+	tests := map[string]string{
+		"unknown field": `{"tool":{}}`,
+		"bad glob":      `{"tools":{"deny":["[a-"]}}`,
+		"not json":      `tools: []`,
+	}
+
+	for name, content := range tests {
+		t.Run(name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "config.json")
+			if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+				t.Fatalf("failed to write config: %v", err)
+			}
+			t.Setenv(configEnv, path)
+
+			if _, err := loadProxyConfig(); !errors.Is(err, errInvalidSetting) {
+				t.Fatalf("expected errInvalidSetting, got: %v", err)
+			}
+		})
+	}
+
+	t.Run("missing explicit file", func(t *testing.T) {
+		t.Setenv(configEnv, filepath.Join(t.TempDir(), "missing.json"))
+		if _, err := loadProxyConfig(); !errors.Is(err, errInvalidSetting) {
+			t.Fatalf("expected errInvalidSetting, got: %v", err)
+		}
+	})
+}
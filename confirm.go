@@ -0,0 +1,248 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+	"time"
+)
+
+const (
+	policyConfirm = "confirm"
+
+	methodElicitationCreate = "elicitation/create"
+	elicitationCapability   = "elicitation"
+	elicitationActionAccept = "accept"
+
+	confirmMethodAuto        = "auto"
+	confirmMethodElicitation = "elicitation"
+	confirmMethodTTY         = "tty"
+
+	defaultConfirmTimeout = 2 * time.Minute
+)
+
+// confirmElicitationSchema asks the user for a single approve checkbox.
+const confirmElicitationSchema = `{"type":"object","properties":{"approve":{"type":"boolean",` +
+	`"title":"Approve","description":"Run this GitHub tool call"}},"required":["approve"]}`
+
+var (
+	// errConfirmTimeout is the denial reason when nobody answers in time.
+	errConfirmTimeout = errors.New("confirmation timed out")
+	// errConfirmUnavailable is returned when there is no way to ask the user.
+	errConfirmUnavailable = errors.New("no confirmation channel available")
+)
+
+// confirmConfig holds write tool calls until the user approves them.
+type confirmConfig struct {
+	Enabled bool `json:"enabled"`
+	// Method is "auto" (elicitation when the client supports it, else tty), "elicitation" or "tty".
+	Method string `json:"method"`
+	// Timeout, like "2m", after which an unanswered call is refused.
+	Timeout string `json:"timeout"`
+}
+
+func (c confirmConfig) validate() error {
+	switch c.Method {
+	case "", confirmMethodAuto, confirmMethodElicitation, confirmMethodTTY:
+	default:
+		return fmt.Errorf("confirm.method: unknown method %q", c.Method)
+	}
+
+	if c.Timeout != "" {
+		d, err := time.ParseDuration(c.Timeout)
+		if err != nil {
+			return fmt.Errorf("confirm.timeout: %w", err)
+		}
+		if d <= 0 {
+			return fmt.Errorf("confirm.timeout: %q must be positive", c.Timeout)
+		}
+	}
+
+	return nil
+}
+
+// confirmGuard holds tool calls that are not read-only until the user approves
+// them through an MCP elicitation request or on the controlling terminal.
+// Rejected and unanswered calls are refused.
+type confirmGuard struct {
+	tools   toolClassifier
+	method  string
+	timeout time.Duration
+
+	openTerminal func() (io.ReadWriteCloser, error)
+	// terminalTurn lets one prompt use the terminal at a time.
+	terminalTurn chan struct{}
+}
+
+// newConfirmGuard returns nil when confirmation is disabled.
+func newConfirmGuard(cfg confirmConfig) *confirmGuard {
+	if !cfg.Enabled {
+		return nil
+	}
+
+	method := cfg.Method
+	if method == "" {
+		method = confirmMethodAuto
+	}
+	timeout := defaultConfirmTimeout
+	if cfg.Timeout != "" {
+		// Validated when the config was loaded.
+		timeout, _ = time.ParseDuration(cfg.Timeout)
+	}
+
+	return &confirmGuard{
+		method:       method,
+		timeout:      timeout,
+		openTerminal: openConfirmTerminal,
+		terminalTurn: make(chan struct{}, 1),
+	}
+}
+
+func (g *confirmGuard) clientToServer(p *stdioProxy, next proxyHandler) proxyHandler {
+	return func(ctx context.Context, msg *rpcMessage) error {
+		call, ok := msg.toolCall()
+		if !ok || g.tools.isReadOnly(call.Name) {
+			return next(ctx, msg)
+		}
+
+		// Keep reading client input meanwhile: the answer to an elicitation
+		// arrives there.
+		go g.hold(ctx, p, msg, call, next)
+
+		return nil
+	}
+}
+
+func (g *confirmGuard) serverToClient(_ *stdioProxy, next proxyHandler) proxyHandler {
+	return func(ctx context.Context, msg *rpcMessage) error {
+		if isToolListResponse(msg) {
+			g.tools.learn(msg)
+		}
+
+		return next(ctx, msg)
+	}
+}
+
+// hold forwards msg once the user approves it and refuses it otherwise.
+func (g *confirmGuard) hold(ctx context.Context, p *stdioProxy, msg *rpcMessage, call toolCallParams, next proxyHandler) {
+	// Stop asking if the client cancels the call meanwhile.
+	callCtx, cancelCall := msg.call.context(ctx)
+	defer cancelCall()
+	askCtx, cancel := context.WithTimeoutCause(callCtx, g.timeout, errConfirmTimeout)
+	defer cancel()
+
+	approved, err := g.ask(askCtx, p, call)
+	if approved {
+		if err := next(ctx, msg); err != nil {
+			slog.WarnContext(ctx, "Failed to forward confirmed tool call", "tool", call.Name, "err", err)
+		}
+		return
+	}
+
+	reason := "declined by the user"
+	if err != nil {
+		reason = err.Error()
+		slog.WarnContext(ctx, "Tool call was not confirmed", "tool", call.Name, "err", err)
+	}
+	err = p.replyError(ctx, msg, rpcCodePolicyDenied, "tool call "+call.Name+" was not confirmed: "+reason,
+		policyDenial{Policy: policyConfirm, Tool: call.Name, Reason: reason})
+	if err != nil {
+		slog.DebugContext(ctx, "Failed to refuse unconfirmed tool call", "tool", call.Name, "err", err)
+	}
+}
+
+func (g *confirmGuard) ask(ctx context.Context, p *stdioProxy, call toolCallParams) (bool, error) {
+	switch {
+	case p.clientCapability(elicitationCapability) != nil && g.method != confirmMethodTTY:
+		return g.askClient(ctx, p, call)
+	case g.method == confirmMethodElicitation:
+		return false, fmt.Errorf("%w: the MCP client does not support elicitation", errConfirmUnavailable)
+	default:
+		return g.askTerminal(ctx, call)
+	}
+}
+
+// askClient sends an elicitation/create request to the client.
+func (g *confirmGuard) askClient(ctx context.Context, p *stdioProxy, call toolCallParams) (bool, error) {
+	params := struct {
+		Message         string          `json:"message"`
+		RequestedSchema json.RawMessage `json:"requestedSchema"`
+	}{
+		Message:         confirmPrompt(call),
+		RequestedSchema: json.RawMessage(confirmElicitationSchema),
+	}
+
+	resp, err := p.requestClient(ctx, methodElicitationCreate, params)
+	if err != nil {
+		return false, err
+	}
+	if resp.Error != nil {
+		return false, fmt.Errorf("%w: elicitation failed: %s", errConfirmUnavailable, resp.Error.Message)
+	}
+
+	var result struct {
+		Action  string `json:"action"`
+		Content struct {
+			Approve bool `json:"approve"`
+		} `json:"content"`
+	}
+	if err := json.Unmarshal(resp.Result, &result); err != nil {
+		return false, fmt.Errorf("invalid elicitation result: %w", err)
+	}
+
+	return result.Action == elicitationActionAccept && result.Content.Approve, nil
+}
+
+// askTerminal prompts on the controlling terminal, since stdio carries MCP.
+func (g *confirmGuard) askTerminal(ctx context.Context, call toolCallParams) (bool, error) {
+	select {
+	case g.terminalTurn <- struct{}{}:
+		defer func() { <-g.terminalTurn }()
+	case <-ctx.Done():
+		return false, context.Cause(ctx)
+	}
+
+	tty, err := g.openTerminal()
+	if err != nil {
+		return false, fmt.Errorf("%w: %w", errConfirmUnavailable, err)
+	}
+	// Closing the terminal also ends a read that timed out.
+	defer func() { _ = tty.Close() }()
+
+	if _, err := fmt.Fprintf(tty, "\ngh-mcp: %s [y/N] ", confirmPrompt(call)); err != nil {
+		return false, fmt.Errorf("%w: %w", errConfirmUnavailable, err)
+	}
+
+	answer := make(chan string, 1)
+	go func() {
+		line, _ := bufio.NewReader(tty).ReadString('\n')
+		answer <- line
+	}()
+
+	select {
+	case line := <-answer:
+		switch strings.ToLower(strings.TrimSpace(line)) {
+		case "y", "yes":
+			return true, nil
+		default:
+			return false, nil
+		}
+	case <-ctx.Done():
+		_, _ = fmt.Fprintln(tty, "(no answer, refused)")
+		return false, context.Cause(ctx)
+	}
+}
+
+func confirmPrompt(call toolCallParams) string {
+	prompt := "Allow GitHub tool call " + call.Name
+	if args := redactArguments(call.Arguments); len(args) > 0 {
+		prompt += " with arguments " + string(args)
+	}
+
+	return prompt + "?"
+}
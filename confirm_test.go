@@ -0,0 +1,198 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeTerminal answers prompts from in and records what was written.
+type fakeTerminal struct {
+	in     io.Reader
+	out    *lockedBuffer
+	closed chan struct{}
+}
+
+func (f *fakeTerminal) Read(p []byte) (int, error)  { return f.in.Read(p) }
+func (f *fakeTerminal) Write(p []byte) (int, error) { return f.out.Write(p) }
+func (f *fakeTerminal) Close() error {
+	if c, ok := f.in.(io.Closer); ok {
+		_ = c.Close()
+	}
+	close(f.closed)
+	return nil
+}
+
+func newTerminalConfirmGuard(t *testing.T, in io.Reader, timeout time.Duration) (*confirmGuard, *fakeTerminal) {
+	t.Helper()
+
+	guard := newConfirmGuard(confirmConfig{Enabled: true, Method: confirmMethodTTY})
+	guard.timeout = timeout
+	term := &fakeTerminal{in: in, out: &lockedBuffer{}, closed: make(chan struct{})}
+	guard.openTerminal = func() (io.ReadWriteCloser, error) { return term, nil }
+
+	return guard, term
+}
+
+func waitForString(t *testing.T, get func() string, want string) string {
+	t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		got := get()
+		if strings.Contains(got, want) {
+			return got
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("got %q, want it to contain %q", got, want)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func serverInput(server *recordingWriteCloser) func() string {
+	return func() string {
+		got, _ := server.snapshot()
+		return got
+	}
+}
+
+const testConfirmCall = `{"jsonrpc":"2.0","id":7,"method":"tools/call",` +
+	`"params":{"name":"merge_pull_request","arguments":{"owner":"o","repo":"r","pullNumber":1}}}`
+
+func TestConfirmGuardForwardsApprovedCall(t *testing.T) {
+	guard, term := newTerminalConfirmGuard(t, strings.NewReader("y\n"), time.Minute)
+	proxy, server, client := newTestProxy(t, defaultProxyMaxMessageSize, guard)
+
+	proxy.handleClientLine(context.Background(), []byte(testConfirmCall))
+
+	waitForString(t, serverInput(server), `"merge_pull_request"`)
+	if prompt := term.out.String(); !strings.Contains(prompt, "merge_pull_request") ||
+		!strings.Contains(prompt, `"owner":"o"`) {
+		t.Fatalf("prompt %q does not describe the call", prompt)
+	}
+	if got := client.String(); got != "" {
+		t.Fatalf("client received %q, want nothing", got)
+	}
+}
+
+func TestConfirmGuardRefusesDeclinedCall(t *testing.T) {
+	guard, _ := newTerminalConfirmGuard(t, strings.NewReader("n\n"), time.Minute)
+	proxy, server, client := newTestProxy(t, defaultProxyMaxMessageSize, guard)
+
+	proxy.handleClientLine(context.Background(), []byte(testConfirmCall))
+
+	waitForString(t, client.String, `"policy":"confirm"`)
+	if got, _ := server.snapshot(); got != "" {
+		t.Fatalf("server received %q, want nothing", got)
+	}
+}
+
+func TestConfirmGuardRefusesUnansweredCall(t *testing.T) {
+	reader, writer := io.Pipe()
+	t.Cleanup(func() { _ = writer.Close() })
+	guard, term := newTerminalConfirmGuard(t, reader, 50*time.Millisecond)
+	proxy, server, client := newTestProxy(t, defaultProxyMaxMessageSize, guard)
+
+	proxy.handleClientLine(context.Background(), []byte(testConfirmCall))
+
+	got := waitForString(t, client.String, `"policy":"confirm"`)
+	if !strings.Contains(got, errConfirmTimeout.Error()) {
+		t.Fatalf("denial %q does not mention the timeout", got)
+	}
+	if got, _ := server.snapshot(); got != "" {
+		t.Fatalf("server received %q, want nothing", got)
+	}
+	select {
+	case <-term.closed:
+	case <-time.After(5 * time.Second):
+		t.Fatal("terminal was not closed after the timeout")
+	}
+}
+
+func TestConfirmGuardSkipsReadOnlyTools(t *testing.T) {
+	guard, _ := newTerminalConfirmGuard(t, strings.NewReader(""), time.Minute)
+	guard.openTerminal = func() (io.ReadWriteCloser, error) {
+		t.Error("read-only tool call asked for confirmation")
+		return nil, errConfirmUnavailable
+	}
+	proxy, server, _ := newTestProxy(t, defaultProxyMaxMessageSize, guard)
+
+	ctx := context.Background()
+	proxy.handleClientLine(ctx, []byte(`{"jsonrpc":"2.0","id":1,"method":"tools/list"}`))
+	if err := proxy.handleServerLine(ctx, []byte(
+		`{"jsonrpc":"2.0","id":1,"result":{"tools":[{"name":"get_issue","annotations":{"readOnlyHint":true}}]}}`,
+	)); err != nil {
+		t.Fatalf("handleServerLine failed: %v", err)
+	}
+
+	call := `{"jsonrpc":"2.0","id":2,"method":"tools/call","params":{"name":"get_issue"}}`
+	proxy.handleClientLine(ctx, []byte(call))
+	if got, _ := server.snapshot(); !strings.HasSuffix(got, call) {
+		t.Fatalf("server received %q, want the call forwarded at once", got)
+	}
+}
+
+func TestConfirmGuardAsksClientThroughElicitation(t *testing.T) {
+	guard := newConfirmGuard(confirmConfig{Enabled: true})
+	guard.openTerminal = func() (io.ReadWriteCloser, error) {
+		t.Error("terminal was used although the client supports elicitation")
+		return nil, errConfirmUnavailable
+	}
+	proxy, server, client := newTestProxy(t, defaultProxyMaxMessageSize, guard)
+
+	ctx := context.Background()
+	proxy.handleClientLine(ctx, []byte(
+		`{"jsonrpc":"2.0","id":0,"method":"initialize","params":{"capabilities":{"elicitation":{}}}}`,
+	))
+	proxy.handleClientLine(ctx, []byte(testConfirmCall))
+
+	out := waitForString(t, client.String, methodElicitationCreate)
+	var req rpcMessage
+	if err := json.Unmarshal([]byte(out), &req); err != nil {
+		t.Fatalf("client received invalid request %q: %v", out, err)
+	}
+	if !strings.Contains(string(req.Params), "merge_pull_request") {
+		t.Fatalf("elicitation %s does not describe the call", req.Params)
+	}
+
+	proxy.handleClientLine(ctx, []byte(
+		`{"jsonrpc":"2.0","id":`+string(req.ID)+`,"result":{"action":"accept","content":{"approve":true}}}`,
+	))
+
+	waitForString(t, serverInput(server), `"merge_pull_request"`)
+	if got, _ := server.snapshot(); strings.Contains(got, `"action"`) {
+		t.Fatalf("elicitation response leaked to the server: %q", got)
+	}
+}
+
+func TestConfirmGuardRequiresElicitationSupport(t *testing.T) {
+	guard := newConfirmGuard(confirmConfig{Enabled: true, Method: confirmMethodElicitation})
+	proxy, _, client := newTestProxy(t, defaultProxyMaxMessageSize, guard)
+
+	proxy.handleClientLine(context.Background(), []byte(testConfirmCall))
+
+	got := waitForString(t, client.String, `"policy":"confirm"`)
+	if !strings.Contains(got, "does not support elicitation") {
+		t.Fatalf("unexpected denial %q", got)
+	}
+}
+
+func TestParseProxyConfigConfirm(t *testing.T) {
+	if _, err := parseProxyConfig([]byte(`{"confirm":{"enabled":true,"method":"tty","timeout":"30s"}}`)); err != nil {
+		t.Fatalf("parseProxyConfig failed: %v", err)
+	}
+
+	for _, data := range []string{
+		`{"confirm":{"method":"email"}}`,
+		`{"confirm":{"timeout":"soon"}}`,
+		`{"confirm":{"timeout":"0s"}}`,
+	} {
+		if _, err := parseProxyConfig([]byte(data)); err == nil {
+			t.Fatalf("parseProxyConfig(%s) succeeded, want error", data)
+		}
+	}
+}
@@ -0,0 +1,19 @@
+//go:build !windows
+
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+)
+
+// openConfirmTerminal opens the controlling terminal for confirmation prompts.
+func openConfirmTerminal() (io.ReadWriteCloser, error) {
+	tty, err := os.OpenFile("/dev/tty", os.O_RDWR, 0)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open controlling terminal: %w", err)
+	}
+
+	return tty, nil
+}
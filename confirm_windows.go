@@ -0,0 +1,53 @@
+//go:build windows
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+)
+
+// consoleTerminal reads from and writes to the process's console.
+type consoleTerminal struct {
+	in  *os.File
+	out *os.File
+}
+
+func (c *consoleTerminal) Read(p []byte) (int, error) {
+	n, err := c.in.Read(p)
+	if err != nil {
+		return n, fmt.Errorf("failed to read console: %w", err)
+	}
+
+	return n, nil
+}
+
+func (c *consoleTerminal) Write(p []byte) (int, error) {
+	n, err := c.out.Write(p)
+	if err != nil {
+		return n, fmt.Errorf("failed to write console: %w", err)
+	}
+
+	return n, nil
+}
+
+func (c *consoleTerminal) Close() error {
+	return errors.Join(c.in.Close(), c.out.Close())
+}
+
+// openConfirmTerminal opens the console for confirmation prompts.
+func openConfirmTerminal() (io.ReadWriteCloser, error) {
+	in, err := os.OpenFile("CONIN$", os.O_RDWR, 0)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open console input: %w", err)
+	}
+	out, err := os.OpenFile("CONOUT$", os.O_RDWR, 0)
+	if err != nil {
+		_ = in.Close()
+		return nil, fmt.Errorf("failed to open console output: %w", err)
+	}
+
+	return &consoleTerminal{in: in, out: out}, nil
+}
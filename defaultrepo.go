@@ -0,0 +1,164 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+	"sync"
+)
+
+const (
+	repoDefaultOwnerArgument = "owner"
+	repoDefaultRepoArgument  = "repo"
+)
+
+// defaultRepoConfig fills missing owner and repo arguments from the git
+// repository gh-mcp was started in.
+type defaultRepoConfig struct {
+	Enabled bool `json:"enabled"`
+}
+
+// repoDefaults fills owner and repo arguments of tools/call requests for tools
+// whose input schema requires both. Such tools are learned from tools/list,
+// where their descriptions name the defaults and the arguments are made
+// optional.
+type repoDefaults struct {
+	owner, repo string
+
+	mu    sync.Mutex
+	tools map[string]bool
+}
+
+func newRepoDefaults(repo localRepository) *repoDefaults {
+	return &repoDefaults{owner: repo.Owner, repo: repo.Repo, tools: make(map[string]bool)}
+}
+
+// detectDefaultRepo returns the GitHub repository of the working directory on
+// host.
+func detectDefaultRepo(ctx context.Context, host string) (localRepository, bool) {
+	rc := detectLocalRepo(ctx, "", host)
+	if rc.Repository == nil {
+		return localRepository{}, false
+	}
+
+	return *rc.Repository, true
+}
+
+func (d *repoDefaults) clientToServer(_ *stdioProxy, next proxyHandler) proxyHandler {
+	return func(ctx context.Context, msg *rpcMessage) error {
+		call, ok := msg.toolCall()
+		if !ok || !d.declares(call.Name) {
+			return next(ctx, msg)
+		}
+
+		if err := d.fill(msg); err != nil {
+			slog.WarnContext(ctx, "Failed to fill default repository", "tool", call.Name, "err", err)
+		}
+
+		return next(ctx, msg)
+	}
+}
+
+func (d *repoDefaults) serverToClient(_ *stdioProxy, next proxyHandler) proxyHandler {
+	return func(ctx context.Context, msg *rpcMessage) error {
+		if !isToolListResponse(msg) {
+			return next(ctx, msg)
+		}
+
+		err := rewriteToolList(msg, func(tool map[string]json.RawMessage) bool {
+			d.amend(tool)
+			return true
+		})
+		if err != nil {
+			slog.WarnContext(ctx, "Failed to add default repository to tools/list", "err", err)
+		}
+
+		return next(ctx, msg)
+	}
+}
+
+func (d *repoDefaults) declares(name string) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	return d.tools[name]
+}
+
+// amend records whether tool requires owner and repo arguments and, if so,
+// mentions the defaults in its description and drops them from required.
+// Tools where either is optional may treat a missing one as meaningful, so
+// they are left alone.
+func (d *repoDefaults) amend(tool map[string]json.RawMessage) {
+	var schema map[string]json.RawMessage
+	_ = json.Unmarshal(tool["inputSchema"], &schema)
+	var required []string
+	_ = json.Unmarshal(schema["required"], &required)
+
+	declares := slices.Contains(required, repoDefaultOwnerArgument) &&
+		slices.Contains(required, repoDefaultRepoArgument)
+
+	d.mu.Lock()
+	d.tools[toolName(tool)] = declares
+	d.mu.Unlock()
+
+	if !declares {
+		return
+	}
+
+	required = slices.DeleteFunc(required, func(name string) bool {
+		return name == repoDefaultOwnerArgument || name == repoDefaultRepoArgument
+	})
+	schema["required"], _ = json.Marshal(required)
+	tool["inputSchema"], _ = json.Marshal(schema)
+
+	var description string
+	_ = json.Unmarshal(tool["description"], &description)
+	note := "If owner and repo are omitted, they default to " + d.owner + "/" + d.repo +
+		", the repository gh-mcp was started in."
+	if description != "" {
+		note = description + "\n\n" + note
+	}
+	tool["description"], _ = json.Marshal(note)
+}
+
+// fill sets a missing owner to the default one, and a missing repo to the
+// default one when the owner is the default owner.
+func (d *repoDefaults) fill(msg *rpcMessage) error {
+	var params map[string]json.RawMessage
+	if err := json.Unmarshal(msg.Params, &params); err != nil {
+		return fmt.Errorf("failed to decode tools/call params: %w", err)
+	}
+	var args map[string]json.RawMessage
+	if raw := params["arguments"]; len(raw) > 0 && string(raw) != "null" {
+		if err := json.Unmarshal(raw, &args); err != nil {
+			return fmt.Errorf("failed to decode tools/call arguments: %w", err)
+		}
+	}
+	if args == nil {
+		args = make(map[string]json.RawMessage)
+	}
+
+	owner := firstStringArgument(args, []string{repoDefaultOwnerArgument})
+	repo := firstStringArgument(args, []string{repoDefaultRepoArgument})
+	if owner != "" && repo != "" {
+		return nil
+	}
+	if owner == "" {
+		owner = d.owner
+		args[repoDefaultOwnerArgument], _ = json.Marshal(owner)
+	}
+	if repo == "" && strings.EqualFold(owner, d.owner) {
+		args[repoDefaultRepoArgument], _ = json.Marshal(d.repo)
+	}
+
+	encoded, err := json.Marshal(args)
+	if err != nil {
+		return fmt.Errorf("failed to encode tools/call arguments: %w", err)
+	}
+	params["arguments"] = encoded
+
+	return msg.setParams(params)
+}
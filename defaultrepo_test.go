@@ -0,0 +1,101 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestRepoDefaultsAmendToolList(t *testing.T) {
+	defaults := newRepoDefaults(localRepository{Owner: "octo", Repo: "project"})
+	proxy, _, client := newTestProxy(t, defaultProxyMaxMessageSize, defaults)
+	ctx := context.Background()
+
+	proxy.handleClientLine(ctx, []byte(`{"jsonrpc":"2.0","id":1,"method":"tools/list"}`))
+	line := `{"jsonrpc":"2.0","id":1,"result":{"tools":[` +
+		`{"name":"get_issue","description":"Get an issue.","inputSchema":{"type":"object",` +
+		`"properties":{"owner":{"type":"string"},"repo":{"type":"string"},"issue_number":{"type":"number"}},` +
+		`"required":["owner","repo","issue_number"]}},` +
+		`{"name":"list_repos","description":"List repositories.","inputSchema":{"type":"object",` +
+		`"properties":{"owner":{"type":"string"},"repo":{"type":"string"}},"required":["owner"]}},` +
+		`{"name":"get_me","description":"Get me.","inputSchema":{"type":"object","properties":{}}}]}}` + "\n"
+	if err := proxy.handleServerLine(ctx, []byte(line)); err != nil {
+		t.Fatalf("handleServerLine failed: %v", err)
+	}
+
+	got := client.String()
+	for _, want := range []string{
+		`"description":"Get an issue.\n\nIf owner and repo are omitted, they default to octo/project, the repository gh-mcp was started in."`,
+		`"required":["issue_number"]`,
+		`"description":"List repositories."`,
+		`"required":["owner"]`,
+		`"description":"Get me."`,
+	} {
+		if !strings.Contains(got, want) {
+			t.Fatalf("tools/list is missing %s:\n%s", want, got)
+		}
+	}
+	if defaults.declares("list_repos") {
+		t.Fatal("list_repos does not require repo but would be filled")
+	}
+}
+
+func TestRepoDefaultsFillArguments(t *testing.T) {
+	tests := []struct {
+		name string
+		tool string
+		args string
+		want string
+	}{
+		{
+			name: "both missing",
+			tool: "get_issue",
+			args: `{"issue_number":1}`,
+			want: `"arguments":{"issue_number":1,"owner":"octo","repo":"project"}`,
+		},
+		{
+			name: "no arguments",
+			tool: "get_issue",
+			args: `null`,
+			want: `"arguments":{"owner":"octo","repo":"project"}`,
+		},
+		{
+			name: "repo missing with default owner",
+			tool: "get_issue",
+			args: `{"owner":"Octo","repo":""}`,
+			want: `"arguments":{"owner":"Octo","repo":"project"}`,
+		},
+		{
+			name: "repo missing with another owner",
+			tool: "get_issue",
+			args: `{"owner":"other"}`,
+			want: `"arguments":{"owner":"other"}`,
+		},
+		{
+			name: "owner missing",
+			tool: "get_issue",
+			args: `{"repo":"tools"}`,
+			want: `"arguments":{"owner":"octo","repo":"tools"}`,
+		},
+		{
+			name: "undeclared tool",
+			tool: "get_me",
+			args: `{}`,
+			want: `"arguments":{}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			defaults := newRepoDefaults(localRepository{Owner: "octo", Repo: "project"})
+			defaults.tools["get_issue"] = true
+			proxy, server, _ := newTestProxy(t, defaultProxyMaxMessageSize, defaults)
+
+			proxy.handleClientLine(context.Background(), []byte(
+				`{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"`+tt.tool+`","arguments":`+tt.args+`}}`))
+
+			if got, _ := server.snapshot(); !strings.Contains(got, tt.want) {
+				t.Fatalf("server received %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
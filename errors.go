@@ -1,10 +1,25 @@
 package main
 
-import "errors"
+import (
+	"errors"
+	"fmt"
+	"os"
+	"syscall"
+)
 
 var (
 	// errServerNonZeroExit is returned when github-mcp-server exits with non-zero status.
 	errServerNonZeroExit = errors.New("server exited with non-zero status")
+	// errServerTerminatedBySignal is returned when github-mcp-server is killed by a signal it did not receive from gh-mcp.
+	errServerTerminatedBySignal = errors.New("server terminated by signal")
+	// errServerFailedWhileStopping is returned when github-mcp-server crashed while gh-mcp was stopping it.
+	errServerFailedWhileStopping = errors.New("server failed while stopping")
+	// errInterrupted is returned when gh-mcp shuts down in response to a termination signal.
+	errInterrupted = errors.New("interrupted")
+	// errClientStdinClosed is the shutdown cause when the MCP client closes gh-mcp's stdin.
+	errClientStdinClosed = errors.New("client closed stdin")
+	// errParentProcessExited is the shutdown cause when the process that launched gh-mcp exits.
+	errParentProcessExited = errors.New("parent process exited")
 	// errNoBundledServerForPlatform is returned when no bundled archive exists for the current platform.
 	errNoBundledServerForPlatform = errors.New("no bundled github-mcp-server for platform")
 	// errUnsupportedBundledArchiveFormat is returned when the bundled archive format is unknown.
@@ -21,3 +36,53 @@ var (
 	errBundledTempParentInsecure     = errors.New("bundled temp parent directory is insecure")
 	errBundledTempParentStateInvalid = errors.New("bundled temp parent directory state is invalid")
 )
+
+// serverExitError records how github-mcp-server terminated on its own, or
+// crashed while being stopped.
+type serverExitError struct {
+	code   int
+	signal syscall.Signal
+}
+
+func (e *serverExitError) Error() string {
+	if e.signal != 0 {
+		return fmt.Sprintf("%s: %s", errServerTerminatedBySignal, e.signal)
+	}
+
+	return fmt.Sprintf("%s: %d", errServerNonZeroExit, e.code)
+}
+
+func (e *serverExitError) Unwrap() error {
+	if e.signal != 0 {
+		return errServerTerminatedBySignal
+	}
+
+	return errServerNonZeroExit
+}
+
+// exitCode returns the status gh-mcp exits with to forward the server's:
+// 128+N for signal N, 64+N for status N up to 63, and exitCodeServerFailure
+// for larger statuses.
+func (e *serverExitError) exitCode() int {
+	if e.signal != 0 {
+		return exitCodeForSignal(e.signal)
+	}
+	if e.code > 0 && e.code <= maxForwardedServerExitCode {
+		return exitCodeServerExitBase + e.code
+	}
+
+	return exitCodeServerFailure
+}
+
+// interruptError records the signal that ended the session.
+type interruptError struct {
+	signal os.Signal
+}
+
+func (e *interruptError) Error() string {
+	return fmt.Sprintf("%s: %s", errInterrupted, e.signal)
+}
+
+func (e *interruptError) Unwrap() error {
+	return errInterrupted
+}
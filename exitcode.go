@@ -0,0 +1,119 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"syscall"
+)
+
+// Process exit codes reported by gh-mcp. The table is documented in README.md
+// under "Exit Codes"; keep both in sync.
+const (
+	// exitCodeOK means the session ended normally.
+	exitCodeOK = 0
+	// exitCodeError is used for failures without a more specific code.
+	exitCodeError = 1
+	// exitCodeAuthFailure means gh credentials could not be resolved.
+	exitCodeAuthFailure = 3
+	// exitCodeIntegrityFailure means the bundled server failed verification or extraction.
+	exitCodeIntegrityFailure = 4
+	// exitCodeUnsupportedPlatform means no bundled server exists for this OS/architecture.
+	exitCodeUnsupportedPlatform = 5
+	// exitCodeInvalidEnvironment means a forwarded environment value or GH_MCP_* setting was rejected.
+	exitCodeInvalidEnvironment = 6
+	// exitCodeServerFailure means the server failed with a status that cannot be forwarded.
+	exitCodeServerFailure = 7
+	// exitCodeResourceLimit means the server was stopped by a configured resource limit.
+	exitCodeResourceLimit = 8
+
+	// exitCodeServerExitBase is added to the server's own exit status, so it
+	// cannot be mistaken for a gh-mcp code.
+	exitCodeServerExitBase = 64
+	// maxForwardedServerExitCode is the largest server status forwarded.
+	maxForwardedServerExitCode = 63
+
+	// exitCodeSignalBase is added to a signal number, following the shell convention.
+	exitCodeSignalBase = 128
+	// maxExitCode is the largest exit status portable across platforms.
+	maxExitCode = 255
+)
+
+var (
+	authFailureErrors = []error{
+		ErrNotLoggedIn,
+		ErrNoHost,
+	}
+	integrityFailureErrors = []error{
+		errBundledChecksumMismatch,
+		errUnsupportedBundledArchiveFormat,
+		errBundledExecutableNotFound,
+		errBundledExecutableTooLarge,
+		errBundledExecutableInvalidSize,
+		errBundledTempParentInsecure,
+		errBundledTempParentStateInvalid,
+	}
+)
+
+// exitCodeForError maps an error returned by run to the process exit code.
+func exitCodeForError(err error) int {
+	if err == nil {
+		return exitCodeOK
+	}
+
+	if errors.Is(err, errServerResourceLimitExceeded) {
+		return exitCodeResourceLimit
+	}
+
+	// Forward the server's own status when it terminated on its own, or
+	// crashed while gh-mcp was stopping it.
+	var serverExit *serverExitError
+	if errors.As(err, &serverExit) {
+		return serverExit.exitCode()
+	}
+
+	var interrupted *interruptError
+	if errors.As(err, &interrupted) {
+		return exitCodeForSignal(interrupted.signal)
+	}
+
+	switch {
+	case isAnyError(err, authFailureErrors):
+		return exitCodeAuthFailure
+	case isAnyError(err, integrityFailureErrors):
+		return exitCodeIntegrityFailure
+	case errors.Is(err, errNoBundledServerForPlatform):
+		return exitCodeUnsupportedPlatform
+	case errors.Is(err, ErrInvalidServerEnvValue), errors.Is(err, errInvalidSetting):
+		return exitCodeInvalidEnvironment
+	case errors.Is(err, errServerNonZeroExit), errors.Is(err, errServerTerminatedBySignal):
+		return exitCodeServerFailure
+	default:
+		return exitCodeError
+	}
+}
+
+func exitCodeForSignal(sig os.Signal) int {
+	if s, ok := sig.(syscall.Signal); ok && s > 0 {
+		return clampExitCode(exitCodeSignalBase + int(s))
+	}
+
+	return exitCodeError
+}
+
+func clampExitCode(code int) int {
+	if code <= 0 || code > maxExitCode {
+		return exitCodeError
+	}
+
+	return code
+}
+
+func isAnyError(err error, targets []error) bool {
+	for _, target := range targets {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+
+	return false
+}
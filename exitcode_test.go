@@ -0,0 +1,87 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"syscall"
+	"testing"
+)
+
+func TestExitCodeForError(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want int
+	}{
+		{"nil", nil, exitCodeOK},
+		{"generic", errors.New("boom"), exitCodeError},
+		{"not logged in", ErrNotLoggedIn, exitCodeAuthFailure},
+		{"no host", ErrNoHost, exitCodeAuthFailure},
+		{
+			"checksum mismatch",
+			fmt.Errorf("%w: archive=x", errBundledChecksumMismatch),
+			exitCodeIntegrityFailure,
+		},
+		{
+			"insecure temp parent",
+			fmt.Errorf("wrapped: %w", errBundledTempParentInsecure),
+			exitCodeIntegrityFailure,
+		},
+		{"unsupported platform", errNoBundledServerForPlatform, exitCodeUnsupportedPlatform},
+		{"invalid env", ErrInvalidServerEnvValue, exitCodeInvalidEnvironment},
+		{"invalid setting", errInvalidSetting, exitCodeInvalidEnvironment},
+		{"bare server failure", errServerNonZeroExit, exitCodeServerFailure},
+		{"server exit code", &serverExitError{code: 42}, exitCodeServerExitBase + 42},
+		{
+			"server exit code colliding with gh-mcp",
+			&serverExitError{code: exitCodeAuthFailure},
+			exitCodeServerExitBase + exitCodeAuthFailure,
+		},
+		{"server exit code too large to forward", &serverExitError{code: 100}, exitCodeServerFailure},
+		{"server killed by signal", &serverExitError{signal: syscall.Signal(9)}, exitCodeSignalBase + 9},
+		{
+			"server crashed while stopping after interrupt",
+			errors.Join(
+				fmt.Errorf("%w: %w", errServerFailedWhileStopping, &serverExitError{code: 3}),
+				&interruptError{signal: syscall.SIGINT},
+			),
+			exitCodeServerExitBase + 3,
+		},
+		{
+			"interrupted",
+			&interruptError{signal: syscall.SIGINT},
+			exitCodeSignalBase + int(syscall.SIGINT),
+		},
+		{
+			"interrupted by SIGTERM",
+			&interruptError{signal: syscall.SIGTERM},
+			exitCodeSignalBase + int(syscall.SIGTERM),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := exitCodeForError(tt.err); got != tt.want {
+				t.Fatalf("exitCodeForError(%v) = %d, want %d", tt.err, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestServerExitErrorMatchesSentinels(t *testing.T) {
+	exited := fmt.Errorf("wrapped: %w", &serverExitError{code: 9})
+	if !errors.Is(exited, errServerNonZeroExit) {
+		t.Fatalf("expected errServerNonZeroExit, got: %v", exited)
+	}
+	if exited.Error() != "wrapped: server exited with non-zero status: 9" {
+		t.Fatalf("unexpected error text: %v", exited)
+	}
+
+	signaled := &serverExitError{signal: syscall.Signal(9)}
+	if !errors.Is(signaled, errServerTerminatedBySignal) {
+		t.Fatalf("expected errServerTerminatedBySignal, got: %v", signaled)
+	}
+	if errors.Is(signaled, errServerNonZeroExit) {
+		t.Fatal("signal termination should not match errServerNonZeroExit")
+	}
+}
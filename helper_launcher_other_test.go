@@ -0,0 +1,10 @@
+//go:build !linux && !darwin
+
+package main
+
+import "os"
+
+// The server launcher helper modes need setrlimit, so they only run on Linux and macOS.
+func runHelperLauncher(_ string) {
+	os.Exit(2)
+}
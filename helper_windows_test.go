@@ -0,0 +1,11 @@
+//go:build windows
+
+package main
+
+import "os"
+
+// Helper modes that rely on Unix signals or the server launcher are not used on Windows.
+
+func waitForHelperSignal() {
+	os.Exit(2)
+}
@@ -0,0 +1,146 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strconv"
+	"time"
+)
+
+// serverLauncherArg makes gh-mcp act as a small launcher that prepares its own
+// process (resource limits, sandbox) and then replaces itself with the server
+// binary. Restrictions applied this way are in force before the server runs any code.
+const serverLauncherArg = "__gh-mcp-launch-server"
+
+// errLauncherUsage is returned when the launcher is invoked with malformed arguments.
+var errLauncherUsage = errors.New("invalid server launcher arguments")
+
+// launcherOptions is what the launcher applies before exec'ing the server.
+type launcherOptions struct {
+	limits  resourceLimits
+	sandbox *sandboxPolicy
+}
+
+func (o launcherOptions) enabled() bool {
+	return o.limits.enabled() || o.sandbox != nil
+}
+
+func (o launcherOptions) args() []string {
+	var args []string
+	if o.limits.memoryBytes > 0 {
+		args = append(args, "-memory="+strconv.FormatUint(o.limits.memoryBytes, 10))
+	}
+	if o.limits.openFiles > 0 {
+		args = append(args, "-open-files="+strconv.FormatUint(o.limits.openFiles, 10))
+	}
+	if o.limits.cpuTime > 0 {
+		args = append(args, "-cpu-time="+o.limits.cpuTime.String())
+	}
+	if o.sandbox != nil {
+		args = append(args, "-sandbox")
+		for _, path := range o.sandbox.execPaths {
+			args = append(args, "-sandbox-exec="+path)
+		}
+		for _, path := range o.sandbox.readPaths {
+			args = append(args, "-sandbox-read="+path)
+		}
+		for _, path := range o.sandbox.writePaths {
+			args = append(args, "-sandbox-write="+path)
+		}
+		for _, port := range o.sandbox.connectPorts {
+			args = append(args, "-sandbox-connect="+strconv.FormatUint(uint64(port), 10))
+		}
+	}
+
+	return args
+}
+
+// wrapServerCommand rewrites cmd to start through the gh-mcp launcher.
+func wrapServerCommand(cmd *exec.Cmd, opts launcherOptions) error {
+	if !opts.enabled() {
+		return nil
+	}
+
+	self, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("failed to locate gh-mcp executable for server launcher: %w", err)
+	}
+
+	args := []string{self, serverLauncherArg}
+	args = append(args, opts.args()...)
+	args = append(args, "--", cmd.Path)
+	args = append(args, cmd.Args[1:]...)
+
+	cmd.Path = self
+	cmd.Args = args
+
+	return nil
+}
+
+func parseLauncherArgs(args []string) (launcherOptions, []string, error) {
+	var opts launcherOptions
+
+	fs := flag.NewFlagSet(serverLauncherArg, flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	fs.Uint64Var(&opts.limits.memoryBytes, "memory", 0, "address space limit in bytes")
+	fs.Uint64Var(&opts.limits.openFiles, "open-files", 0, "open file descriptor limit")
+	fs.DurationVar(&opts.limits.cpuTime, "cpu-time", 0, "CPU time limit")
+
+	var sandbox sandboxPolicy
+	sandboxEnabled := fs.Bool("sandbox", false, "restrict filesystem, network and process creation")
+	fs.Func("sandbox-exec", "directory the server may read and execute", appendStringFlag(&sandbox.execPaths))
+	fs.Func("sandbox-read", "path the server may read", appendStringFlag(&sandbox.readPaths))
+	fs.Func("sandbox-write", "path the server may read and write", appendStringFlag(&sandbox.writePaths))
+	fs.Func("sandbox-connect", "TCP port the server may connect to", func(value string) error {
+		port, err := strconv.ParseUint(value, 10, 16)
+		if err != nil {
+			return fmt.Errorf("invalid port %q: %w", value, err)
+		}
+		sandbox.connectPorts = append(sandbox.connectPorts, uint16(port))
+		return nil
+	})
+
+	if err := fs.Parse(args); err != nil {
+		return launcherOptions{}, nil, fmt.Errorf("%w: %w", errLauncherUsage, err)
+	}
+	if *sandboxEnabled {
+		opts.sandbox = &sandbox
+	}
+	if fs.NArg() == 0 {
+		return launcherOptions{}, nil, fmt.Errorf("%w: missing server command", errLauncherUsage)
+	}
+	if opts.limits.cpuTime < 0 || opts.limits.cpuTime%time.Second != 0 {
+		return launcherOptions{}, nil, fmt.Errorf(
+			"%w: cpu-time must be whole seconds",
+			errLauncherUsage,
+		)
+	}
+
+	return opts, fs.Args(), nil
+}
+
+func appendStringFlag(dst *[]string) func(string) error {
+	return func(value string) error {
+		*dst = append(*dst, value)
+		return nil
+	}
+}
+
+// runServerLauncher is the entry point for serverLauncherArg. It only returns
+// if preparing or exec'ing the server fails.
+func runServerLauncher(args []string) int {
+	opts, command, err := parseLauncherArgs(args)
+	if err == nil {
+		err = applyLauncherOptions(opts)
+	}
+	if err == nil {
+		err = execServer(command)
+	}
+
+	fmt.Fprintf(os.Stderr, "gh-mcp: server launcher failed: %v\n", err)
+	return exitCodeServerFailure
+}
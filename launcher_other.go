@@ -0,0 +1,16 @@
+//go:build !linux && !darwin && !windows
+
+package main
+
+import "errors"
+
+// errLauncherUnsupported is returned because no github-mcp-server is bundled for this platform.
+var errLauncherUnsupported = errors.New("server launcher is not supported on this platform")
+
+func applyLauncherOptions(_ launcherOptions) error {
+	return errLauncherUnsupported
+}
+
+func execServer(_ []string) error {
+	return errLauncherUnsupported
+}
@@ -0,0 +1,70 @@
+//go:build linux || darwin
+
+package main
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"syscall"
+	"time"
+)
+
+func applyLauncherOptions(opts launcherOptions) error {
+	limits := opts.limits
+
+	if limits.memoryBytes > 0 {
+		if err := setResourceLimit(syscall.RLIMIT_AS, limits.memoryBytes, limits.memoryBytes); err != nil {
+			return fmt.Errorf("failed to limit address space: %w", err)
+		}
+	}
+	if limits.openFiles > 0 {
+		if err := setResourceLimit(syscall.RLIMIT_NOFILE, limits.openFiles, limits.openFiles); err != nil {
+			return fmt.Errorf("failed to limit open files: %w", err)
+		}
+	}
+	if limits.cpuTime > 0 {
+		// The soft limit delivers SIGXCPU; the hard limit one second later kills.
+		seconds := uint64(limits.cpuTime / time.Second)
+		if err := setResourceLimit(syscall.RLIMIT_CPU, seconds, seconds+1); err != nil {
+			return fmt.Errorf("failed to limit CPU time: %w", err)
+		}
+	}
+
+	if opts.sandbox != nil {
+		return applySandbox(opts.sandbox)
+	}
+
+	return nil
+}
+
+// setResourceLimit lowers a limit, never raising it above the current hard limit.
+func setResourceLimit(resource int, soft, hard uint64) error {
+	var current syscall.Rlimit
+	if err := syscall.Getrlimit(resource, &current); err != nil {
+		return fmt.Errorf("getrlimit: %w", err)
+	}
+
+	limit := syscall.Rlimit{Cur: soft, Max: hard}
+	if current.Max != math.MaxUint64 && limit.Max > current.Max {
+		limit.Max = current.Max
+	}
+	if limit.Cur > limit.Max {
+		limit.Cur = limit.Max
+	}
+
+	if err := syscall.Setrlimit(resource, &limit); err != nil {
+		return fmt.Errorf("setrlimit: %w", err)
+	}
+
+	return nil
+}
+
+func execServer(command []string) error {
+	// #nosec G204 -- command is the extracted bundled server passed by the parent gh-mcp
+	if err := syscall.Exec(command[0], command, os.Environ()); err != nil {
+		return fmt.Errorf("failed to exec %s: %w", command[0], err)
+	}
+
+	return nil
+}
@@ -0,0 +1,16 @@
+//go:build windows
+
+package main
+
+import "errors"
+
+// errLauncherUnsupported is returned because Windows applies limits with Job Objects instead.
+var errLauncherUnsupported = errors.New("server launcher is not supported on Windows")
+
+func applyLauncherOptions(_ launcherOptions) error {
+	return errLauncherUnsupported
+}
+
+func execServer(_ []string) error {
+	return errLauncherUnsupported
+}
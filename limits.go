@@ -0,0 +1,191 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	limitMemoryEnv    = "GH_MCP_LIMIT_MEMORY"
+	limitOpenFilesEnv = "GH_MCP_LIMIT_OPEN_FILES"
+	limitCPUTimeEnv   = "GH_MCP_LIMIT_CPU_TIME"
+
+	resourceMemory    = "memory"
+	resourceOpenFiles = "open files"
+	resourceCPUTime   = "cpu time"
+
+	// A failed allocation never reaches the limit itself, so treat peaks close
+	// to it as evidence of a memory limit violation.
+	memoryViolationRatio = 0.9
+)
+
+// errServerResourceLimitExceeded is returned when github-mcp-server fails
+// because it hit a configured resource limit.
+var errServerResourceLimitExceeded = errors.New("server exceeded resource limit")
+
+var byteSizeSuffixes = []struct {
+	suffix     string
+	multiplier uint64
+}{
+	// Longest suffixes first so "MiB" is not read as "B".
+	{"KIB", 1 << 10},
+	{"MIB", 1 << 20},
+	{"GIB", 1 << 30},
+	{"K", 1 << 10},
+	{"M", 1 << 20},
+	{"G", 1 << 30},
+	{"B", 1},
+}
+
+// resourceLimits caps the bundled server process. Zero values are unlimited.
+type resourceLimits struct {
+	// memoryBytes is the address space limit on Unix and the process memory limit on Windows.
+	memoryBytes uint64
+	openFiles   uint64
+	cpuTime     time.Duration
+}
+
+func resourceLimitsFromEnv() (resourceLimits, error) {
+	var limits resourceLimits
+
+	if value := os.Getenv(limitMemoryEnv); value != "" {
+		size, err := parseByteSize(value)
+		if err != nil {
+			return resourceLimits{}, fmt.Errorf(
+				"%w: %s=%q: %w",
+				errInvalidSetting,
+				limitMemoryEnv,
+				value,
+				err,
+			)
+		}
+		limits.memoryBytes = size
+	}
+
+	if value := os.Getenv(limitOpenFilesEnv); value != "" {
+		count, err := strconv.ParseUint(value, 10, 64)
+		if err != nil || count == 0 {
+			return resourceLimits{}, fmt.Errorf(
+				"%w: %s=%q must be a positive integer",
+				errInvalidSetting,
+				limitOpenFilesEnv,
+				value,
+			)
+		}
+		limits.openFiles = count
+	}
+
+	cpuTime, err := durationSettingFromEnv(limitCPUTimeEnv, 0)
+	if err != nil {
+		return resourceLimits{}, err
+	}
+	if cpuTime > 0 && cpuTime < time.Second {
+		return resourceLimits{}, fmt.Errorf(
+			"%w: %s must be at least 1s",
+			errInvalidSetting,
+			limitCPUTimeEnv,
+		)
+	}
+	limits.cpuTime = cpuTime
+
+	return limits, nil
+}
+
+func (l resourceLimits) enabled() bool {
+	return l.memoryBytes > 0 || l.openFiles > 0 || l.cpuTime > 0
+}
+
+// parseByteSize parses sizes like "512MiB", "2G" or "1048576". Suffixes are binary.
+func parseByteSize(value string) (uint64, error) {
+	normalized := strings.ToUpper(strings.TrimSpace(value))
+
+	multiplier := uint64(1)
+	for _, s := range byteSizeSuffixes {
+		if strings.HasSuffix(normalized, s.suffix) {
+			normalized = strings.TrimSpace(strings.TrimSuffix(normalized, s.suffix))
+			multiplier = s.multiplier
+			break
+		}
+	}
+
+	n, err := strconv.ParseUint(normalized, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid byte size: %w", err)
+	}
+	if n == 0 || n > math.MaxUint64/multiplier {
+		return 0, fmt.Errorf("invalid byte size: %w", strconv.ErrRange)
+	}
+
+	return n * multiplier, nil
+}
+
+// resourceGuard applies limits to one server process and attributes its
+// failure to a limit when how it exited, or the resources it used, show it.
+type resourceGuard struct {
+	limits resourceLimits
+	job    resourceLimitJob
+}
+
+func newResourceGuard(limits resourceLimits) *resourceGuard {
+	return &resourceGuard{limits: limits}
+}
+
+// classify turns a server failure into errServerResourceLimitExceeded when it
+// was caused by a configured limit.
+func (g *resourceGuard) classify(err error, state *os.ProcessState) error {
+	if err == nil || !g.limits.enabled() {
+		return err
+	}
+	if !errors.Is(err, errServerNonZeroExit) && !errors.Is(err, errServerTerminatedBySignal) {
+		return err
+	}
+
+	resource, ok := g.violation(state)
+	if !ok {
+		return err
+	}
+
+	return fmt.Errorf(
+		"%w: %s (limit %s; %s)",
+		errServerResourceLimitExceeded,
+		resource,
+		g.limitDescription(resource),
+		err.Error(),
+	)
+}
+
+func (g *resourceGuard) violation(state *os.ProcessState) (string, bool) {
+	if resource, ok := g.platformViolation(state); ok {
+		return resource, true
+	}
+
+	if g.cpuTimeExhausted(state) {
+		return resourceCPUTime, true
+	}
+
+	return "", false
+}
+
+// cpuTimeExhausted reports whether the server used up its CPU time limit.
+func (g *resourceGuard) cpuTimeExhausted(state *os.ProcessState) bool {
+	return g.limits.cpuTime > 0 && state != nil &&
+		state.UserTime()+state.SystemTime() >= g.limits.cpuTime
+}
+
+func (g *resourceGuard) limitDescription(resource string) string {
+	switch resource {
+	case resourceMemory:
+		return strconv.FormatUint(g.limits.memoryBytes, 10) + " bytes"
+	case resourceOpenFiles:
+		return strconv.FormatUint(g.limits.openFiles, 10)
+	case resourceCPUTime:
+		return g.limits.cpuTime.String()
+	default:
+		return "unknown"
+	}
+}
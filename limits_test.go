@@ -0,0 +1,129 @@
+package main
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParseByteSize(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    uint64
+		wantErr bool
+	}{
+		{value: "1048576", want: 1 << 20},
+		{value: "512MiB", want: 512 << 20},
+		{value: "2g", want: 2 << 30},
+		{value: "64 KiB", want: 64 << 10},
+		{value: "10B", want: 10},
+		{value: "0", wantErr: true},
+		{value: "lots", wantErr: true},
+		{value: "-1M", wantErr: true},
+		{value: "99999999999999999999G", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			got, err := parseByteSize(tt.value)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("expected error for %q, got %d", tt.value, got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseByteSize(%q) returned error: %v", tt.value, err)
+			}
+			if got != tt.want {
+				t.Fatalf("parseByteSize(%q) = %d, want %d", tt.value, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestResourceLimitsFromEnv(t *testing.T) {
+	t.Run("unset", func(t *testing.T) {
+		t.Setenv(limitMemoryEnv, "")
+		t.Setenv(limitOpenFilesEnv, "")
+		t.Setenv(limitCPUTimeEnv, "")
+
+		limits, err := resourceLimitsFromEnv()
+		if err != nil {
+			t.Fatalf("resourceLimitsFromEnv returned error: %v", err)
+		}
+		if limits.enabled() {
+			t.Fatalf("expected no limits, got %+v", limits)
+		}
+	})
+
+	t.Run("all set", func(t *testing.T) {
+		t.Setenv(limitMemoryEnv, "1GiB")
+		t.Setenv(limitOpenFilesEnv, "256")
+		t.Setenv(limitCPUTimeEnv, "2m")
+
+		limits, err := resourceLimitsFromEnv()
+		if err != nil {
+			t.Fatalf("resourceLimitsFromEnv returned error: %v", err)
+		}
+		want := resourceLimits{memoryBytes: 1 << 30, openFiles: 256, cpuTime: 2 * time.Minute}
+		if limits != want {
+			t.Fatalf("limits = %+v, want %+v", limits, want)
+		}
+	})
+
+	for name, env := range map[string][2]string{
+		"bad memory":     {limitMemoryEnv, "huge"},
+		"zero files":     {limitOpenFilesEnv, "0"},
+		"sub-second cpu": {limitCPUTimeEnv, "500ms"},
+	} {
+		t.Run(name, func(t *testing.T) {
+			t.Setenv(env[0], env[1])
+
+			_, err := resourceLimitsFromEnv()
+			if !errors.Is(err, errInvalidSetting) {
+				t.Fatalf("expected errInvalidSetting, got: %v", err)
+			}
+		})
+	}
+}
+
+func TestLauncherArgsRoundTrip(t *testing.T) {
+	opts := launcherOptions{
+		limits: resourceLimits{memoryBytes: 1 << 30, openFiles: 128, cpuTime: 90 * time.Second},
+		sandbox: &sandboxPolicy{
+			execPaths:    []string{"/tmp/gh-mcp"},
+			readPaths:    []string{"/etc/ssl/certs", "/etc/resolv.conf"},
+			writePaths:   []string{"/tmp"},
+			connectPorts: []uint16{53, 443},
+		},
+	}
+
+	args := append(opts.args(), "--", "/tmp/github-mcp-server", "stdio")
+	parsed, command, err := parseLauncherArgs(args)
+	if err != nil {
+		t.Fatalf("parseLauncherArgs returned error: %v", err)
+	}
+	if !reflect.DeepEqual(parsed, opts) {
+		t.Fatalf("parsed options = %+v (sandbox %+v), want %+v (sandbox %+v)",
+			parsed, parsed.sandbox, opts, opts.sandbox)
+	}
+	if len(command) != 2 || command[0] != "/tmp/github-mcp-server" || command[1] != "stdio" {
+		t.Fatalf("unexpected server command: %v", command)
+	}
+
+	if _, _, err := parseLauncherArgs([]string{"-memory=1"}); !errors.Is(err, errLauncherUsage) {
+		t.Fatalf("expected errLauncherUsage for missing command, got: %v", err)
+	}
+}
+
+func TestResourceGuardRequiresEvidence(t *testing.T) {
+	guard := newResourceGuard(resourceLimits{memoryBytes: 1 << 30, openFiles: 16})
+
+	// Exiting with a status says nothing about which limit, if any, was hit.
+	err := guard.classify(&serverExitError{code: 2}, nil)
+	if !errors.Is(err, errServerNonZeroExit) || errors.Is(err, errServerResourceLimitExceeded) {
+		t.Fatalf("expected plain server exit error, got: %v", err)
+	}
+}
@@ -0,0 +1,89 @@
+//go:build !windows
+
+package main
+
+import (
+	"log/slog"
+	"os"
+	"os/exec"
+	"runtime"
+	"syscall"
+)
+
+// resourceLimitJob is unused on Unix, where limits are set by the launcher.
+type resourceLimitJob struct{}
+
+// launcherResourceLimits returns the limits the launcher applies with setrlimit before exec.
+// macOS does not enforce RLIMIT_AS, so the memory limit is left out there.
+func launcherResourceLimits(limits resourceLimits) resourceLimits {
+	if runtime.GOOS == "darwin" {
+		limits.memoryBytes = 0
+	}
+
+	return limits
+}
+
+func (g *resourceGuard) prepare(_ *exec.Cmd) error {
+	if runtime.GOOS == "darwin" && g.limits.memoryBytes > 0 {
+		slog.Warn("Memory limits are not supported on macOS; ignoring", "env", limitMemoryEnv)
+		g.limits.memoryBytes = 0
+	}
+
+	return nil
+}
+
+func (g *resourceGuard) attach(_ *exec.Cmd) error {
+	return nil
+}
+
+func (g *resourceGuard) close() {}
+
+// platformViolation attributes the exit to a limit from the signal that ended
+// the server and its peak memory use.
+func (g *resourceGuard) platformViolation(state *os.ProcessState) (string, bool) {
+	if state == nil {
+		return "", false
+	}
+
+	if status, ok := state.Sys().(syscall.WaitStatus); ok && status.Signaled() {
+		switch status.Signal() {
+		case syscall.SIGXCPU:
+			if g.limits.cpuTime > 0 {
+				return resourceCPUTime, true
+			}
+		case syscall.SIGKILL:
+			// The kernel kills at the hard CPU limit, and when memory runs out.
+			if g.cpuTimeExhausted(state) {
+				return resourceCPUTime, true
+			}
+			if g.limits.memoryBytes > 0 {
+				return resourceMemory, true
+			}
+		default:
+		}
+	}
+
+	if g.limits.memoryBytes > 0 &&
+		float64(maxRSSBytes(state)) >= float64(g.limits.memoryBytes)*memoryViolationRatio {
+		return resourceMemory, true
+	}
+
+	return "", false
+}
+
+// maxRSSBytes returns the server's peak resident set size. macOS reports
+// ru_maxrss in bytes; other Unix systems report kilobytes.
+func maxRSSBytes(state *os.ProcessState) uint64 {
+	usage, ok := state.SysUsage().(*syscall.Rusage)
+	if !ok || usage.Maxrss <= 0 {
+		return 0
+	}
+
+	// #nosec G115 -- Maxrss is checked to be positive above
+	maxRSS := uint64(usage.Maxrss)
+	if runtime.GOOS == "darwin" {
+		return maxRSS
+	}
+
+	return maxRSS * 1024
+}
@@ -0,0 +1,105 @@
+//go:build linux || darwin
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// runHelperLauncher runs the server launcher in the helper process; mode picks the command.
+func runHelperLauncher(mode string) {
+	var args []string
+	switch mode {
+	case "launch-open-files":
+		args = []string{"-open-files=64", "--", "/bin/sh", "-c", "ulimit -n"}
+	case "launch-cpu-spin":
+		args = []string{
+			"-cpu-time=1s", "--", os.Args[0], "-test.run=TestServerProcessHelper", "--", "spin",
+		}
+	case "launch-sandbox":
+		// Everything is readable so the test binary can run; only /dev/null is writable.
+		args = []string{
+			"-sandbox", "-sandbox-exec=/", "-sandbox-write=" + os.DevNull, "--",
+			os.Args[0], "-test.run=TestServerProcessHelper", "--", "sandbox-probe",
+		}
+	case "sandbox-probe":
+		runSandboxProbe()
+	}
+
+	os.Exit(runServerLauncher(args))
+}
+
+func TestServerLauncherAppliesOpenFileLimit(t *testing.T) {
+	cmd := newServerTestHelperCommand(t, "launch-open-files")
+	out, err := cmd.Output()
+	if err != nil {
+		t.Fatalf("launcher helper failed: %v", err)
+	}
+	if got := strings.TrimSpace(string(out)); got != "64" {
+		t.Fatalf("open file limit in server = %q, want 64", got)
+	}
+}
+
+func TestResourceGuardReportsCPUTimeLimit(t *testing.T) {
+	if testing.Short() {
+		t.Skip("spins for about a second of CPU time")
+	}
+
+	cmd := newServerTestHelperCommand(t, "launch-cpu-spin")
+	guard := newResourceGuard(resourceLimits{cpuTime: time.Second})
+
+	err := guard.classify(normalizeServerExit(cmd.Run()), cmd.ProcessState)
+	if !errors.Is(err, errServerResourceLimitExceeded) {
+		t.Fatalf("expected errServerResourceLimitExceeded, got: %v", err)
+	}
+}
+
+// runSandboxProbe reports which operations the sandbox blocked, one per line.
+func runSandboxProbe() {
+	// #nosec G204 -- test-only probe re-runs the current test binary
+	if err := exec.Command(os.Args[0], "-test.run=^$").Run(); errors.Is(err, syscall.EPERM) {
+		fmt.Println("spawn blocked")
+	}
+
+	probe := filepath.Join(os.Getenv("GH_MCP_TEST_SANDBOX_DIR"), "probe")
+	if err := os.WriteFile(probe, nil, 0o600); errors.Is(err, syscall.EACCES) {
+		fmt.Println("write blocked")
+	}
+
+	os.Exit(0)
+}
+
+func TestResourceGuardAttributesSIGKILLToMemoryLimit(t *testing.T) {
+	cmd := newServerTestHelperCommand(t, "sleep")
+	if err := cmd.Start(); err != nil {
+		t.Fatalf("failed to start helper process: %v", err)
+	}
+	_ = cmd.Process.Kill()
+	exitErr := normalizeServerExit(cmd.Wait())
+
+	guard := newResourceGuard(resourceLimits{memoryBytes: 1 << 40})
+	err := guard.classify(exitErr, cmd.ProcessState)
+	if !errors.Is(err, errServerResourceLimitExceeded) {
+		t.Fatalf("expected errServerResourceLimitExceeded, got: %v", err)
+	}
+	if !strings.Contains(err.Error(), resourceMemory) {
+		t.Fatalf("expected memory to be named, got: %v", err)
+	}
+	if got := exitCodeForError(err); got != exitCodeResourceLimit {
+		t.Fatalf("exit code = %d, want %d", got, exitCodeResourceLimit)
+	}
+
+	// Without a memory limit the kill is reported as the server's own exit.
+	unlimited := newResourceGuard(resourceLimits{openFiles: 16})
+	if err := unlimited.classify(exitErr, cmd.ProcessState); !errors.Is(err, errServerTerminatedBySignal) {
+		t.Fatalf("expected plain signal exit error, got: %v", err)
+	}
+}
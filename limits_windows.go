@@ -0,0 +1,120 @@
+//go:build windows
+
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"os/exec"
+	"time"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+// Job Object times are expressed in 100-nanosecond intervals.
+const jobTimeUnit = 100 * time.Nanosecond
+
+// resourceLimitJob holds the Job Object the server is assigned to.
+type resourceLimitJob struct {
+	handle windows.Handle
+}
+
+// launcherResourceLimits returns no limits: Windows applies them with a Job Object instead.
+func launcherResourceLimits(_ resourceLimits) resourceLimits {
+	return resourceLimits{}
+}
+
+func (g *resourceGuard) prepare(_ *exec.Cmd) error {
+	if g.limits.openFiles > 0 {
+		slog.Warn("Open file limits are not supported on Windows; ignoring", "env", limitOpenFilesEnv)
+	}
+
+	return nil
+}
+
+// attach places the started server in a Job Object carrying the limits.
+func (g *resourceGuard) attach(cmd *exec.Cmd) error {
+	if g.limits.memoryBytes == 0 && g.limits.cpuTime == 0 {
+		return nil
+	}
+
+	job, err := windows.CreateJobObject(nil, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create job object: %w", err)
+	}
+
+	var info windows.JOBOBJECT_EXTENDED_LIMIT_INFORMATION
+	info.BasicLimitInformation.LimitFlags = windows.JOB_OBJECT_LIMIT_KILL_ON_JOB_CLOSE
+	if g.limits.memoryBytes > 0 {
+		info.BasicLimitInformation.LimitFlags |= windows.JOB_OBJECT_LIMIT_PROCESS_MEMORY
+		info.ProcessMemoryLimit = uintptr(g.limits.memoryBytes)
+	}
+	if g.limits.cpuTime > 0 {
+		info.BasicLimitInformation.LimitFlags |= windows.JOB_OBJECT_LIMIT_PROCESS_TIME
+		info.BasicLimitInformation.PerProcessUserTimeLimit = int64(g.limits.cpuTime / jobTimeUnit)
+	}
+
+	// #nosec G103 -- Job Object APIs take a pointer to the information struct
+	if _, err := windows.SetInformationJobObject(
+		job,
+		windows.JobObjectExtendedLimitInformation,
+		uintptr(unsafe.Pointer(&info)),
+		uint32(unsafe.Sizeof(info)),
+	); err != nil {
+		_ = windows.CloseHandle(job)
+		return fmt.Errorf("failed to set job object limits: %w", err)
+	}
+
+	// #nosec G115 -- process IDs are non-negative and fit in uint32 on Windows
+	proc, err := windows.OpenProcess(
+		windows.PROCESS_SET_QUOTA|windows.PROCESS_TERMINATE,
+		false,
+		uint32(cmd.Process.Pid),
+	)
+	if err != nil {
+		_ = windows.CloseHandle(job)
+		return fmt.Errorf("failed to open github-mcp-server process: %w", err)
+	}
+	defer func() { _ = windows.CloseHandle(proc) }()
+
+	if err := windows.AssignProcessToJobObject(job, proc); err != nil {
+		_ = windows.CloseHandle(job)
+		return fmt.Errorf("failed to assign github-mcp-server to job object: %w", err)
+	}
+
+	g.job.handle = job
+	return nil
+}
+
+func (g *resourceGuard) close() {
+	if g.job.handle != 0 {
+		_ = windows.CloseHandle(g.job.handle)
+		g.job.handle = 0
+	}
+}
+
+func (g *resourceGuard) platformViolation(_ *os.ProcessState) (string, bool) {
+	if g.job.handle == 0 || g.limits.memoryBytes == 0 {
+		return "", false
+	}
+
+	var info windows.JOBOBJECT_EXTENDED_LIMIT_INFORMATION
+	// #nosec G103 -- Job Object APIs take a pointer to the information struct
+	if err := windows.QueryInformationJobObject(
+		g.job.handle,
+		windows.JobObjectExtendedLimitInformation,
+		uintptr(unsafe.Pointer(&info)),
+		uint32(unsafe.Sizeof(info)),
+		nil,
+	); err != nil {
+		return "", false
+	}
+
+	if float64(info.PeakProcessMemoryUsed) >= float64(g.limits.memoryBytes)*memoryViolationRatio {
+		return resourceMemory, true
+	}
+
+	return "", false
+}
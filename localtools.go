@@ -0,0 +1,405 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"os/exec"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/cli/go-gh/v2/pkg/config"
+)
+
+const (
+	localToolWhoami      = "gh_mcp_whoami"
+	localToolRepoContext = "gh_mcp_local_repo_context"
+	localToolSessionInfo = "gh_mcp_session_info"
+
+	// localGitTimeout bounds each git command run for the local repository context.
+	localGitTimeout = 5 * time.Second
+)
+
+// localToolsConfig adds tools answered by gh-mcp itself.
+type localToolsConfig struct {
+	Enabled bool `json:"enabled"`
+}
+
+// localToolDefinition describes a local tool. Local tools are read-only and
+// take no arguments.
+type localToolDefinition struct {
+	name, title, description string
+}
+
+var localToolDefinitions = []localToolDefinition{
+	{
+		name:  localToolWhoami,
+		title: "GitHub identity",
+		description: "Returns the GitHub host gh-mcp is authenticated to, the account login when gh knows it, " +
+			"and the kind of token in use. The token itself is never returned.",
+	},
+	{
+		name:  localToolRepoContext,
+		title: "Local repository context",
+		description: "Returns the git repository gh-mcp was started in: its root, current branch and commit, " +
+			"remotes, and the GitHub owner and repository they point to.",
+	},
+	{
+		name:  localToolSessionInfo,
+		title: "gh-mcp session",
+		description: "Returns the gh-mcp session: the bundled github-mcp-server version, the toolsets and tools " +
+			"it was configured with, and the gh-mcp policies in effect.",
+	},
+}
+
+// localRemotePreference orders remotes when choosing the current repository,
+// as gh does.
+var localRemotePreference = []string{"upstream", "github", "origin"}
+
+// localTools answers gh_mcp_* tool calls without the server, from the
+// session's credentials and settings and the git repository in the working
+// directory. The tools are added to tools/list.
+type localTools struct {
+	session proxySession
+	// readOnly reports whether read-only mode is enforced.
+	readOnly bool
+	// policies are the policy file sections in effect.
+	policies          []string
+	additionalServers []string
+	// dir is where git runs; empty is the working directory.
+	dir string
+	// user returns the login gh has recorded for a host, or "".
+	user func(host string) string
+}
+
+func newLocalTools(cfg *proxyConfig, session proxySession) *localTools {
+	l := &localTools{session: session, readOnly: readOnlyRequested(cfg), user: ghUserForHost}
+	for _, policy := range []struct {
+		name    string
+		enabled bool
+	}{
+		{"tools", !cfg.Tools.empty()},
+		{"scope", !cfg.Scope.empty()},
+		{"readOnly", l.readOnly},
+		{"audit", cfg.Audit.enabled()},
+		{"confirm", cfg.Confirm.Enabled},
+		{"rateLimit", !cfg.RateLimit.empty()},
+		{"timeouts", !cfg.Timeouts.empty()},
+		{"resultSize", !cfg.ResultSize.empty()},
+		{"redactSecrets", cfg.RedactSecrets.enabled()},
+		{"cache", cfg.Cache.Enabled},
+		{"defaultRepo", cfg.DefaultRepo.Enabled},
+		{"toolOverrides", !cfg.ToolOverrides.empty()},
+	} {
+		if policy.enabled {
+			l.policies = append(l.policies, policy.name)
+		}
+	}
+	for _, server := range cfg.Servers {
+		l.additionalServers = append(l.additionalServers, server.Name)
+	}
+
+	return l
+}
+
+// ghUserForHost reads the login of host from the gh configuration.
+func ghUserForHost(host string) string {
+	cfg, err := config.Read(nil)
+	if err != nil {
+		return ""
+	}
+	user, _ := cfg.Get([]string{"hosts", host, "user"})
+
+	return user
+}
+
+func (l *localTools) clientToServer(p *stdioProxy, next proxyHandler) proxyHandler {
+	return func(ctx context.Context, msg *rpcMessage) error {
+		call, ok := msg.toolCall()
+		if !ok || !isLocalTool(call.Name) {
+			return next(ctx, msg)
+		}
+
+		result, err := l.call(ctx, call.Name)
+		if err != nil {
+			slog.WarnContext(ctx, "Local tool failed", "tool", call.Name, "err", err)
+			return p.replyError(ctx, msg, rpcCodeInternalError, "gh-mcp could not answer "+call.Name, nil)
+		}
+
+		return p.reply(ctx, msg, result)
+	}
+}
+
+func (l *localTools) serverToClient(_ *stdioProxy, next proxyHandler) proxyHandler {
+	return func(ctx context.Context, msg *rpcMessage) error {
+		if isToolListResponse(msg) && lastListPage(msg) {
+			if err := appendListItems(msg, "tools", localToolList()); err != nil {
+				slog.WarnContext(ctx, "Failed to add local tools to tools/list", "err", err)
+			}
+		}
+
+		return next(ctx, msg)
+	}
+}
+
+func isLocalTool(name string) bool {
+	return slices.ContainsFunc(localToolDefinitions, func(d localToolDefinition) bool {
+		return d.name == name
+	})
+}
+
+func localToolList() []json.RawMessage {
+	tools := make([]json.RawMessage, 0, len(localToolDefinitions))
+	for _, d := range localToolDefinitions {
+		data, _ := json.Marshal(map[string]any{
+			"name":        d.name,
+			"title":       d.title,
+			"description": d.description,
+			"inputSchema": map[string]any{"type": "object", "properties": map[string]any{}},
+			"annotations": map[string]any{"title": d.title, "readOnlyHint": true, "openWorldHint": false},
+		})
+		tools = append(tools, data)
+	}
+
+	return tools
+}
+
+// call returns the tools/call result of a local tool, with its output both
+// as structured content and as JSON text.
+func (l *localTools) call(ctx context.Context, name string) (json.RawMessage, error) {
+	var output any
+	switch name {
+	case localToolWhoami:
+		output = l.whoami()
+	case localToolRepoContext:
+		output = l.repoContext(ctx)
+	default:
+		output = l.sessionInfo()
+	}
+
+	text, err := json.Marshal(output)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode %s result: %w", name, err)
+	}
+	result, err := json.Marshal(map[string]any{
+		"content":           []any{map[string]string{"type": "text", "text": string(text)}},
+		"structuredContent": json.RawMessage(text),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode %s result: %w", name, err)
+	}
+
+	return result, nil
+}
+
+type localWhoami struct {
+	Host string `json:"host"`
+	// User is the login gh recorded for the host, absent for tokens from the environment.
+	User      string `json:"user,omitempty"`
+	TokenType string `json:"tokenType"`
+}
+
+func (l *localTools) whoami() localWhoami {
+	host := hostName(l.session.host)
+
+	return localWhoami{
+		Host:      host,
+		User:      l.user(host),
+		TokenType: l.session.tokenType,
+	}
+}
+
+// githubTokenType names the kind of a GitHub token from its prefix.
+func githubTokenType(token string) string {
+	for _, kind := range []struct{ prefix, name string }{
+		{"github_pat_", "fine-grained-personal-access-token"},
+		{"ghp_", "personal-access-token"},
+		{"gho_", "oauth"},
+		{"ghu_", "github-app-user"},
+		{"ghs_", "github-app-installation"},
+	} {
+		if strings.HasPrefix(token, kind.prefix) {
+			return kind.name
+		}
+	}
+
+	return "unknown"
+}
+
+// hostName strips the scheme gh-mcp adds to GITHUB_HOST.
+func hostName(host string) string {
+	if u, err := url.Parse(host); err == nil && u.Host != "" {
+		return u.Host
+	}
+
+	return host
+}
+
+type localRepoContext struct {
+	InRepository bool   `json:"inRepository"`
+	Root         string `json:"root,omitempty"`
+	// Branch is empty when HEAD is detached.
+	Branch string `json:"branch,omitempty"`
+	// Commit is empty before the first commit.
+	Commit  string        `json:"commit,omitempty"`
+	Remotes []localRemote `json:"remotes,omitempty"`
+	// Repository is the GitHub repository of the preferred remote on the
+	// authenticated host.
+	Repository *localRepository `json:"repository,omitempty"`
+}
+
+type localRemote struct {
+	Name string `json:"name"`
+	// URL has any credentials removed.
+	URL   string `json:"url"`
+	Host  string `json:"host,omitempty"`
+	Owner string `json:"owner,omitempty"`
+	Repo  string `json:"repo,omitempty"`
+}
+
+type localRepository struct {
+	Owner  string `json:"owner"`
+	Repo   string `json:"repo"`
+	Remote string `json:"remote"`
+}
+
+func (l *localTools) repoContext(ctx context.Context) localRepoContext {
+	return detectLocalRepo(ctx, l.dir, hostName(l.session.host))
+}
+
+// detectLocalRepo describes the git repository containing dir, choosing the
+// preferred remote on host as its GitHub repository.
+func detectLocalRepo(ctx context.Context, dir, host string) localRepoContext {
+	root, err := runGit(ctx, dir, "rev-parse", "--show-toplevel")
+	if err != nil {
+		return localRepoContext{}
+	}
+
+	rc := localRepoContext{InRepository: true, Root: root}
+	rc.Branch, _ = runGit(ctx, dir, "symbolic-ref", "--quiet", "--short", "HEAD")
+	rc.Commit, _ = runGit(ctx, dir, "rev-parse", "--verify", "--quiet", "HEAD")
+
+	urls, _ := runGit(ctx, dir, "config", "--get-regexp", `^remote\..*\.url$`)
+	for line := range strings.Lines(urls) {
+		key, rawURL, ok := strings.Cut(strings.TrimSpace(line), " ")
+		name := strings.TrimSuffix(strings.TrimPrefix(key, "remote."), ".url")
+		if !ok || name == "" {
+			continue
+		}
+		rc.Remotes = append(rc.Remotes, parseGitRemote(name, rawURL))
+	}
+
+	rank := func(name string) int {
+		if i := slices.Index(localRemotePreference, name); i >= 0 {
+			return i
+		}
+		return len(localRemotePreference)
+	}
+	for _, remote := range rc.Remotes {
+		if !strings.EqualFold(remote.Host, host) || remote.Owner == "" {
+			continue
+		}
+		if rc.Repository == nil || rank(remote.Name) < rank(rc.Repository.Remote) {
+			rc.Repository = &localRepository{Owner: remote.Owner, Repo: remote.Repo, Remote: remote.Name}
+		}
+	}
+
+	return rc
+}
+
+// runGit runs git in dir, the working directory when empty, and returns its
+// trimmed output.
+func runGit(ctx context.Context, dir string, args ...string) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, localGitTimeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, "git", args...)
+	cmd.Dir = dir
+	var stdout bytes.Buffer
+	cmd.Stdout = &stdout
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("git %s: %w", args[0], err)
+	}
+
+	return strings.TrimSpace(stdout.String()), nil
+}
+
+// parseGitRemote reads the host, owner and repository from a remote URL in
+// URL or scp-like syntax, dropping any credentials.
+func parseGitRemote(name, rawURL string) localRemote {
+	remote := localRemote{Name: name, URL: rawURL}
+
+	var host, path string
+	if u, err := url.Parse(rawURL); err == nil && u.Scheme != "" && u.Host != "" {
+		u.User = nil
+		remote.URL = u.String()
+		host, path = u.Hostname(), u.Path
+	} else if at, rest, ok := strings.Cut(rawURL, ":"); ok && !strings.Contains(at, "/") {
+		// scp-like syntax, as in git@github.com:owner/repo.git
+		if _, h, ok := strings.Cut(at, "@"); ok {
+			at = h
+			remote.URL = "git@" + at + ":" + rest
+		}
+		host, path = at, rest
+	}
+
+	owner, repo, ok := strings.Cut(strings.Trim(path, "/"), "/")
+	if !ok || host == "" || owner == "" || strings.Contains(repo, "/") {
+		return remote
+	}
+	remote.Host = host
+	remote.Owner = owner
+	remote.Repo = strings.TrimSuffix(repo, ".git")
+
+	return remote
+}
+
+type localSessionInfo struct {
+	SessionID     string `json:"sessionId"`
+	ServerVersion string `json:"serverVersion"`
+	Host          string `json:"host"`
+	// Toolsets and Tools are as configured through GITHUB_TOOLSETS and GITHUB_TOOLS.
+	Toolsets        []string `json:"toolsets,omitempty"`
+	Tools           []string `json:"tools,omitempty"`
+	DynamicToolsets bool     `json:"dynamicToolsets"`
+	ReadOnly        bool     `json:"readOnly"`
+	LockdownMode    bool     `json:"lockdownMode"`
+	// Policies lists the policy file sections in effect.
+	Policies          []string `json:"policies,omitempty"`
+	AdditionalServers []string `json:"additionalServers,omitempty"`
+}
+
+func (l *localTools) sessionInfo() localSessionInfo {
+	settings := l.session.settings
+	flag := func(key string) bool {
+		enabled, err := strconv.ParseBool(settings[key])
+		return err == nil && enabled
+	}
+	list := func(key string) []string {
+		var items []string
+		for item := range strings.SplitSeq(settings[key], ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+		return items
+	}
+
+	return localSessionInfo{
+		SessionID:         l.session.id,
+		ServerVersion:     mcpServerVersion,
+		Host:              hostName(l.session.host),
+		Toolsets:          list("GITHUB_TOOLSETS"),
+		Tools:             list("GITHUB_TOOLS"),
+		DynamicToolsets:   flag("GITHUB_DYNAMIC_TOOLSETS"),
+		ReadOnly:          flag("GITHUB_READ_ONLY") || l.readOnly,
+		LockdownMode:      flag("GITHUB_LOCKDOWN_MODE"),
+		Policies:          l.policies,
+		AdditionalServers: l.additionalServers,
+	}
+}
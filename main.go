@@ -15,6 +15,10 @@ import (
 var ErrInvalidServerEnvValue = errors.New("invalid server environment value")
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == serverLauncherArg {
+		os.Exit(runServerLauncher(os.Args[2:]))
+	}
+
 	os.Exit(mainRun())
 }
 
@@ -34,7 +38,7 @@ func parseLogLevel() slog.Level {
 
 func mainRun() int {
 	// Set up a context that listens for termination signals
-	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	ctx, stop := notifyInterruptContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
 	defer stop()
 
 	// Initialize slog with text handler for CLI output
@@ -43,11 +47,44 @@ func mainRun() int {
 	}))
 	slog.SetDefault(logger)
 
-	if err := run(ctx); err != nil {
+	err := run(ctx)
+	if err == nil {
+		// A clean shutdown after a signal still reports the interrupt to the caller.
+		err = context.Cause(ctx)
+	}
+
+	if errors.Is(err, errInterrupted) {
+		slog.InfoContext(ctx, "🛑 Interrupted", "err", err)
+	} else if err != nil {
 		slog.ErrorContext(ctx, "Error", "err", err)
-		return 1
 	}
-	return 0
+
+	return exitCodeForError(err)
+}
+
+// notifyInterruptContext is like signal.NotifyContext but records the received
+// signal as the context cause so the exit code can reflect it.
+func notifyInterruptContext(
+	parent context.Context,
+	signals ...os.Signal,
+) (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancelCause(parent)
+
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, signals...)
+
+	go func() {
+		select {
+		case sig := <-sigCh:
+			cancel(&interruptError{signal: sig})
+		case <-ctx.Done():
+		}
+	}()
+
+	return ctx, func() {
+		signal.Stop(sigCh)
+		cancel(nil)
+	}
 }
 
 // runner interface for dependency injection
@@ -57,6 +94,7 @@ type runner interface {
 		ctx context.Context,
 		env []string,
 		streams *ioStreams,
+		telemetry *sessionTelemetry,
 	) error
 }
 
@@ -71,8 +109,9 @@ func (r *realRunner) runServer(
 	ctx context.Context,
 	env []string,
 	streams *ioStreams,
+	telemetry *sessionTelemetry,
 ) error {
-	return runBundledServer(ctx, env, streams)
+	return runBundledServer(ctx, env, streams, telemetry)
 }
 
 func run(ctx context.Context) error {
@@ -80,9 +119,17 @@ func run(ctx context.Context) error {
 }
 
 func runWithRunner(ctx context.Context, r runner) error {
+	telemetry, stopTelemetry, err := startTelemetryFromEnv(ctx)
+	if err != nil {
+		return err
+	}
+	defer stopTelemetry()
+
 	// 1. Get Auth
 	slog.InfoContext(ctx, "🔐 Retrieving GitHub credentials...")
+	endAuth := telemetry.phase(phaseAuth)
 	auth, err := r.getAuth()
+	endAuth(err)
 	if err != nil {
 		return err
 	}
@@ -122,7 +169,7 @@ func runWithRunner(ctx context.Context, r runner) error {
 
 	// 4. Run the bundled server and stream I/O.
 	slog.InfoContext(ctx, "✅ Ready! Starting MCP server...")
-	if err := r.runServer(ctx, env, defaultIOStreams()); err != nil {
+	if err := r.runServer(ctx, env, defaultIOStreams(), telemetry); err != nil {
 		return err
 	}
 
@@ -138,6 +185,18 @@ func appendServerEnv(env []string, key, value string) ([]string, error) {
 	return append(env, key+"="+value), nil
 }
 
+// serverEnvValue returns the last value of key in env.
+func serverEnvValue(env []string, key string) string {
+	var value string
+	for _, item := range env {
+		if k, v, ok := strings.Cut(item, "="); ok && k == key {
+			value = v
+		}
+	}
+
+	return value
+}
+
 func validateServerEnvValue(key, value string) error {
 	if strings.ContainsRune(value, '\x00') {
 		return fmt.Errorf("%w: %s contains NUL byte", ErrInvalidServerEnvValue, key)
@@ -26,7 +26,7 @@ func (m *mockRunner) getAuth() (*authDetails, error) {
 	return m.authDetails, m.authErr
 }
 
-func (m *mockRunner) runServer(_ context.Context, env []string, _ *ioStreams) error {
+func (m *mockRunner) runServer(_ context.Context, env []string, _ *ioStreams, _ *sessionTelemetry) error {
 	m.capturedEnv = env
 	return m.runServerErr
 }
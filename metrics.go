@@ -0,0 +1,278 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"maps"
+	"net"
+	"net/http"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	metricsAddrEnv = "GH_MCP_METRICS_ADDR"
+	metricsPath    = "/metrics"
+
+	metricsReadHeaderTimeout = 5 * time.Second
+
+	phaseAuth       = "auth"
+	phaseChecksum   = "checksum"
+	phaseExtraction = "extraction"
+	phaseSpawn      = "spawn"
+
+	// unlistedToolLabel replaces tool names the server never listed, so
+	// clients calling made-up names cannot grow the label set without bound.
+	unlistedToolLabel = "unknown"
+)
+
+var (
+	// toolCallDurationBuckets are the upper bounds, in seconds, of the tool
+	// call latency histogram.
+	toolCallDurationBuckets = []float64{0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60}
+	// startupPhaseDurationBuckets are the upper bounds, in seconds, of the
+	// startup phase histogram.
+	startupPhaseDurationBuckets = []float64{0.01, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+)
+
+// sessionMetrics collects usage of one gh-mcp session for the Prometheus text
+// format. A nil *sessionMetrics records nothing.
+type sessionMetrics struct {
+	// addr is where the metrics are served.
+	addr string
+
+	mu        sync.Mutex
+	listed    map[string]bool
+	toolCalls map[[2]string]uint64
+	durations map[string]*histogram
+	bytes     map[string]uint64
+	restarts  uint64
+	phases    map[string]*histogram
+}
+
+type histogram struct {
+	buckets []float64
+	counts  []uint64
+	count   uint64
+	sum     float64
+}
+
+func newHistogram(buckets []float64) *histogram {
+	return &histogram{buckets: buckets, counts: make([]uint64, len(buckets))}
+}
+
+func (h *histogram) observe(d time.Duration) {
+	seconds := d.Seconds()
+	for i, bound := range h.buckets {
+		if seconds <= bound {
+			h.counts[i]++
+		}
+	}
+	h.count++
+	h.sum += seconds
+}
+
+// write writes the histogram's series for one value of the label.
+func (h *histogram) write(b *strings.Builder, name, label, value string) {
+	value = metricLabel(value)
+	for i, bound := range h.buckets {
+		fmt.Fprintf(b, "%s_bucket{%s=%s,le=\"%s\"} %d\n", name, label, value, formatMetricValue(bound), h.counts[i])
+	}
+	fmt.Fprintf(b, "%s_bucket{%s=%s,le=\"+Inf\"} %d\n", name, label, value, h.count)
+	fmt.Fprintf(b, "%s_sum{%s=%s} %s\n", name, label, value, formatMetricValue(h.sum))
+	fmt.Fprintf(b, "%s_count{%s=%s} %d\n", name, label, value, h.count)
+}
+
+func newSessionMetrics() *sessionMetrics {
+	return &sessionMetrics{
+		listed:    make(map[string]bool),
+		toolCalls: make(map[[2]string]uint64),
+		durations: make(map[string]*histogram),
+		bytes:     make(map[string]uint64),
+		phases:    make(map[string]*histogram),
+	}
+}
+
+// startMetricsFromEnv serves metrics on GH_MCP_METRICS_ADDR, which must be a
+// loopback address. Without it, the returned metrics are nil.
+func startMetricsFromEnv(ctx context.Context) (*sessionMetrics, func(), error) {
+	addr := os.Getenv(metricsAddrEnv)
+	if addr == "" {
+		return nil, func() {}, nil
+	}
+
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: %s=%q: %w", errInvalidSetting, metricsAddrEnv, addr, err)
+	}
+	if ip := net.ParseIP(host); host != "localhost" && (ip == nil || !ip.IsLoopback()) {
+		return nil, nil, fmt.Errorf("%w: %s=%q must be a loopback address", errInvalidSetting, metricsAddrEnv, addr)
+	}
+
+	listener, err := (&net.ListenConfig{}).Listen(ctx, "tcp", addr)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to listen for metrics on %s: %w", addr, err)
+	}
+
+	metrics := newSessionMetrics()
+	metrics.addr = listener.Addr().String()
+	mux := http.NewServeMux()
+	mux.HandleFunc(metricsPath, func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		if err := metrics.writeTo(w); err != nil {
+			slog.Debug("Failed to write metrics", "err", err)
+		}
+	})
+	server := &http.Server{Handler: mux, ReadHeaderTimeout: metricsReadHeaderTimeout}
+
+	go func() {
+		if err := server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			slog.Warn("Metrics listener stopped", "err", err)
+		}
+	}()
+	slog.InfoContext(ctx, "📈 Serving metrics", "url", "http://"+metrics.addr+metricsPath)
+
+	return metrics, func() { _ = server.Close() }, nil
+}
+
+// observePhase records how long a startup phase took.
+func (m *sessionMetrics) observePhase(phase string, d time.Duration) {
+	if m == nil {
+		return
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	h, ok := m.phases[phase]
+	if !ok {
+		h = newHistogram(startupPhaseDurationBuckets)
+		m.phases[phase] = h
+	}
+	h.observe(d)
+}
+
+// listTools records tool names the client was offered in tools/list.
+func (m *sessionMetrics) listTools(names []string) {
+	if m == nil {
+		return
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, name := range names {
+		m.listed[name] = true
+	}
+}
+
+// addBytes counts proxied bytes in one direction.
+func (m *sessionMetrics) addBytes(direction string, n int) {
+	if m == nil || n == 0 {
+		return
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.bytes[direction] += uint64(n)
+}
+
+func (m *sessionMetrics) serverRestarted() {
+	if m == nil {
+		return
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.restarts++
+}
+
+// observeToolCall counts an answered tool call and its latency. Tools that
+// were never listed are counted under unlistedToolLabel.
+func (m *sessionMetrics) observeToolCall(tool, outcome string, d time.Duration) {
+	if m == nil {
+		return
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if !m.listed[tool] {
+		tool = unlistedToolLabel
+	}
+	m.toolCalls[[2]string{tool, outcome}]++
+
+	h, ok := m.durations[tool]
+	if !ok {
+		h = newHistogram(toolCallDurationBuckets)
+		m.durations[tool] = h
+	}
+	h.observe(d)
+}
+
+// writeTo writes the metrics in the Prometheus text exposition format.
+func (m *sessionMetrics) writeTo(w io.Writer) error {
+	m.mu.Lock()
+	var b strings.Builder
+
+	writeMetricHeader(&b, "gh_mcp_tool_calls_total", "counter", "Tool calls answered, by tool and outcome.")
+	calls := make([][2]string, 0, len(m.toolCalls))
+	for key := range m.toolCalls {
+		calls = append(calls, key)
+	}
+	slices.SortFunc(calls, func(a, b [2]string) int {
+		return strings.Compare(a[0]+"\x00"+a[1], b[0]+"\x00"+b[1])
+	})
+	for _, key := range calls {
+		fmt.Fprintf(&b, "gh_mcp_tool_calls_total{tool=%s,outcome=%s} %d\n",
+			metricLabel(key[0]), metricLabel(key[1]), m.toolCalls[key])
+	}
+
+	writeMetricHeader(&b, "gh_mcp_tool_call_duration_seconds", "histogram",
+		"Time from a tool call reaching gh-mcp to its answer.")
+	for _, tool := range slices.Sorted(maps.Keys(m.durations)) {
+		m.durations[tool].write(&b, "gh_mcp_tool_call_duration_seconds", "tool", tool)
+	}
+
+	writeMetricHeader(&b, "gh_mcp_proxy_bytes_total", "counter", "Bytes of MCP traffic proxied, by direction.")
+	for _, direction := range slices.Sorted(maps.Keys(m.bytes)) {
+		fmt.Fprintf(&b, "gh_mcp_proxy_bytes_total{direction=%s} %d\n", metricLabel(direction), m.bytes[direction])
+	}
+
+	writeMetricHeader(&b, "gh_mcp_server_restarts_total", "counter", "Restarts of github-mcp-server.")
+	fmt.Fprintf(&b, "gh_mcp_server_restarts_total %d\n", m.restarts)
+
+	writeMetricHeader(&b, "gh_mcp_startup_phase_duration_seconds", "histogram", "Duration of each run of a startup phase.")
+	for _, phase := range slices.Sorted(maps.Keys(m.phases)) {
+		m.phases[phase].write(&b, "gh_mcp_startup_phase_duration_seconds", "phase", phase)
+	}
+	m.mu.Unlock()
+
+	if _, err := io.WriteString(w, b.String()); err != nil {
+		return fmt.Errorf("failed to write metrics: %w", err)
+	}
+
+	return nil
+}
+
+func writeMetricHeader(b *strings.Builder, name, kind, help string) {
+	fmt.Fprintf(b, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, kind)
+}
+
+// metricLabel quotes a label value, escaping as the text format requires.
+func metricLabel(value string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(value) + `"`
+}
+
+func formatMetricValue(v float64) string {
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
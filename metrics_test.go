@@ -0,0 +1,130 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSessionMetricsWritePrometheusText(t *testing.T) {
+	metrics := newSessionMetrics()
+	metrics.listTools([]string{"get_issue", `odd"name`})
+	metrics.observeToolCall("get_issue", auditStatusOK, 200*time.Millisecond)
+	metrics.observeToolCall("get_issue", auditStatusDenied, 2*time.Second)
+	metrics.observeToolCall(`odd"name`, auditStatusOK, time.Millisecond)
+	metrics.observeToolCall("made_up", auditStatusError, time.Millisecond)
+	metrics.addBytes(traceFromClient, 10)
+	metrics.addBytes(traceFromClient, 5)
+	metrics.serverRestarted()
+	metrics.observePhase(phaseAuth, 1500*time.Millisecond)
+	metrics.observePhase(phaseAuth, 500*time.Millisecond)
+
+	var b strings.Builder
+	if err := metrics.writeTo(&b); err != nil {
+		t.Fatalf("writeTo failed: %v", err)
+	}
+	text := b.String()
+
+	for _, want := range []string{
+		"# TYPE gh_mcp_tool_calls_total counter\n",
+		`gh_mcp_tool_calls_total{tool="get_issue",outcome="denied"} 1` + "\n",
+		`gh_mcp_tool_calls_total{tool="get_issue",outcome="ok"} 1` + "\n",
+		`gh_mcp_tool_calls_total{tool="odd\"name",outcome="ok"} 1` + "\n",
+		`gh_mcp_tool_calls_total{tool="unknown",outcome="error"} 1` + "\n",
+		"# TYPE gh_mcp_tool_call_duration_seconds histogram\n",
+		`gh_mcp_tool_call_duration_seconds_bucket{tool="get_issue",le="0.1"} 0` + "\n",
+		`gh_mcp_tool_call_duration_seconds_bucket{tool="get_issue",le="0.25"} 1` + "\n",
+		`gh_mcp_tool_call_duration_seconds_bucket{tool="get_issue",le="+Inf"} 2` + "\n",
+		`gh_mcp_tool_call_duration_seconds_sum{tool="get_issue"} 2.2` + "\n",
+		`gh_mcp_tool_call_duration_seconds_count{tool="get_issue"} 2` + "\n",
+		`gh_mcp_proxy_bytes_total{direction="client->gh-mcp"} 15` + "\n",
+		"gh_mcp_server_restarts_total 1\n",
+		"# TYPE gh_mcp_startup_phase_duration_seconds histogram\n",
+		`gh_mcp_startup_phase_duration_seconds_bucket{phase="auth",le="0.5"} 1` + "\n",
+		`gh_mcp_startup_phase_duration_seconds_bucket{phase="auth",le="2.5"} 2` + "\n",
+		`gh_mcp_startup_phase_duration_seconds_sum{phase="auth"} 2` + "\n",
+		`gh_mcp_startup_phase_duration_seconds_count{phase="auth"} 2` + "\n",
+	} {
+		if !strings.Contains(text, want) {
+			t.Fatalf("metrics are missing %q:\n%s", want, text)
+		}
+	}
+}
+
+func TestToolCallTelemetryCountsAnsweredToolCalls(t *testing.T) {
+	metrics := newSessionMetrics()
+	telemetry := newSessionTelemetry(metrics, nil)
+	proxy, _, _ := newTestProxy(t, defaultProxyMaxMessageSize, &toolCallTelemetry{telemetry: telemetry})
+	proxy.observe(nil, metrics)
+
+	ctx := context.Background()
+	proxy.handleClientLine(ctx, []byte(`{"jsonrpc":"2.0","id":1,"method":"tools/list"}`))
+	if err := proxy.handleServerLine(ctx, []byte(`{"jsonrpc":"2.0","id":1,"result":{"tools":[{"name":"get_me"}]}}`)); err != nil {
+		t.Fatalf("handleServerLine failed: %v", err)
+	}
+	proxy.handleClientLine(ctx, testToolCallLine(2, "get_me"))
+	response := `{"jsonrpc":"2.0","id":2,"result":{"content":[],"isError":true}}` + "\n"
+	if err := proxy.handleServerLine(ctx, []byte(response)); err != nil {
+		t.Fatalf("handleServerLine failed: %v", err)
+	}
+	proxy.handleClientLine(ctx, testToolCallLine(3, "not_listed"))
+	if err := proxy.handleServerLine(ctx, []byte(`{"jsonrpc":"2.0","id":3,"result":{"content":[]}}`)); err != nil {
+		t.Fatalf("handleServerLine failed: %v", err)
+	}
+
+	var b strings.Builder
+	if err := metrics.writeTo(&b); err != nil {
+		t.Fatalf("writeTo failed: %v", err)
+	}
+	for _, want := range []string{
+		`gh_mcp_tool_calls_total{tool="get_me",outcome="tool_error"} 1`,
+		`gh_mcp_tool_calls_total{tool="unknown",outcome="ok"} 1`,
+		`gh_mcp_proxy_bytes_total{direction="gh-mcp->client"} `,
+	} {
+		if !strings.Contains(b.String(), want) {
+			t.Fatalf("metrics are missing %q:\n%s", want, b.String())
+		}
+	}
+}
+
+func TestStartMetricsFromEnvServesLoopbackOnly(t *testing.T) {
+	t.Setenv(metricsAddrEnv, "0.0.0.0:0")
+	if _, _, err := startMetricsFromEnv(t.Context()); !errors.Is(err, errInvalidSetting) {
+		t.Fatalf("startMetricsFromEnv error = %v, want errInvalidSetting for a public address", err)
+	}
+
+	t.Setenv(metricsAddrEnv, "example.com:9464")
+	if _, _, err := startMetricsFromEnv(t.Context()); !errors.Is(err, errInvalidSetting) {
+		t.Fatalf("startMetricsFromEnv error = %v, want errInvalidSetting for a remote host", err)
+	}
+}
+
+func TestStartMetricsFromEnvServesMetrics(t *testing.T) {
+	t.Setenv(metricsAddrEnv, "127.0.0.1:0")
+	metrics, stop, err := startMetricsFromEnv(t.Context())
+	if err != nil {
+		t.Fatalf("startMetricsFromEnv failed: %v", err)
+	}
+	defer stop()
+	metrics.serverRestarted()
+
+	url := "http://" + metrics.addr + metricsPath
+	req, err := http.NewRequestWithContext(t.Context(), http.MethodGet, url, nil)
+	if err != nil {
+		t.Fatalf("NewRequest failed: %v", err)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("GET %s failed: %v", url, err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	body, _ := io.ReadAll(resp.Body)
+
+	if resp.StatusCode != http.StatusOK || !strings.Contains(string(body), "gh_mcp_server_restarts_total 1\n") {
+		t.Fatalf("GET %s = %d %q", url, resp.StatusCode, body)
+	}
+}
@@ -0,0 +1,403 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	otlpEndpointEnv       = "OTEL_EXPORTER_OTLP_ENDPOINT"
+	otlpTracesEndpointEnv = "OTEL_EXPORTER_OTLP_TRACES_ENDPOINT"
+	otlpHeadersEnv        = "OTEL_EXPORTER_OTLP_HEADERS"
+	otelServiceNameEnv    = "OTEL_SERVICE_NAME"
+
+	otlpTracesPath     = "/v1/traces"
+	defaultServiceName = "gh-mcp"
+	otelScopeName      = "github.com/shuymn/gh-mcp"
+
+	otlpExportInterval = 5 * time.Second
+	otlpExportTimeout  = 10 * time.Second
+	otlpMaxBatch       = 256
+	// otlpFlushTimeout bounds the final export at shutdown, so an unreachable
+	// collector cannot hold up gh-mcp's exit.
+	otlpFlushTimeout = 2 * time.Second
+	// otlpMaxQueue bounds the spans held while the collector is unreachable.
+	otlpMaxQueue = 4096
+
+	spanKindInternal = 1
+	spanKindServer   = 2
+
+	spanStatusOK    = 1
+	spanStatusError = 2
+)
+
+// errOTLPExport is returned when the collector rejects exported spans.
+var errOTLPExport = errors.New("OTLP export failed")
+
+// spanContext identifies a span within a trace.
+type spanContext struct {
+	traceID    [16]byte
+	spanID     [8]byte
+	traceState string
+	// unsampled spans and their children are not exported.
+	unsampled bool
+}
+
+func (c spanContext) valid() bool {
+	return c.traceID != [16]byte{} && c.spanID != [8]byte{}
+}
+
+// child returns a new span context in c's trace, or in a new trace when c is
+// not valid.
+func (c spanContext) child() spanContext {
+	sc := spanContext{traceID: c.traceID, traceState: c.traceState, unsampled: c.unsampled}
+	if !c.valid() {
+		sc = spanContext{}
+		_, _ = rand.Read(sc.traceID[:])
+	}
+	_, _ = rand.Read(sc.spanID[:])
+
+	return sc
+}
+
+// parseTraceparent reads a W3C traceparent header value.
+func parseTraceparent(value, state string) (spanContext, bool) {
+	parts := strings.Split(strings.TrimSpace(value), "-")
+	if len(parts) < 4 || len(parts[0]) != 2 || parts[0] == "ff" ||
+		len(parts[1]) != 32 || len(parts[2]) != 16 || len(parts[3]) != 2 {
+		return spanContext{}, false
+	}
+	if parts[0] == "00" && len(parts) != 4 {
+		return spanContext{}, false
+	}
+
+	var sc spanContext
+	flags, err1 := hex.DecodeString(parts[3])
+	_, err2 := hex.Decode(sc.traceID[:], []byte(parts[1]))
+	_, err3 := hex.Decode(sc.spanID[:], []byte(parts[2]))
+	if err1 != nil || err2 != nil || err3 != nil || !sc.valid() {
+		return spanContext{}, false
+	}
+	sc.unsampled = flags[0]&1 == 0
+	sc.traceState = state
+
+	return sc, true
+}
+
+// otlpSpan is a finished span in the OTLP/JSON encoding.
+type otlpSpan struct {
+	TraceID           string          `json:"traceId"`
+	SpanID            string          `json:"spanId"`
+	ParentSpanID      string          `json:"parentSpanId,omitempty"`
+	TraceState        string          `json:"traceState,omitempty"`
+	Name              string          `json:"name"`
+	Kind              int             `json:"kind"`
+	StartTimeUnixNano string          `json:"startTimeUnixNano"`
+	EndTimeUnixNano   string          `json:"endTimeUnixNano"`
+	Attributes        []otlpAttribute `json:"attributes,omitempty"`
+	Status            otlpStatus      `json:"status"`
+}
+
+type otlpAttribute struct {
+	Key   string       `json:"key"`
+	Value otlpAnyValue `json:"value"`
+}
+
+type otlpAnyValue struct {
+	StringValue *string `json:"stringValue,omitempty"`
+	IntValue    *string `json:"intValue,omitempty"`
+}
+
+type otlpStatus struct {
+	Code    int    `json:"code"`
+	Message string `json:"message,omitempty"`
+}
+
+func stringAttribute(key, value string) otlpAttribute {
+	return otlpAttribute{Key: key, Value: otlpAnyValue{StringValue: &value}}
+}
+
+func intAttribute(key string, value int64) otlpAttribute {
+	v := strconv.FormatInt(value, 10)
+	return otlpAttribute{Key: key, Value: otlpAnyValue{IntValue: &v}}
+}
+
+// spanRecord describes a finished span.
+type spanRecord struct {
+	name       string
+	kind       int
+	parent     spanContext
+	start, end time.Time
+	attributes []otlpAttribute
+	// failure, when set, marks the span as an error with this message.
+	failure string
+}
+
+// spanExporter batches finished spans and posts them to an OTLP/HTTP traces
+// endpoint as JSON. A nil exporter exports nothing.
+type spanExporter struct {
+	endpoint string
+	headers  map[string]string
+	resource []otlpAttribute
+	client   *http.Client
+	// flushTimeout bounds the export at shutdown.
+	flushTimeout time.Duration
+
+	mu      sync.Mutex
+	queue   []otlpSpan
+	dropped int
+	failed  bool
+
+	// exportMu keeps one export in flight at a time.
+	exportMu sync.Mutex
+	wake     chan struct{}
+	done     chan struct{}
+	stopped  chan struct{}
+}
+
+// startSpanExporterFromEnv exports spans to the OTLP/HTTP traces endpoint
+// configured through the standard OTEL_* variables. Without one, the returned
+// exporter is nil. The returned function flushes the remaining spans.
+func startSpanExporterFromEnv() (*spanExporter, func(), error) {
+	endpoint := os.Getenv(otlpTracesEndpointEnv)
+	source := otlpTracesEndpointEnv
+	if endpoint == "" {
+		base := os.Getenv(otlpEndpointEnv)
+		if base == "" {
+			return nil, func() {}, nil
+		}
+		endpoint = strings.TrimRight(base, "/") + otlpTracesPath
+		source = otlpEndpointEnv
+	}
+	if u, err := url.Parse(endpoint); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, nil, fmt.Errorf("%w: %s=%q is not an http(s) URL", errInvalidSetting, source, endpoint)
+	}
+
+	headers, err := parseOTLPHeaders(os.Getenv(otlpHeadersEnv))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	serviceName := os.Getenv(otelServiceNameEnv)
+	if serviceName == "" {
+		serviceName = defaultServiceName
+	}
+
+	e := &spanExporter{
+		endpoint: endpoint,
+		headers:  headers,
+		resource: []otlpAttribute{
+			stringAttribute("service.name", serviceName),
+			stringAttribute("github_mcp_server.version", mcpServerVersion),
+		},
+		client:       &http.Client{Timeout: otlpExportTimeout},
+		flushTimeout: otlpFlushTimeout,
+		wake:         make(chan struct{}, 1),
+		done:         make(chan struct{}),
+		stopped:      make(chan struct{}),
+	}
+	e.start()
+
+	return e, e.shutdown, nil
+}
+
+// parseOTLPHeaders reads "key=value" pairs separated by commas, with values
+// URL-encoded as the OpenTelemetry specification defines.
+func parseOTLPHeaders(value string) (map[string]string, error) {
+	headers := make(map[string]string)
+	for item := range strings.SplitSeq(value, ",") {
+		if strings.TrimSpace(item) == "" {
+			continue
+		}
+		key, raw, ok := strings.Cut(item, "=")
+		key = strings.TrimSpace(key)
+		decoded, err := url.QueryUnescape(strings.TrimSpace(raw))
+		if !ok || key == "" || err != nil {
+			return nil, fmt.Errorf("%w: %s has an invalid entry", errInvalidSetting, otlpHeadersEnv)
+		}
+		headers[key] = decoded
+	}
+
+	return headers, nil
+}
+
+// start exports queued spans periodically until shutdown.
+func (e *spanExporter) start() {
+	go func() {
+		defer close(e.stopped)
+
+		// Shutdown abandons a periodic export; its spans go out in the final flush.
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		go func() {
+			<-e.done
+			cancel()
+		}()
+
+		ticker := time.NewTicker(otlpExportInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+			case <-e.wake:
+			case <-e.done:
+				return
+			}
+			e.export(ctx)
+		}
+	}()
+}
+
+// shutdown stops periodic exports and sends the remaining spans, giving up
+// after flushTimeout.
+func (e *spanExporter) shutdown() {
+	close(e.done)
+	<-e.stopped
+
+	ctx, cancel := context.WithTimeout(context.Background(), e.flushTimeout)
+	defer cancel()
+	e.export(ctx)
+
+	e.mu.Lock()
+	dropped := e.dropped + len(e.queue)
+	e.mu.Unlock()
+	if dropped > 0 {
+		slog.Warn("Dropped trace spans the collector did not accept", "spans", dropped)
+	}
+}
+
+// record queues the finished span sc. Unsampled spans are dropped.
+func (e *spanExporter) record(sc spanContext, rec spanRecord) {
+	if e == nil || sc.unsampled {
+		return
+	}
+
+	span := otlpSpan{
+		TraceID:           hex.EncodeToString(sc.traceID[:]),
+		SpanID:            hex.EncodeToString(sc.spanID[:]),
+		TraceState:        sc.traceState,
+		Name:              rec.name,
+		Kind:              rec.kind,
+		StartTimeUnixNano: strconv.FormatInt(rec.start.UnixNano(), 10),
+		EndTimeUnixNano:   strconv.FormatInt(rec.end.UnixNano(), 10),
+		Attributes:        rec.attributes,
+		Status:            otlpStatus{Code: spanStatusOK},
+	}
+	if rec.parent.valid() {
+		span.ParentSpanID = hex.EncodeToString(rec.parent.spanID[:])
+	}
+	if rec.failure != "" {
+		span.Status = otlpStatus{Code: spanStatusError, Message: rec.failure}
+	}
+
+	e.mu.Lock()
+	if len(e.queue) >= otlpMaxQueue {
+		e.queue = e.queue[1:]
+		e.dropped++
+	}
+	e.queue = append(e.queue, span)
+	full := len(e.queue) >= otlpMaxBatch
+	e.mu.Unlock()
+
+	if full {
+		select {
+		case e.wake <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// export posts queued spans in batches. Spans the collector does not accept
+// are put back for the next attempt.
+func (e *spanExporter) export(ctx context.Context) {
+	e.exportMu.Lock()
+	defer e.exportMu.Unlock()
+
+	for {
+		e.mu.Lock()
+		n := min(len(e.queue), otlpMaxBatch)
+		batch := append([]otlpSpan(nil), e.queue[:n]...)
+		e.queue = e.queue[n:]
+		e.mu.Unlock()
+		if n == 0 {
+			return
+		}
+
+		if err := e.post(ctx, batch); err != nil {
+			e.mu.Lock()
+			if !e.failed {
+				e.failed = true
+				slog.WarnContext(ctx, "Failed to export trace spans", "endpoint", e.endpoint, "err", err)
+			}
+			keep := min(len(batch), otlpMaxQueue-len(e.queue))
+			e.dropped += len(batch) - keep
+			e.queue = append(batch[:keep], e.queue...)
+			e.mu.Unlock()
+			return
+		}
+	}
+}
+
+func (e *spanExporter) post(ctx context.Context, spans []otlpSpan) error {
+	type scopeSpans struct {
+		Scope struct {
+			Name string `json:"name"`
+		} `json:"scope"`
+		Spans []otlpSpan `json:"spans"`
+	}
+	type resourceSpans struct {
+		Resource struct {
+			Attributes []otlpAttribute `json:"attributes"`
+		} `json:"resource"`
+		ScopeSpans []scopeSpans `json:"scopeSpans"`
+	}
+
+	var rs resourceSpans
+	rs.Resource.Attributes = e.resource
+	ss := scopeSpans{Spans: spans}
+	ss.Scope.Name = otelScopeName
+	rs.ScopeSpans = []scopeSpans{ss}
+
+	body, err := json.Marshal(struct {
+		ResourceSpans []resourceSpans `json:"resourceSpans"`
+	}{ResourceSpans: []resourceSpans{rs}})
+	if err != nil {
+		return fmt.Errorf("failed to encode spans: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, e.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create OTLP request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for key, value := range e.headers {
+		req.Header.Set(key, value)
+	}
+
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to post spans: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	_, _ = io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("%w: collector answered %s", errOTLPExport, resp.Status)
+	}
+
+	return nil
+}
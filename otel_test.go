@@ -0,0 +1,205 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+// testCollector is an OTLP/HTTP collector stand-in that keeps the spans it
+// receives.
+type testCollector struct {
+	mu      sync.Mutex
+	spans   []otlpSpan
+	service string
+	auth    string
+}
+
+func startTestCollector(t *testing.T) *testCollector {
+	t.Helper()
+
+	c := &testCollector{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != otlpTracesPath || r.Header.Get("Content-Type") != "application/json" {
+			http.Error(w, "unexpected request", http.StatusBadRequest)
+			return
+		}
+		var body struct {
+			ResourceSpans []struct {
+				Resource struct {
+					Attributes []otlpAttribute `json:"attributes"`
+				} `json:"resource"`
+				ScopeSpans []struct {
+					Spans []otlpSpan `json:"spans"`
+				} `json:"scopeSpans"`
+			} `json:"resourceSpans"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		c.auth = r.Header.Get("Authorization")
+		for _, rs := range body.ResourceSpans {
+			for _, attr := range rs.Resource.Attributes {
+				if attr.Key == "service.name" && attr.Value.StringValue != nil {
+					c.service = *attr.Value.StringValue
+				}
+			}
+			for _, ss := range rs.ScopeSpans {
+				c.spans = append(c.spans, ss.Spans...)
+			}
+		}
+	}))
+	t.Cleanup(server.Close)
+	t.Setenv(otlpEndpointEnv, server.URL+"/")
+
+	return c
+}
+
+func (c *testCollector) span(t *testing.T, name string) otlpSpan {
+	t.Helper()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, span := range c.spans {
+		if span.Name == name {
+			return span
+		}
+	}
+	t.Fatalf("collector has no span %q among %d", name, len(c.spans))
+
+	return otlpSpan{}
+}
+
+func TestSessionTelemetryExportsStartupPhases(t *testing.T) {
+	collector := startTestCollector(t)
+	t.Setenv(otlpHeadersEnv, "Authorization=Bearer%20secret")
+	t.Setenv(otelServiceNameEnv, "agent-gh")
+
+	telemetry, stop, err := startTelemetryFromEnv(t.Context())
+	if err != nil {
+		t.Fatalf("startTelemetryFromEnv failed: %v", err)
+	}
+	telemetry.phase(phaseAuth)(nil)
+	telemetry.phase(phaseChecksum)(errBundledChecksumMismatch)
+	stop()
+
+	startup := collector.span(t, "gh-mcp startup")
+	auth := collector.span(t, "gh-mcp auth")
+	checksum := collector.span(t, "gh-mcp checksum")
+	if auth.TraceID != startup.TraceID || auth.ParentSpanID != startup.SpanID || checksum.ParentSpanID != startup.SpanID {
+		t.Fatalf("phases are not children of the startup span: startup=%+v auth=%+v checksum=%+v", startup, auth, checksum)
+	}
+	if auth.Status.Code != spanStatusOK || checksum.Status.Code != spanStatusError || startup.Status.Code != spanStatusError {
+		t.Fatalf("unexpected statuses: startup=%+v auth=%+v checksum=%+v", startup.Status, auth.Status, checksum.Status)
+	}
+	if collector.service != "agent-gh" || collector.auth != "Bearer secret" {
+		t.Fatalf("collector got service %q and Authorization %q", collector.service, collector.auth)
+	}
+}
+
+func TestSpanExporterShutdownGivesUpOnUnresponsiveCollector(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(_ http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-release:
+		}
+	}))
+	t.Cleanup(server.Close)
+	t.Cleanup(func() { close(release) })
+	t.Setenv(otlpEndpointEnv, server.URL)
+
+	exporter, stop, err := startSpanExporterFromEnv()
+	if err != nil {
+		t.Fatalf("startSpanExporterFromEnv failed: %v", err)
+	}
+	exporter.flushTimeout = 50 * time.Millisecond
+	exporter.record(spanContext{}.child(), spanRecord{name: "gh-mcp startup", start: time.Now(), end: time.Now()})
+
+	started := time.Now()
+	stop()
+	if elapsed := time.Since(started); elapsed >= otlpExportTimeout/2 {
+		t.Fatalf("shutdown took %s, want it bounded by the flush timeout", elapsed)
+	}
+}
+
+func TestToolCallTelemetryContinuesClientTrace(t *testing.T) {
+	collector := startTestCollector(t)
+	spans, stop, err := startSpanExporterFromEnv()
+	if err != nil {
+		t.Fatalf("startSpanExporterFromEnv failed: %v", err)
+	}
+	proxy, _, _ := newTestProxy(t, defaultProxyMaxMessageSize,
+		&toolCallTelemetry{telemetry: newSessionTelemetry(nil, spans)})
+
+	ctx := context.Background()
+	traceID := "4bf92f3577b34da6a3ce929d0e0e4736"
+	proxy.handleClientLine(ctx, []byte(`{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"get_me",`+
+		`"_meta":{"traceparent":"00-`+traceID+`-00f067aa0ba902b7-01","tracestate":"vendor=1"}}}`))
+	proxy.handleClientLine(ctx, testToolCallLine(2, "get_issue"))
+	for _, response := range []string{
+		`{"jsonrpc":"2.0","id":1,"result":{"content":[]}}`,
+		`{"jsonrpc":"2.0","id":2,"error":{"code":-32603,"message":"boom"}}`,
+	} {
+		if err := proxy.handleServerLine(ctx, []byte(response+"\n")); err != nil {
+			t.Fatalf("handleServerLine failed: %v", err)
+		}
+	}
+	stop()
+
+	getMe := collector.span(t, "tools/call get_me")
+	if getMe.TraceID != traceID || getMe.ParentSpanID != "00f067aa0ba902b7" || getMe.TraceState != "vendor=1" ||
+		getMe.Kind != spanKindServer || getMe.Status.Code != spanStatusOK {
+		t.Fatalf("get_me span does not continue the client trace: %+v", getMe)
+	}
+	getIssue := collector.span(t, "tools/call get_issue")
+	if getIssue.TraceID == traceID || getIssue.ParentSpanID != "" || getIssue.Status.Code != spanStatusError {
+		t.Fatalf("get_issue span = %+v, want an errored root span", getIssue)
+	}
+}
+
+func TestParseTraceparent(t *testing.T) {
+	tests := []struct {
+		value     string
+		valid     bool
+		unsampled bool
+	}{
+		{value: "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", valid: true},
+		{value: "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00", valid: true, unsampled: true},
+		{value: "01-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra", valid: true},
+		{value: "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra"},
+		{value: "ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"},
+		{value: "00-00000000000000000000000000000000-00f067aa0ba902b7-01"},
+		{value: "00-4bf92f3577b34da6a3ce929d0e0e4736-zzf067aa0ba902b7-01"},
+		{value: ""},
+	}
+	for _, tt := range tests {
+		sc, ok := parseTraceparent(tt.value, "")
+		if ok != tt.valid || sc.unsampled != tt.unsampled {
+			t.Errorf("parseTraceparent(%q) = %+v, %v; want valid=%v unsampled=%v",
+				tt.value, sc, ok, tt.valid, tt.unsampled)
+		}
+	}
+}
+
+func TestStartSpanExporterFromEnvRejectsInvalidSettings(t *testing.T) {
+	t.Setenv(otlpEndpointEnv, "collector:4318")
+	if _, _, err := startSpanExporterFromEnv(); !errors.Is(err, errInvalidSetting) {
+		t.Fatalf("startSpanExporterFromEnv error = %v, want errInvalidSetting for an endpoint without scheme", err)
+	}
+
+	t.Setenv(otlpEndpointEnv, "http://127.0.0.1:4318")
+	t.Setenv(otlpHeadersEnv, "no-separator")
+	if _, _, err := startSpanExporterFromEnv(); !errors.Is(err, errInvalidSetting) {
+		t.Fatalf("startSpanExporterFromEnv error = %v, want errInvalidSetting for malformed headers", err)
+	}
+}
@@ -0,0 +1,276 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"maps"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+)
+
+const rpcCodeInvalidParams = -32602
+
+// toolNamePrefixPattern keeps prefixed names within the characters MCP
+// allows in tool names.
+var toolNamePrefixPattern = regexp.MustCompile(`^[A-Za-z0-9_.-]*$`)
+
+// toolOverridesConfig rewrites the tools clients see in tools/list.
+type toolOverridesConfig struct {
+	// Prefix is added to every tool name clients see and removed from their
+	// calls. Other policies keep using the unprefixed names.
+	Prefix string         `json:"prefix"`
+	Tools  []toolOverride `json:"tools"`
+}
+
+// toolOverride changes one tool's definition.
+type toolOverride struct {
+	Name string `json:"name"`
+	// Description replaces the tool's description.
+	Description string `json:"description"`
+	// AppendDescription is added after the description, replaced or not.
+	AppendDescription string `json:"appendDescription"`
+	// HideProperties are removed from the input schema and from calls.
+	HideProperties []string `json:"hideProperties"`
+}
+
+func (c toolOverridesConfig) empty() bool {
+	return c.Prefix == "" && len(c.Tools) == 0
+}
+
+func (c toolOverridesConfig) validate() error {
+	if !toolNamePrefixPattern.MatchString(c.Prefix) {
+		return fmt.Errorf("toolOverrides.prefix: %q must be letters, digits, '_', '-' and '.'", c.Prefix)
+	}
+
+	seen := make(map[string]bool, len(c.Tools))
+	for i, override := range c.Tools {
+		field := fmt.Sprintf("toolOverrides.tools[%d]", i)
+		if override.Name == "" {
+			return fmt.Errorf("%s.name: required", field)
+		}
+		if seen[override.Name] {
+			return fmt.Errorf("%s.name: duplicate tool %q", field, override.Name)
+		}
+		seen[override.Name] = true
+		if override.Description == "" && override.AppendDescription == "" && len(override.HideProperties) == 0 {
+			return fmt.Errorf("%s: overrides nothing", field)
+		}
+		for _, property := range override.HideProperties {
+			if property == "" {
+				return fmt.Errorf("%s.hideProperties: must not contain an empty name", field)
+			}
+		}
+	}
+
+	return nil
+}
+
+// toolOverrides applies the configured descriptions, hidden properties and
+// name prefix to tools/list, and reverses them on tools/call. Overrides are
+// checked against the first complete tools/list.
+type toolOverrides struct {
+	prefix string
+	tools  map[string]toolOverride
+
+	mu      sync.Mutex
+	checked bool
+	// listed holds the input properties of each tool listed so far, until the
+	// overrides are checked.
+	listed map[string][]string
+}
+
+// newToolOverrides returns nil when the config changes nothing.
+func newToolOverrides(cfg toolOverridesConfig) *toolOverrides {
+	if cfg.empty() {
+		return nil
+	}
+
+	o := &toolOverrides{prefix: cfg.Prefix, tools: make(map[string]toolOverride, len(cfg.Tools))}
+	for _, override := range cfg.Tools {
+		o.tools[override.Name] = override
+	}
+
+	return o
+}
+
+func (o *toolOverrides) clientToServer(p *stdioProxy, next proxyHandler) proxyHandler {
+	return func(ctx context.Context, msg *rpcMessage) error {
+		call, ok := msg.toolCall()
+		if !ok {
+			return next(ctx, msg)
+		}
+
+		name, ok := strings.CutPrefix(call.Name, o.prefix)
+		if !ok {
+			return p.replyError(ctx, msg, rpcCodeInvalidParams, "unknown tool "+call.Name, nil)
+		}
+		if o.prefix == "" && len(o.tools[name].HideProperties) == 0 {
+			return next(ctx, msg)
+		}
+
+		if err := o.restore(msg, name); err != nil {
+			slog.WarnContext(ctx, "Failed to restore tool call", "tool", call.Name, "err", err)
+			return p.replyError(ctx, msg, rpcCodeInternalError, "gh-mcp could not forward "+call.Name, nil)
+		}
+
+		return next(ctx, msg)
+	}
+}
+
+func (o *toolOverrides) serverToClient(p *stdioProxy, next proxyHandler) proxyHandler {
+	return func(ctx context.Context, msg *rpcMessage) error {
+		if !isToolListResponse(msg) {
+			return next(ctx, msg)
+		}
+
+		err := rewriteToolList(msg, func(tool map[string]json.RawMessage) bool {
+			o.apply(tool)
+			return true
+		})
+		if err != nil {
+			slog.WarnContext(ctx, "Failed to apply tool overrides to tools/list", "err", err)
+			return next(ctx, msg)
+		}
+		if !lastListPage(msg) {
+			return next(ctx, msg)
+		}
+
+		if err := o.check(); err != nil {
+			slog.ErrorContext(ctx, "Tool overrides do not match the server's tools", "err", err)
+			p.end(err)
+			return p.replyError(ctx, msg.call.request, rpcCodeInternalError,
+				"gh-mcp tool overrides do not match the server's tools", nil)
+		}
+
+		return next(ctx, msg)
+	}
+}
+
+// apply rewrites one tool of a tools/list result.
+func (o *toolOverrides) apply(tool map[string]json.RawMessage) {
+	name := toolName(tool)
+
+	var schema map[string]json.RawMessage
+	_ = json.Unmarshal(tool["inputSchema"], &schema)
+	var properties map[string]json.RawMessage
+	_ = json.Unmarshal(schema["properties"], &properties)
+	o.record(name, properties)
+
+	if override, ok := o.tools[name]; ok {
+		if len(override.HideProperties) > 0 && properties != nil {
+			for _, property := range override.HideProperties {
+				delete(properties, property)
+			}
+			schema["properties"], _ = json.Marshal(properties)
+
+			var required []string
+			if err := json.Unmarshal(schema["required"], &required); err == nil {
+				required = slices.DeleteFunc(required, func(name string) bool {
+					return slices.Contains(override.HideProperties, name)
+				})
+				schema["required"], _ = json.Marshal(required)
+			}
+			tool["inputSchema"], _ = json.Marshal(schema)
+		}
+
+		var description string
+		_ = json.Unmarshal(tool["description"], &description)
+		if override.Description != "" {
+			description = override.Description
+		}
+		if override.AppendDescription != "" {
+			if description != "" {
+				description += "\n\n"
+			}
+			description += override.AppendDescription
+		}
+		tool["description"], _ = json.Marshal(description)
+	}
+
+	tool["name"], _ = json.Marshal(o.prefix + name)
+}
+
+// record remembers a listed tool until the overrides are checked.
+func (o *toolOverrides) record(name string, properties map[string]json.RawMessage) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if o.checked {
+		return
+	}
+	if o.listed == nil {
+		o.listed = make(map[string][]string)
+	}
+	for property := range properties {
+		o.listed[name] = append(o.listed[name], property)
+	}
+	if _, ok := o.listed[name]; !ok {
+		o.listed[name] = nil
+	}
+}
+
+// check compares the overrides with the first complete tools/list, and fails
+// with errInvalidSetting when they name tools or properties it does not
+// contain. Later tool lists are not checked.
+func (o *toolOverrides) check() error {
+	o.mu.Lock()
+	if o.checked {
+		o.mu.Unlock()
+		return nil
+	}
+	o.checked = true
+	listed := o.listed
+	o.listed = nil
+	o.mu.Unlock()
+
+	if mismatches := toolOverrideMismatches(o.tools, listed); len(mismatches) > 0 {
+		return fmt.Errorf("%w: toolOverrides: %s", errInvalidSetting, strings.Join(mismatches, "; "))
+	}
+
+	return nil
+}
+
+// toolOverrideMismatches describes the overrides naming tools, or hiding
+// properties, that listed does not contain.
+func toolOverrideMismatches(tools map[string]toolOverride, listed map[string][]string) []string {
+	var mismatches []string
+	for _, name := range slices.Sorted(maps.Keys(tools)) {
+		properties, ok := listed[name]
+		if !ok {
+			mismatches = append(mismatches, fmt.Sprintf("tool %q is not listed", name))
+			continue
+		}
+		for _, property := range tools[name].HideProperties {
+			if !slices.Contains(properties, property) {
+				mismatches = append(mismatches, fmt.Sprintf("tool %q has no property %q", name, property))
+			}
+		}
+	}
+
+	return mismatches
+}
+
+// restore removes the prefix and hidden arguments from a tools/call request.
+func (o *toolOverrides) restore(msg *rpcMessage, name string) error {
+	var params map[string]json.RawMessage
+	if err := json.Unmarshal(msg.Params, &params); err != nil {
+		return fmt.Errorf("failed to decode tools/call params: %w", err)
+	}
+	params["name"], _ = json.Marshal(name)
+
+	if hidden := o.tools[name].HideProperties; len(hidden) > 0 {
+		var args map[string]json.RawMessage
+		if err := json.Unmarshal(params["arguments"], &args); err == nil && args != nil {
+			for _, property := range hidden {
+				delete(args, property)
+			}
+			params["arguments"], _ = json.Marshal(args)
+		}
+	}
+
+	return msg.setParams(params)
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func newTestToolOverrides() *toolOverrides {
+	return newToolOverrides(toolOverridesConfig{
+		Prefix: "gh_",
+		Tools: []toolOverride{
+			{Name: "get_issue", Description: "Read one issue.", HideProperties: []string{"owner"}},
+			{Name: "get_me", AppendDescription: "Use before anything else."},
+		},
+	})
+}
+
+func TestToolOverridesRewriteToolList(t *testing.T) {
+	proxy, _, client := newTestProxy(t, defaultProxyMaxMessageSize, newTestToolOverrides())
+	ctx := context.Background()
+
+	proxy.handleClientLine(ctx, []byte(`{"jsonrpc":"2.0","id":1,"method":"tools/list"}`))
+	line := `{"jsonrpc":"2.0","id":1,"result":{"tools":[` +
+		`{"name":"get_issue","description":"Get an issue.","inputSchema":{"type":"object",` +
+		`"properties":{"owner":{"type":"string"},"repo":{"type":"string"}},"required":["owner","repo"]}},` +
+		`{"name":"get_me","description":"Get me."}]}}` + "\n"
+	if err := proxy.handleServerLine(ctx, []byte(line)); err != nil {
+		t.Fatalf("handleServerLine failed: %v", err)
+	}
+
+	want := `"tools":[` +
+		`{"description":"Read one issue.","inputSchema":{"properties":{"repo":{"type":"string"}},"required":["repo"],"type":"object"},"name":"gh_get_issue"},` +
+		`{"description":"Get me.\n\nUse before anything else.","name":"gh_get_me"}]`
+	if got := client.String(); !strings.Contains(got, want) {
+		t.Fatalf("tools/list = %s, want %s", got, want)
+	}
+}
+
+func TestToolOverridesRestoreToolCall(t *testing.T) {
+	proxy, server, client := newTestProxy(t, defaultProxyMaxMessageSize, newTestToolOverrides())
+	ctx := context.Background()
+
+	proxy.handleClientLine(ctx, []byte(`{"jsonrpc":"2.0","id":1,"method":"tools/call",`+
+		`"params":{"name":"gh_get_issue","arguments":{"owner":"octo","repo":"project"}}}`))
+	proxy.handleClientLine(ctx, testToolCallLine(2, "get_issue"))
+
+	got, _ := server.snapshot()
+	want := `{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"arguments":{"repo":"project"},"name":"get_issue"}}` + "\n"
+	if got != want {
+		t.Fatalf("server received %q, want %q", got, want)
+	}
+	if !strings.Contains(client.String(), `"id":2,"error":{"code":-32602,"message":"unknown tool get_issue"}`) {
+		t.Fatalf("unprefixed call was not refused:\n%s", client.String())
+	}
+}
+
+func TestParseProxyConfigValidatesToolOverrides(t *testing.T) {
+	for _, overrides := range []string{
+		`{"prefix":"gh mcp"}`,
+		`{"tools":[{"description":"x"}]}`,
+		`{"tools":[{"name":"a","description":"x"},{"name":"a","appendDescription":"y"}]}`,
+		`{"tools":[{"name":"a"}]}`,
+		`{"tools":[{"name":"a","hideProperties":[""]}]}`,
+	} {
+		if _, err := parseProxyConfig([]byte(`{"toolOverrides":` + overrides + `}`)); err == nil {
+			t.Errorf("parseProxyConfig accepted toolOverrides %s", overrides)
+		}
+	}
+}
+
+func TestToolOverridesEndSessionWhenFirstToolListDoesNotMatch(t *testing.T) {
+	overrides := newToolOverrides(toolOverridesConfig{Tools: []toolOverride{
+		{Name: "get_issue", HideProperties: []string{"owner"}},
+		{Name: "missing", Description: "Gone."},
+	}})
+	proxy, _, client := newTestProxy(t, defaultProxyMaxMessageSize, overrides)
+	ctx, cancel := context.WithCancelCause(context.Background())
+	defer cancel(nil)
+	proxy.endSessionWith(cancel)
+
+	proxy.handleClientLine(ctx, []byte(`{"jsonrpc":"2.0","id":1,"method":"tools/list"}`))
+	line := `{"jsonrpc":"2.0","id":1,"result":{"tools":[{"name":"get_issue","inputSchema":{"type":"object"}}]}}`
+	if err := proxy.handleServerLine(ctx, []byte(line)); err != nil {
+		t.Fatalf("handleServerLine failed: %v", err)
+	}
+
+	if got := client.String(); !strings.Contains(got, `"id":1,"error":{"code":-32603`) || strings.Contains(got, `"tools"`) {
+		t.Fatalf("client received %s, want the tools/list refused", got)
+	}
+	err := proxy.endError()
+	if !errors.Is(err, errInvalidSetting) || !errors.Is(context.Cause(ctx), errInvalidSetting) {
+		t.Fatalf("session ended with %v (cause %v), want errInvalidSetting", err, context.Cause(ctx))
+	}
+	for _, want := range []string{`tool "get_issue" has no property "owner"`, `tool "missing" is not listed`} {
+		if !strings.Contains(err.Error(), want) {
+			t.Fatalf("error %q does not mention %s", err, want)
+		}
+	}
+	if got := exitCodeForError(err); got != exitCodeInvalidEnvironment {
+		t.Fatalf("exit code = %d, want %d", got, exitCodeInvalidEnvironment)
+	}
+}
@@ -0,0 +1,26 @@
+//go:build linux
+
+package main
+
+import (
+	"context"
+	"os/exec"
+	"syscall"
+)
+
+// configureServerParentDeathSignal asks the kernel (PR_SET_PDEATHSIG) to send
+// SIGTERM to the server if gh-mcp dies without stopping it. The signal is tied
+// to the OS thread that started the server; Go only retires threads locked by
+// exiting goroutines, which gh-mcp does not use.
+func configureServerParentDeathSignal(cmd *exec.Cmd) {
+	if cmd.SysProcAttr == nil {
+		cmd.SysProcAttr = &syscall.SysProcAttr{}
+	}
+	cmd.SysProcAttr.Pdeathsig = syscall.SIGTERM
+}
+
+// watchParentProcess is a no-op on Linux: the server is covered by
+// PR_SET_PDEATHSIG, and a dead client closes gh-mcp's stdin.
+func watchParentProcess(_ context.Context, _ func()) func() {
+	return func() {}
+}
@@ -0,0 +1,22 @@
+//go:build linux
+
+package main
+
+import (
+	"os/exec"
+	"syscall"
+	"testing"
+)
+
+func TestConfigureServerParentDeathSignal(t *testing.T) {
+	cmd := exec.Command("github-mcp-server")
+	configureServerProcessGroup(cmd, signalRelayConfig{processGroup: true})
+	configureServerParentDeathSignal(cmd)
+
+	if cmd.SysProcAttr.Pdeathsig != syscall.SIGTERM {
+		t.Fatalf("Pdeathsig = %v, want SIGTERM", cmd.SysProcAttr.Pdeathsig)
+	}
+	if !cmd.SysProcAttr.Setpgid {
+		t.Fatal("expected existing process group setting to be preserved")
+	}
+}
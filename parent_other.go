@@ -0,0 +1,40 @@
+//go:build !linux && !windows
+
+package main
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"time"
+)
+
+const parentWatchInterval = time.Second
+
+func configureServerParentDeathSignal(_ *exec.Cmd) {}
+
+// watchParentProcess calls onExit once gh-mcp is re-parented, which means the
+// MCP client that launched it has exited. It returns a function that stops watching.
+func watchParentProcess(ctx context.Context, onExit func()) func() {
+	initial := os.Getppid()
+	ctx, cancel := context.WithCancel(ctx)
+
+	go func() {
+		ticker := time.NewTicker(parentWatchInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if os.Getppid() != initial {
+					onExit()
+					return
+				}
+			}
+		}
+	}()
+
+	return cancel
+}
@@ -0,0 +1,96 @@
+//go:build windows
+
+package main
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"os/exec"
+	"time"
+
+	"golang.org/x/sys/windows"
+)
+
+const parentWatchInterval = time.Second
+
+func configureServerParentDeathSignal(_ *exec.Cmd) {}
+
+// watchParentProcess calls onExit once the process that launched gh-mcp exits.
+// Windows does not re-parent orphans, so it waits on a handle to the parent.
+// It returns a function that stops watching.
+func watchParentProcess(ctx context.Context, onExit func()) func() {
+	ppid := os.Getppid()
+	if ppid <= 0 {
+		return func() {}
+	}
+
+	// #nosec G115 -- process IDs are non-negative and fit in uint32 on Windows
+	handle, err := windows.OpenProcess(
+		windows.SYNCHRONIZE|windows.PROCESS_QUERY_LIMITED_INFORMATION,
+		false,
+		uint32(ppid),
+	)
+	if err != nil {
+		slog.DebugContext(ctx, "Parent process watch unavailable", "ppid", ppid, "err", err)
+		return func() {}
+	}
+
+	// The parent's PID may already belong to a newer process; a parent
+	// created after gh-mcp cannot be the one that launched it.
+	if startedAfterCurrentProcess(handle) {
+		_ = windows.CloseHandle(handle)
+		slog.DebugContext(ctx, "Parent process ID was reused", "ppid", ppid)
+		onExit()
+		return func() {}
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	done := make(chan struct{})
+
+	go func() {
+		defer close(done)
+		defer func() { _ = windows.CloseHandle(handle) }()
+
+		timeout := uint32(parentWatchInterval / time.Millisecond)
+		for ctx.Err() == nil {
+			event, err := windows.WaitForSingleObject(handle, timeout)
+			if err != nil {
+				return
+			}
+			if event == windows.WAIT_OBJECT_0 {
+				onExit()
+				return
+			}
+		}
+	}()
+
+	return func() {
+		cancel()
+		<-done
+	}
+}
+
+// startedAfterCurrentProcess reports whether the process behind handle was
+// created after gh-mcp. It returns false when either creation time is unknown.
+func startedAfterCurrentProcess(handle windows.Handle) bool {
+	created, ok := processCreationTime(handle)
+	if !ok {
+		return false
+	}
+	self, ok := processCreationTime(windows.CurrentProcess())
+	if !ok {
+		return false
+	}
+
+	return created.Nanoseconds() > self.Nanoseconds()
+}
+
+func processCreationTime(handle windows.Handle) (windows.Filetime, bool) {
+	var creation, exit, kernel, user windows.Filetime
+	if err := windows.GetProcessTimes(handle, &creation, &exit, &kernel, &user); err != nil {
+		return windows.Filetime{}, false
+	}
+
+	return creation, true
+}
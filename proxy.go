@@ -0,0 +1,923 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	proxyMaxMessageSizeEnv     = "GH_MCP_PROXY_MAX_MESSAGE_SIZE"
+	defaultProxyMaxMessageSize = 64 << 20 // 64 MiB
+
+	proxyReadBufferSize = 64 << 10 // 64 KiB
+	// oversizedHeadSize is how much of a refused oversized line is kept to
+	// find its ID.
+	oversizedHeadSize = 4 << 10 // 4 KiB
+	// proxyRequestIDPrefix marks requests the proxy itself sends, so their
+	// responses are consumed instead of forwarded.
+	proxyRequestIDPrefix = "gh-mcp-"
+)
+
+var (
+	// errProxyServerStopped is returned to proxy-originated requests whose server stopped.
+	errProxyServerStopped = errors.New("github-mcp-server stopped before responding")
+	// errRequestCancelled is the cause of a call's context once it is cancelled.
+	errRequestCancelled = errors.New("request was cancelled")
+)
+
+// proxyHandler processes one message travelling in one direction.
+type proxyHandler func(ctx context.Context, msg *rpcMessage) error
+
+// proxyMiddleware observes or rewrites MCP traffic. A middleware either calls
+// next, possibly with a modified message, or answers the message itself.
+type proxyMiddleware interface {
+	clientToServer(p *stdioProxy, next proxyHandler) proxyHandler
+	serverToClient(p *stdioProxy, next proxyHandler) proxyHandler
+}
+
+// answerObserver is implemented by middlewares that report each client
+// request once, with the response that settled it.
+type answerObserver interface {
+	observeAnswer(ctx context.Context, resp *rpcMessage)
+}
+
+// passthroughMiddleware forwards both directions unchanged. Middlewares embed
+// it and override only the direction they handle.
+type passthroughMiddleware struct{}
+
+func (passthroughMiddleware) clientToServer(_ *stdioProxy, next proxyHandler) proxyHandler {
+	return next
+}
+
+func (passthroughMiddleware) serverToClient(_ *stdioProxy, next proxyHandler) proxyHandler {
+	return next
+}
+
+// pendingCall is a client request and when the proxy received it. While the
+// server has not answered, it is kept in the proxy's pending map.
+type pendingCall struct {
+	request *rpcMessage
+	started time.Time
+
+	// response is the first response that settled the call; later ones are dropped.
+	response atomic.Pointer[rpcMessage]
+	// withdrawn is set when the client cancelled the request and expects no response.
+	withdrawn atomic.Bool
+	// forwarded is set once the request was sent to github-mcp-server rather
+	// than answered by a middleware or another server.
+	forwarded atomic.Bool
+
+	cancelOnce sync.Once
+	cancelled  chan struct{}
+}
+
+func newPendingCall(request *rpcMessage) *pendingCall {
+	return &pendingCall{request: request, started: time.Now(), cancelled: make(chan struct{})}
+}
+
+// answered reports whether a response already settled the call.
+func (c *pendingCall) answered() bool {
+	return c.response.Load() != nil
+}
+
+// cancel tells middlewares holding the call to give up on it.
+func (c *pendingCall) cancel() {
+	c.cancelOnce.Do(func() { close(c.cancelled) })
+}
+
+// context returns a context that is also cancelled when the call is.
+func (c *pendingCall) context(ctx context.Context) (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancelCause(ctx)
+	go func() {
+		select {
+		case <-c.cancelled:
+			cancel(errRequestCancelled)
+		case <-ctx.Done():
+		}
+	}()
+
+	return ctx, func() { cancel(nil) }
+}
+
+// proxySession identifies one gh-mcp run in logs and audit records.
+type proxySession struct {
+	id   string
+	host string
+	// account identifies the host and credentials without revealing them, to
+	// keep data persisted for one account from being served to another.
+	account string
+	// tokenType is the kind of GitHub token in use, such as "oauth".
+	tokenType string
+	// settings are the GITHUB_* variables forwarded to the server, except the token.
+	settings map[string]string
+}
+
+func newProxySession(serverEnv []string) (proxySession, error) {
+	var id [8]byte
+	if _, err := rand.Read(id[:]); err != nil {
+		return proxySession{}, fmt.Errorf("failed to read random bytes for session ID: %w", err)
+	}
+
+	host := serverEnvValue(serverEnv, "GITHUB_HOST")
+	token := serverEnvValue(serverEnv, "GITHUB_PERSONAL_ACCESS_TOKEN")
+	account := sha256.Sum256([]byte(host + "\x00" + token))
+	settings := make(map[string]string)
+	for _, item := range serverEnv {
+		key, value, ok := strings.Cut(item, "=")
+		if ok && strings.HasPrefix(key, "GITHUB_") && key != "GITHUB_PERSONAL_ACCESS_TOKEN" {
+			settings[key] = value
+		}
+	}
+
+	return proxySession{
+		id:        hex.EncodeToString(id[:]),
+		host:      host,
+		account:   hex.EncodeToString(account[:8]),
+		tokenType: githubTokenType(token),
+		settings:  settings,
+	}, nil
+}
+
+// stdioProxy sits between the MCP client and github-mcp-server. It frames
+// newline-delimited JSON-RPC in both directions, tracks pending requests and
+// runs the middleware chain. Lines that are not JSON-RPC, and lines longer than
+// the size limit, are passed through untouched when there are no middlewares;
+// otherwise they are refused so no policy can be bypassed.
+type stdioProxy struct {
+	maxMessageSize int
+	middlewares    []proxyMiddleware
+	observers      []answerObserver
+	toServer       proxyHandler
+	toClient       proxyHandler
+
+	server *proxyOutput
+	client *proxyOutput
+	stdin  *stdinForwarder
+	// fromClient and fromServer observe the frames read from each side.
+	fromClient frameTap
+	fromServer frameTap
+
+	mu      sync.Mutex
+	pending map[string]*pendingCall
+	waiters map[string]chan *rpcMessage
+	// clientWaiters are requests the proxy sent to the client.
+	clientWaiters map[string]chan *rpcMessage
+	// inbound are client requests the client has no response for yet.
+	inbound    map[string]*pendingCall
+	nextID     uint64
+	initialize *rpcMessage
+	// ended is the error a middleware ended the session with; stop ends it.
+	ended error
+	stop  context.CancelCauseFunc
+}
+
+// proxyOutput serializes writes to one side. A line streamed in chunks holds
+// the lock until its last chunk so no other message lands inside it.
+type proxyOutput struct {
+	mu        sync.Mutex
+	w         io.Writer
+	streaming bool
+	tap       frameTap
+}
+
+// frameTap reports the frames passing in one direction to the protocol
+// tracer and the session metrics, either of which may be nil.
+type frameTap struct {
+	direction string
+	trace     *protocolTracer
+	metrics   *sessionMetrics
+}
+
+func (t frameTap) line(data []byte) {
+	t.trace.line(t.direction, data)
+	t.metrics.addBytes(t.direction, len(data))
+}
+
+func (t frameTap) chunk(data []byte, last bool) {
+	t.trace.chunk(t.direction, data, last)
+	t.metrics.addBytes(t.direction, len(data))
+}
+
+func proxyMaxMessageSizeFromEnv() (int, error) {
+	value := os.Getenv(proxyMaxMessageSizeEnv)
+	if value == "" {
+		return defaultProxyMaxMessageSize, nil
+	}
+
+	size, err := parseByteSize(value)
+	if err != nil || size > math.MaxInt {
+		return 0, fmt.Errorf("%w: %s=%q is not a valid size", errInvalidSetting, proxyMaxMessageSizeEnv, value)
+	}
+
+	return int(size), nil
+}
+
+// newStdioProxy returns a proxy that writes client messages to stdin and
+// server messages to client. Middlewares see client messages in order and
+// server messages in reverse order.
+func newStdioProxy(
+	stdin *stdinForwarder,
+	client io.Writer,
+	maxMessageSize int,
+	middlewares ...proxyMiddleware,
+) *stdioProxy {
+	p := &stdioProxy{
+		maxMessageSize: maxMessageSize,
+		middlewares:    middlewares,
+		server:         &proxyOutput{w: stdin},
+		client:         &proxyOutput{w: client},
+		stdin:          stdin,
+		pending:        make(map[string]*pendingCall),
+		waiters:        make(map[string]chan *rpcMessage),
+		clientWaiters:  make(map[string]chan *rpcMessage),
+		inbound:        make(map[string]*pendingCall),
+	}
+
+	p.toServer = p.forwardToServer
+	p.toClient = p.forwardToClient
+	for i := len(middlewares) - 1; i >= 0; i-- {
+		p.toServer = middlewares[i].clientToServer(p, p.toServer)
+	}
+	for _, m := range middlewares {
+		p.toClient = m.serverToClient(p, p.toClient)
+		if o, ok := m.(answerObserver); ok {
+			p.observers = append(p.observers, o)
+		}
+	}
+
+	return p
+}
+
+// observe reports every frame the proxy reads and writes to trace and
+// metrics, either of which may be nil. It must be called before the proxy
+// serves either side.
+func (p *stdioProxy) observe(trace *protocolTracer, metrics *sessionMetrics) {
+	p.fromClient = frameTap{direction: traceFromClient, trace: trace, metrics: metrics}
+	p.fromServer = frameTap{direction: traceFromServer, trace: trace, metrics: metrics}
+	p.server.tap = frameTap{direction: traceToServer, trace: trace, metrics: metrics}
+	p.client.tap = frameTap{direction: traceToClient, trace: trace, metrics: metrics}
+}
+
+// endSessionWith lets middlewares end the session by calling stop. It must be
+// called before the proxy serves either side.
+func (p *stdioProxy) endSessionWith(stop context.CancelCauseFunc) {
+	p.stop = stop
+}
+
+// end stops the session because of err, which gh-mcp then exits with.
+func (p *stdioProxy) end(err error) {
+	p.mu.Lock()
+	if p.ended == nil {
+		p.ended = err
+	}
+	stop := p.stop
+	p.mu.Unlock()
+
+	if stop != nil {
+		stop(err)
+	}
+}
+
+// endError returns the error a middleware ended the session with, if any.
+func (p *stdioProxy) endError() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return p.ended
+}
+
+// serveClient forwards client input until the client closes its stdin.
+func (p *stdioProxy) serveClient(ctx context.Context, src io.Reader) {
+	var refused oversizedFrame
+	err := readFrames(src, p.maxMessageSize, func(line []byte) error {
+		p.fromClient.line(line)
+		p.handleClientLine(ctx, line)
+		return nil
+	}, func(chunk []byte, last bool) error {
+		p.fromClient.chunk(chunk, last)
+		if len(p.middlewares) == 0 {
+			return p.server.stream(chunk, last)
+		}
+		refused.add(chunk)
+		if last {
+			p.refuseOversizedRequest(ctx, refused.take())
+		}
+		return nil
+	})
+	if err != nil {
+		slog.DebugContext(ctx, "Stopped reading client stdin", "err", err)
+	}
+
+	p.stdin.finish()
+}
+
+// serveServer forwards one server process's output until it closes stdout.
+// Output the client can no longer receive is discarded so the server never
+// blocks on a full pipe.
+func (p *stdioProxy) serveServer(ctx context.Context, src io.Reader) {
+	var refused oversizedFrame
+	err := readFrames(src, p.maxMessageSize, func(line []byte) error {
+		p.fromServer.line(line)
+		if err := p.handleServerLine(ctx, line); err != nil {
+			slog.DebugContext(ctx, "Dropped github-mcp-server output", "err", err)
+		}
+		return nil
+	}, func(chunk []byte, last bool) error {
+		p.fromServer.chunk(chunk, last)
+		if len(p.middlewares) > 0 {
+			refused.add(chunk)
+			if last {
+				p.dropOversizedResponse(ctx, refused.take())
+			}
+			return nil
+		}
+		if err := p.client.stream(chunk, last); err != nil {
+			slog.DebugContext(ctx, "Dropped github-mcp-server output", "err", err)
+		}
+		return nil
+	})
+	if err != nil {
+		slog.DebugContext(ctx, "Stopped reading github-mcp-server output", "err", err)
+	}
+}
+
+func (p *stdioProxy) handleClientLine(ctx context.Context, line []byte) {
+	msgs, batch, err := parseRPCLine(line)
+	if err != nil {
+		if len(p.middlewares) == 0 {
+			// Let the server report malformed input itself.
+			_ = p.server.write(line)
+			return
+		}
+		p.refuseMalformedRequest(ctx, line)
+		return
+	}
+
+	forward := msgs[:0]
+	for _, msg := range msgs {
+		if msg.isResponse() && p.deliverToClientWaiter(msg) {
+			continue
+		}
+		if msg.isRequest() {
+			msg.call = newPendingCall(msg)
+			p.mu.Lock()
+			p.inbound[msg.idKey()] = msg.call
+			p.mu.Unlock()
+		}
+		if msg.isNotification() && msg.Method == methodCancelled {
+			p.withdraw(ctx, msg)
+		}
+		forward = append(forward, msg)
+	}
+
+	if batch && len(p.middlewares) == 0 && len(forward) == len(msgs) {
+		for _, msg := range msgs {
+			p.trackRequest(msg)
+		}
+		_ = p.server.write(line)
+		return
+	}
+
+	// With middlewares, batch entries are handled and forwarded one by one.
+	for _, msg := range forward {
+		if err := p.toServer(ctx, msg); err != nil {
+			slog.WarnContext(ctx, "Failed to forward client message", "method", msg.Method, "err", err)
+		}
+	}
+}
+
+func (p *stdioProxy) handleServerLine(ctx context.Context, line []byte) error {
+	msgs, batch, err := parseRPCLine(line)
+	if err != nil {
+		if len(p.middlewares) > 0 {
+			return fmt.Errorf("refused output that is not JSON-RPC: %w", err)
+		}
+		return p.client.write(line)
+	}
+
+	forward := msgs[:0]
+	for _, msg := range msgs {
+		if msg.isResponse() && p.deliverToWaiter(msg) {
+			continue
+		}
+		if msg.isResponse() {
+			msg.call = p.takePending(msg.idKey())
+		}
+		forward = append(forward, msg)
+	}
+
+	if batch && len(p.middlewares) == 0 && len(forward) == len(msgs) {
+		for _, msg := range msgs {
+			if msg.call != nil {
+				p.answer(ctx, msg)
+			}
+		}
+		return p.client.write(line)
+	}
+
+	for _, msg := range forward {
+		if err := p.toClient(ctx, msg); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// forwardToServer is the end of the client-to-server chain.
+func (p *stdioProxy) forwardToServer(ctx context.Context, msg *rpcMessage) error {
+	if msg.call != nil && msg.call.withdrawn.Load() {
+		// The client cancelled the request while a middleware held it. Answer it
+		// so middlewares release what they hold; the answer is not delivered.
+		return p.replyError(ctx, msg, rpcCodeInternalError, errRequestCancelled.Error(), nil)
+	}
+
+	data, err := msg.bytes()
+	if err != nil {
+		return err
+	}
+
+	p.trackRequest(msg)
+	if err := p.server.write(data); err != nil {
+		return err
+	}
+
+	if msg.isRequest() && msg.Method == methodInitialize {
+		p.mu.Lock()
+		p.initialize = msg
+		p.mu.Unlock()
+	}
+
+	return nil
+}
+
+// forwardToClient is the end of the server-to-client chain.
+func (p *stdioProxy) forwardToClient(ctx context.Context, msg *rpcMessage) error {
+	if msg.call != nil && !p.answer(ctx, msg) {
+		slog.DebugContext(ctx, "Dropped response to an answered or cancelled request", "id", msg.idKey())
+		return nil
+	}
+
+	data, err := msg.bytes()
+	if err != nil {
+		return err
+	}
+
+	return p.client.write(data)
+}
+
+// answer settles resp.call with resp and reports whether resp goes to the
+// client. Only the first response settles a call, and only that one is
+// reported to observers, including when the client cancelled the request.
+func (p *stdioProxy) answer(ctx context.Context, resp *rpcMessage) bool {
+	call := resp.call
+	if !call.response.CompareAndSwap(nil, resp) {
+		return false
+	}
+
+	p.forgetInbound(call)
+	for _, o := range p.observers {
+		o.observeAnswer(ctx, resp)
+	}
+
+	return !call.withdrawn.Load()
+}
+
+func (p *stdioProxy) forgetInbound(call *pendingCall) {
+	key := call.request.idKey()
+
+	p.mu.Lock()
+	if p.inbound[key] == call {
+		delete(p.inbound, key)
+	}
+	p.mu.Unlock()
+}
+
+// withdraw handles a client's notifications/cancelled. The request's response
+// is dropped and middlewares holding it are told to give up. The notification
+// itself still goes to the server.
+func (p *stdioProxy) withdraw(ctx context.Context, msg *rpcMessage) {
+	var params struct {
+		RequestID json.RawMessage `json:"requestId"`
+	}
+	if err := json.Unmarshal(msg.Params, &params); err != nil {
+		return
+	}
+
+	p.mu.Lock()
+	call := p.inbound[string(bytes.TrimSpace(params.RequestID))]
+	p.mu.Unlock()
+	if call == nil {
+		return
+	}
+
+	slog.DebugContext(ctx, "Client cancelled request", "id", call.request.idKey(), "method", call.request.Method)
+	call.withdrawn.Store(true)
+	p.forgetInbound(call)
+	call.cancel()
+}
+
+// notifyServer sends a notification of the proxy's own to the server.
+func (p *stdioProxy) notifyServer(method string, params any) error {
+	msg, err := newRPCRequest(nil, method, params)
+	if err != nil {
+		return err
+	}
+	data, err := msg.bytes()
+	if err != nil {
+		return err
+	}
+
+	return p.server.write(data)
+}
+
+func (p *stdioProxy) trackRequest(msg *rpcMessage) {
+	if msg.call == nil {
+		return
+	}
+	msg.call.forwarded.Store(true)
+
+	p.mu.Lock()
+	p.pending[msg.idKey()] = msg.call
+	p.mu.Unlock()
+}
+
+func (p *stdioProxy) takePending(key string) *pendingCall {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	call := p.pending[key]
+	delete(p.pending, key)
+
+	return call
+}
+
+func (p *stdioProxy) deliverToWaiter(msg *rpcMessage) bool {
+	p.mu.Lock()
+	ch, ok := p.waiters[msg.idKey()]
+	delete(p.waiters, msg.idKey())
+	p.mu.Unlock()
+
+	if ok {
+		ch <- msg
+	}
+
+	return ok
+}
+
+func (p *stdioProxy) deliverToClientWaiter(msg *rpcMessage) bool {
+	p.mu.Lock()
+	ch, ok := p.clientWaiters[msg.idKey()]
+	delete(p.clientWaiters, msg.idKey())
+	p.mu.Unlock()
+
+	if ok {
+		ch <- msg
+	}
+
+	return ok
+}
+
+// requestClient sends a request of the proxy's own to the client and waits
+// for the response.
+func (p *stdioProxy) requestClient(ctx context.Context, method string, params any) (*rpcMessage, error) {
+	id := p.newRequestID()
+	req, err := newRPCRequest(id, method, params)
+	if err != nil {
+		return nil, err
+	}
+	data, err := req.bytes()
+	if err != nil {
+		return nil, err
+	}
+
+	ch := make(chan *rpcMessage, 1)
+	p.mu.Lock()
+	p.clientWaiters[string(id)] = ch
+	p.mu.Unlock()
+	defer func() {
+		p.mu.Lock()
+		delete(p.clientWaiters, string(id))
+		p.mu.Unlock()
+	}()
+
+	if err := p.client.write(data); err != nil {
+		return nil, err
+	}
+
+	select {
+	case resp := <-ch:
+		return resp, nil
+	case <-ctx.Done():
+		return nil, fmt.Errorf("no response from MCP client to %s: %w", method, context.Cause(ctx))
+	}
+}
+
+// clientCapability returns the named capability the client declared in its
+// initialize request, or nil.
+func (p *stdioProxy) clientCapability(name string) json.RawMessage {
+	p.mu.Lock()
+	initialize := p.initialize
+	p.mu.Unlock()
+
+	if initialize == nil {
+		return nil
+	}
+
+	var params struct {
+		Capabilities map[string]json.RawMessage `json:"capabilities"`
+	}
+	if err := json.Unmarshal(initialize.Params, &params); err != nil {
+		return nil
+	}
+
+	return params.Capabilities[name]
+}
+
+// newRequestID returns an ID for a request the proxy sends itself.
+func (p *stdioProxy) newRequestID() json.RawMessage {
+	p.mu.Lock()
+	p.nextID++
+	id := p.nextID
+	p.mu.Unlock()
+
+	return json.RawMessage(strconv.Quote(proxyRequestIDPrefix + strconv.FormatUint(id, 10)))
+}
+
+// expectResponse registers id so its response is delivered on the returned
+// channel instead of being forwarded to the client.
+func (p *stdioProxy) expectResponse(id json.RawMessage) <-chan *rpcMessage {
+	ch := make(chan *rpcMessage, 1)
+
+	p.mu.Lock()
+	p.waiters[string(id)] = ch
+	p.mu.Unlock()
+
+	return ch
+}
+
+// reply answers a client request with result without involving the server.
+// The response still passes through the server-to-client middlewares.
+func (p *stdioProxy) reply(ctx context.Context, req *rpcMessage, result json.RawMessage) error {
+	resp := newRPCResponse(req.ID, result)
+	resp.call = req.call
+
+	return p.toClient(ctx, resp)
+}
+
+// replyError answers a client request without involving the server. The
+// response still passes through the server-to-client middlewares.
+func (p *stdioProxy) replyError(
+	ctx context.Context,
+	req *rpcMessage,
+	code int,
+	message string,
+	data any,
+) error {
+	resp, err := newRPCErrorResponse(req.ID, code, message, data)
+	if err != nil {
+		return err
+	}
+	resp.call = req.call
+
+	return p.toClient(ctx, resp)
+}
+
+// replaySession re-sends the client's initialize handshake to a restarted
+// server through w, before client input is attached. The server's reply to
+// the replayed initialize is consumed by the proxy.
+func (p *stdioProxy) replaySession(w io.Writer) error {
+	p.mu.Lock()
+	initialize := p.initialize
+	p.mu.Unlock()
+
+	if initialize == nil {
+		return nil
+	}
+
+	id := p.newRequestID()
+	req, err := newRPCRequest(id, methodInitialize, initialize.Params)
+	if err != nil {
+		return err
+	}
+	initialized, err := newRPCRequest(nil, methodInitialized, nil)
+	if err != nil {
+		return err
+	}
+
+	p.expectResponse(id)
+	for _, msg := range []*rpcMessage{req, initialized} {
+		data, err := msg.bytes()
+		if err != nil {
+			return err
+		}
+		p.server.tap.line(data)
+		if _, err := w.Write(data); err != nil {
+			return fmt.Errorf("failed to replay MCP session to github-mcp-server: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// oversizedFrame keeps the start of a refused oversized line.
+type oversizedFrame struct {
+	head []byte
+}
+
+func (f *oversizedFrame) add(chunk []byte) {
+	if room := oversizedHeadSize - len(f.head); room > 0 {
+		f.head = append(f.head, chunk[:min(room, len(chunk))]...)
+	}
+}
+
+func (f *oversizedFrame) take() []byte {
+	head := f.head
+	f.head = nil
+	return head
+}
+
+// refuseMalformedRequest answers a client line that is not JSON-RPC, which
+// no middleware could inspect. Blank lines are ignored.
+func (p *stdioProxy) refuseMalformedRequest(ctx context.Context, line []byte) {
+	trimmed := bytes.TrimSpace(line)
+	if len(trimmed) == 0 {
+		return
+	}
+
+	code, message := rpcCodeParseError, "Parse error"
+	if json.Valid(trimmed) {
+		code, message = rpcCodeInvalidRequest, "Invalid Request"
+	}
+	slog.WarnContext(ctx, "Refused client message that is not JSON-RPC")
+	p.replyRefused(ctx, frameID(trimmed), code, message)
+}
+
+// refuseOversizedRequest answers a client line longer than the size limit,
+// which was discarded unread.
+func (p *stdioProxy) refuseOversizedRequest(ctx context.Context, head []byte) {
+	slog.WarnContext(ctx, "Refused client message larger than the size limit", "limit", p.maxMessageSize)
+	p.replyRefused(ctx, frameID(head), rpcCodeInvalidRequest,
+		"message exceeds "+proxyMaxMessageSizeEnv)
+}
+
+func (p *stdioProxy) replyRefused(ctx context.Context, id json.RawMessage, code int, message string) {
+	if len(id) == 0 {
+		id = json.RawMessage("null")
+	}
+	if err := p.replyError(ctx, &rpcMessage{ID: id}, code, message, nil); err != nil {
+		slog.DebugContext(ctx, "Failed to refuse client message", "err", err)
+	}
+}
+
+// dropOversizedResponse fails the client request a server line longer than
+// the size limit answered, if its ID could be read.
+func (p *stdioProxy) dropOversizedResponse(ctx context.Context, head []byte) {
+	slog.WarnContext(ctx, "Dropped github-mcp-server message larger than the size limit", "limit", p.maxMessageSize)
+
+	id := frameID(head)
+	if len(id) == 0 {
+		return
+	}
+	call := p.takePending(string(bytes.TrimSpace(id)))
+	if call == nil {
+		return
+	}
+	if err := p.replyError(ctx, call.request, rpcCodeInternalError,
+		"github-mcp-server response exceeds "+proxyMaxMessageSizeEnv, nil); err != nil {
+		slog.DebugContext(ctx, "Failed to fail oversized response", "err", err)
+	}
+}
+
+// serverStopped fails requests the stopped server will never answer.
+func (p *stdioProxy) serverStopped(ctx context.Context) {
+	p.mu.Lock()
+	pending := p.pending
+	waiters := p.waiters
+	p.pending = make(map[string]*pendingCall)
+	p.waiters = make(map[string]chan *rpcMessage)
+	p.mu.Unlock()
+
+	for _, call := range pending {
+		if err := p.replyError(ctx, call.request, rpcCodeInternalError, errProxyServerStopped.Error(), nil); err != nil {
+			slog.DebugContext(ctx, "Failed to fail pending request", "method", call.request.Method, "err", err)
+		}
+	}
+	for _, ch := range waiters {
+		close(ch)
+	}
+}
+
+func (o *proxyOutput) write(data []byte) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	o.tap.line(data)
+	if _, err := o.w.Write(data); err != nil {
+		return fmt.Errorf("failed to write MCP message: %w", err)
+	}
+
+	return nil
+}
+
+// stream writes one chunk of an oversized line; last releases the output.
+func (o *proxyOutput) stream(chunk []byte, last bool) error {
+	if !o.streaming {
+		o.mu.Lock()
+		o.streaming = true
+	}
+	defer func() {
+		if last {
+			o.streaming = false
+			o.mu.Unlock()
+		}
+	}()
+
+	o.tap.chunk(chunk, last)
+	if len(chunk) == 0 {
+		return nil
+	}
+	if _, err := o.w.Write(chunk); err != nil {
+		return fmt.Errorf("failed to write MCP message: %w", err)
+	}
+
+	return nil
+}
+
+// readFrames splits r into newline-terminated lines. Lines up to maxSize bytes
+// go to onLine whole; longer lines are passed to onOversized in chunks as they
+// arrive so they never have to fit in memory. A final unterminated line is
+// delivered at EOF.
+func readFrames(
+	r io.Reader,
+	maxSize int,
+	onLine func([]byte) error,
+	onOversized func(chunk []byte, last bool) error,
+) error {
+	br := bufio.NewReaderSize(r, proxyReadBufferSize)
+
+	var line []byte
+	oversized := false
+	for {
+		chunk, err := br.ReadSlice('\n')
+		complete := err == nil
+
+		var cbErr error
+		switch {
+		case oversized:
+			cbErr = onOversized(chunk, complete)
+			oversized = !complete
+		case len(line)+len(chunk) > maxSize:
+			cbErr = onOversized(append(line, chunk...), complete)
+			line = nil
+			oversized = !complete
+		default:
+			line = append(line, chunk...)
+			if complete {
+				cbErr = onLine(line)
+				line = nil
+			}
+		}
+		if cbErr != nil {
+			if oversized {
+				_ = onOversized(nil, true)
+			}
+			return cbErr
+		}
+
+		if err == nil || errors.Is(err, bufio.ErrBufferFull) {
+			continue
+		}
+
+		if len(line) > 0 {
+			if cbErr := onLine(line); cbErr != nil {
+				return cbErr
+			}
+		}
+		if oversized {
+			if cbErr := onOversized(nil, true); cbErr != nil {
+				return cbErr
+			}
+		}
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		return fmt.Errorf("failed to read MCP stream: %w", err)
+	}
+}
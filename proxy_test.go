@@ -0,0 +1,284 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// lockedBuffer is a bytes.Buffer safe for concurrent writers.
+type lockedBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *lockedBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.buf.Write(p)
+}
+
+func (b *lockedBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.buf.String()
+}
+
+// newTestProxy returns a proxy whose server side writes to the returned recorder.
+func newTestProxy(
+	t *testing.T,
+	maxMessageSize int,
+	middlewares ...proxyMiddleware,
+) (*stdioProxy, *recordingWriteCloser, *lockedBuffer) {
+	t.Helper()
+
+	server := &recordingWriteCloser{}
+	client := &lockedBuffer{}
+	stdin := newStdinForwarder(nil)
+	stdin.attach(server)
+
+	return newStdioProxy(stdin, client, maxMessageSize, middlewares...), server, client
+}
+
+func TestStdioProxyPassesThroughBytesExactly(t *testing.T) {
+	proxy, server, client := newTestProxy(t, defaultProxyMaxMessageSize)
+
+	clientInput := strings.Join([]string{
+		`{"jsonrpc":"2.0","id":1,"method":"initialize","params":{"b":1,  "a":2}}` + "\r\n",
+		`not json at all` + "\n",
+		"\n",
+		`[{"jsonrpc":"2.0","id":2,"method":"ping"},{"jsonrpc":"2.0","method":"notifications/x"}]` + "\n",
+		`{"jsonrpc":"2.0","id":"3","method":"tools/list"}`,
+	}, "")
+	proxy.serveClient(context.Background(), strings.NewReader(clientInput))
+
+	if got, closed := server.snapshot(); got != clientInput || !closed {
+		t.Fatalf("server received %q (closed=%v), want %q", got, closed, clientInput)
+	}
+
+	serverOutput := `{"jsonrpc":"2.0","id":1,"result":{"z":0, "y":1}}` + "\n" +
+		`{"jsonrpc":"2.0","method":"notifications/message","params":{}}` + "\n" +
+		`{"jsonrpc":"2.0","id":"3","result":{}}`
+	proxy.serveServer(context.Background(), strings.NewReader(serverOutput))
+
+	if got := client.String(); got != serverOutput {
+		t.Fatalf("client received %q, want %q", got, serverOutput)
+	}
+}
+
+func TestStdioProxyStreamsOversizedLines(t *testing.T) {
+	proxy, server, client := newTestProxy(t, 64)
+
+	large := `{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"data":"` +
+		strings.Repeat("x", proxyReadBufferSize*2) + `"}}` + "\n"
+	small := `{"jsonrpc":"2.0","id":2,"method":"ping"}` + "\n"
+	proxy.serveClient(context.Background(), strings.NewReader(large+small))
+
+	if got, _ := server.snapshot(); got != large+small {
+		t.Fatalf("server received %d bytes, want %d", len(got), len(large+small))
+	}
+
+	proxy.serveServer(context.Background(), strings.NewReader(large))
+	if got := client.String(); got != large {
+		t.Fatalf("client received %d bytes, want %d", len(got), len(large))
+	}
+}
+
+func TestStdioProxyRefusesOversizedLinesWithMiddlewares(t *testing.T) {
+	var seen []string
+	recorder := &observingMiddleware{seen: func(msg *rpcMessage) { seen = append(seen, msg.Method) }}
+	proxy, server, client := newTestProxy(t, 64, recorder)
+
+	large := `{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"data":"` +
+		strings.Repeat("x", proxyReadBufferSize*2) + `"}}` + "\n"
+	small := `{"jsonrpc":"2.0","id":2,"method":"ping"}` + "\n"
+	proxy.serveClient(context.Background(), strings.NewReader(large+small))
+
+	if got, _ := server.snapshot(); got != small {
+		t.Fatalf("server received %d bytes, want only the ping", len(got))
+	}
+	if len(seen) != 1 || seen[0] != "ping" {
+		t.Fatalf("middleware saw %v, want [ping]", seen)
+	}
+	want := `{"jsonrpc":"2.0","id":1,"error":{"code":-32600,"message":"message exceeds ` + proxyMaxMessageSizeEnv + `"}}`
+	if got := client.String(); !strings.Contains(got, want) {
+		t.Fatalf("client received %q, want %s", got, want)
+	}
+
+	// An oversized response is dropped and its request failed instead.
+	before := len(client.String())
+	proxy.serveServer(context.Background(), strings.NewReader(
+		`{"jsonrpc":"2.0","id":2,"result":{"data":"`+strings.Repeat("x", proxyReadBufferSize*2)+`"}}`+"\n"))
+	if got := client.String()[before:]; strings.Contains(got, "xxxx") ||
+		!strings.Contains(got, `"id":2,"error":{"code":-32603`) {
+		t.Fatalf("client received %q, want an error for id 2", got)
+	}
+}
+
+func TestStdioProxyRefusesOversizedDeniedToolCall(t *testing.T) {
+	filter := newToolFilter(globListConfig{Deny: []string{"delete_*"}})
+	proxy, server, client := newTestProxy(t, 1024, filter)
+
+	call := `{"jsonrpc":"2.0","id":5,"method":"tools/call","params":{"name":"delete_repo","arguments":{"pad":"` +
+		strings.Repeat(" ", 4096) + `"}}}` + "\n"
+	proxy.serveClient(context.Background(), strings.NewReader(call))
+
+	if got, _ := server.snapshot(); got != "" {
+		t.Fatalf("server received %d bytes of a denied call", len(got))
+	}
+	if got := client.String(); !strings.Contains(got, `"id":5,"error":{"code":-32600`) {
+		t.Fatalf("client received %q, want an error for id 5", got)
+	}
+}
+
+func TestStdioProxyRefusesMalformedLinesWithMiddlewares(t *testing.T) {
+	proxy, server, client := newTestProxy(t, defaultProxyMaxMessageSize, &observingMiddleware{})
+
+	proxy.serveClient(context.Background(), strings.NewReader("not json\n\n"+`{"id":3,"method":"tools/call"}`+"\n"))
+	proxy.serveServer(context.Background(), strings.NewReader("server noise\n"))
+
+	if got, _ := server.snapshot(); got != "" {
+		t.Fatalf("server received %q, want nothing", got)
+	}
+	want := `{"jsonrpc":"2.0","id":null,"error":{"code":-32700,"message":"Parse error"}}` + "\n" +
+		`{"jsonrpc":"2.0","id":3,"error":{"code":-32600,"message":"Invalid Request"}}` + "\n"
+	if got := client.String(); got != want {
+		t.Fatalf("client received %q, want %q", got, want)
+	}
+}
+
+func TestStdioProxyMiddlewareCanAnswerRequests(t *testing.T) {
+	deny := &denyingMiddleware{method: "tools/call"}
+	proxy, server, client := newTestProxy(t, defaultProxyMaxMessageSize, deny)
+
+	input := `{"jsonrpc":"2.0","id":7,"method":"tools/call","params":{"name":"x"}}` + "\n" +
+		`{"jsonrpc":"2.0","id":8,"method":"ping"}` + "\n"
+	proxy.serveClient(context.Background(), strings.NewReader(input))
+
+	if got, _ := server.snapshot(); got != `{"jsonrpc":"2.0","id":8,"method":"ping"}`+"\n" {
+		t.Fatalf("server received %q, want only the ping", got)
+	}
+
+	var resp rpcMessage
+	if err := json.Unmarshal([]byte(client.String()), &resp); err != nil {
+		t.Fatalf("client received invalid response %q: %v", client.String(), err)
+	}
+	if string(resp.ID) != "7" || resp.Error == nil || resp.Error.Code != rpcCodeInternalError {
+		t.Fatalf("unexpected denial response: %q", client.String())
+	}
+}
+
+func TestStdioProxyMatchesResponsesToPendingCalls(t *testing.T) {
+	var calls []*pendingCall
+	recorder := &observingMiddleware{response: func(msg *rpcMessage) { calls = append(calls, msg.call) }}
+	proxy, _, _ := newTestProxy(t, defaultProxyMaxMessageSize, recorder)
+
+	proxy.serveClient(context.Background(), strings.NewReader(
+		`{"jsonrpc":"2.0","id":"a","method":"tools/list"}`+"\n",
+	))
+	proxy.serveServer(context.Background(), strings.NewReader(
+		`{"jsonrpc":"2.0","id":"a","result":{}}`+"\n"+`{"jsonrpc":"2.0","id":"b","result":{}}`+"\n",
+	))
+
+	if len(calls) != 2 {
+		t.Fatalf("middleware saw %d responses, want 2", len(calls))
+	}
+	if calls[0] == nil || calls[0].request.Method != "tools/list" {
+		t.Fatalf("first response was not matched to its request: %+v", calls[0])
+	}
+	if calls[1] != nil {
+		t.Fatalf("unknown response should have no call, got %+v", calls[1])
+	}
+}
+
+func TestStdioProxyFailsPendingCallsWhenServerStops(t *testing.T) {
+	proxy, _, client := newTestProxy(t, defaultProxyMaxMessageSize)
+
+	proxy.serveClient(context.Background(), strings.NewReader(
+		`{"jsonrpc":"2.0","id":5,"method":"tools/call"}`+"\n",
+	))
+	proxy.serverStopped(context.Background())
+
+	if got := client.String(); !strings.Contains(got, `"id":5`) ||
+		!strings.Contains(got, errProxyServerStopped.Error()) {
+		t.Fatalf("client received %q, want an error for request 5", got)
+	}
+}
+
+func TestStdioProxyReplaysInitializeAfterRestart(t *testing.T) {
+	proxy, _, client := newTestProxy(t, defaultProxyMaxMessageSize)
+
+	proxy.serveClient(context.Background(), strings.NewReader(
+		`{"jsonrpc":"2.0","id":1,"method":"initialize","params":{"protocolVersion":"2025-06-18"}}`+"\n",
+	))
+
+	var restarted bytes.Buffer
+	if err := proxy.replaySession(&restarted); err != nil {
+		t.Fatalf("replaySession returned error: %v", err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(restarted.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("replayed %d messages, want initialize and initialized: %q", len(lines), restarted.String())
+	}
+	initialize, err := parseRPCMessage([]byte(lines[0]))
+	if err != nil || initialize.Method != methodInitialize ||
+		!strings.Contains(string(initialize.Params), "2025-06-18") {
+		t.Fatalf("unexpected replayed initialize: %q", lines[0])
+	}
+
+	// The restarted server's reply belongs to the proxy, not the client.
+	proxy.serveServer(context.Background(), strings.NewReader(
+		`{"jsonrpc":"2.0","id":`+string(initialize.ID)+`,"result":{}}`+"\n",
+	))
+	if got := client.String(); got != "" {
+		t.Fatalf("client received replayed initialize response: %q", got)
+	}
+}
+
+// observingMiddleware records client requests and server responses.
+type observingMiddleware struct {
+	passthroughMiddleware
+
+	seen     func(*rpcMessage)
+	response func(*rpcMessage)
+}
+
+func (m *observingMiddleware) clientToServer(_ *stdioProxy, next proxyHandler) proxyHandler {
+	return func(ctx context.Context, msg *rpcMessage) error {
+		if m.seen != nil {
+			m.seen(msg)
+		}
+		return next(ctx, msg)
+	}
+}
+
+func (m *observingMiddleware) serverToClient(_ *stdioProxy, next proxyHandler) proxyHandler {
+	return func(ctx context.Context, msg *rpcMessage) error {
+		if m.response != nil && msg.isResponse() {
+			m.response(msg)
+		}
+		return next(ctx, msg)
+	}
+}
+
+// denyingMiddleware answers every request for method with an error.
+type denyingMiddleware struct {
+	passthroughMiddleware
+
+	method string
+}
+
+func (m *denyingMiddleware) clientToServer(p *stdioProxy, next proxyHandler) proxyHandler {
+	return func(ctx context.Context, msg *rpcMessage) error {
+		if msg.isRequest() && msg.Method == m.method {
+			return p.replyError(ctx, msg, rpcCodeInternalError, "denied", nil)
+		}
+		return next(ctx, msg)
+	}
+}
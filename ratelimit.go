@@ -0,0 +1,395 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"math"
+	"sync"
+	"time"
+)
+
+const (
+	policyRateLimit = "rate-limit"
+
+	rateLimitOnLimitDelay  = "delay"
+	rateLimitOnLimitReject = "reject"
+
+	defaultRateLimitMaxDelay = 30 * time.Second
+	// inFlightRetryAfter is the retry hint for calls refused because too many
+	// are in flight, since when a slot frees up cannot be predicted.
+	inFlightRetryAfter = time.Second
+)
+
+// rateLimitConfig limits how fast and how many tool calls at once reach the
+// server, for all tools together and per tool name.
+type rateLimitConfig struct {
+	Global rateLimitRule `json:"global"`
+	// Tools apply to tool names matching their pattern; the first match wins.
+	// Every matching tool name gets its own counters.
+	Tools []toolRateLimitRule `json:"tools"`
+	// OnLimit is "delay" (default), which holds calls until they fit, or "reject".
+	OnLimit string `json:"onLimit"`
+	// MaxDelay, like "30s", caps how long a call is held before it is rejected.
+	MaxDelay string `json:"maxDelay"`
+}
+
+// rateLimitRule is a token bucket and an in-flight limit. Zero values mean unlimited.
+type rateLimitRule struct {
+	PerMinute float64 `json:"perMinute"`
+	// Burst defaults to one minute's worth of calls.
+	Burst       int `json:"burst"`
+	MaxInFlight int `json:"maxInFlight"`
+}
+
+type toolRateLimitRule struct {
+	rateLimitRule
+
+	Name string `json:"name"`
+}
+
+func (c rateLimitConfig) empty() bool {
+	return c.Global.unlimited() && len(c.Tools) == 0
+}
+
+func (c rateLimitConfig) validate() error {
+	switch c.OnLimit {
+	case "", rateLimitOnLimitDelay, rateLimitOnLimitReject:
+	default:
+		return fmt.Errorf("rateLimit.onLimit: unknown action %q", c.OnLimit)
+	}
+	if c.MaxDelay != "" {
+		d, err := time.ParseDuration(c.MaxDelay)
+		if err != nil {
+			return fmt.Errorf("rateLimit.maxDelay: %w", err)
+		}
+		if d < 0 {
+			return fmt.Errorf("rateLimit.maxDelay: %q must not be negative", c.MaxDelay)
+		}
+	}
+
+	if err := c.Global.validate("rateLimit.global"); err != nil {
+		return err
+	}
+	for i, rule := range c.Tools {
+		field := fmt.Sprintf("rateLimit.tools[%d]", i)
+		if err := validateGlobs(field+".name", []string{rule.Name}); err != nil {
+			return err
+		}
+		if err := rule.validate(field); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (r rateLimitRule) unlimited() bool {
+	return r.PerMinute == 0 && r.MaxInFlight == 0
+}
+
+func (r rateLimitRule) validate(field string) error {
+	if r.PerMinute < 0 || math.IsInf(r.PerMinute, 0) || math.IsNaN(r.PerMinute) {
+		return fmt.Errorf("%s.perMinute: %v is not a valid rate", field, r.PerMinute)
+	}
+	if r.Burst < 0 {
+		return fmt.Errorf("%s.burst: %d must not be negative", field, r.Burst)
+	}
+	if r.MaxInFlight < 0 {
+		return fmt.Errorf("%s.maxInFlight: %d must not be negative", field, r.MaxInFlight)
+	}
+
+	return nil
+}
+
+// tokenBucket refills at rate tokens per second up to burst.
+type tokenBucket struct {
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+func newTokenBucket(perMinute float64, burst int, now time.Time) *tokenBucket {
+	if perMinute == 0 {
+		return nil
+	}
+
+	size := float64(burst)
+	if burst == 0 {
+		size = math.Max(1, math.Ceil(perMinute))
+	}
+
+	return &tokenBucket{rate: perMinute / 60, burst: size, tokens: size, last: now}
+}
+
+func (b *tokenBucket) refill(now time.Time) {
+	if elapsed := now.Sub(b.last).Seconds(); elapsed > 0 {
+		b.tokens = math.Min(b.burst, b.tokens+elapsed*b.rate)
+		b.last = now
+	}
+}
+
+// wait returns how long until a token is available.
+func (b *tokenBucket) wait(now time.Time) time.Duration {
+	b.refill(now)
+	if b.tokens >= 1 {
+		return 0
+	}
+
+	return time.Duration((1 - b.tokens) / b.rate * float64(time.Second))
+}
+
+// limitCounters track one rule in effect: the global one, or the one for a
+// tool name. bucket is nil without a rate and max is zero without an in-flight
+// limit.
+type limitCounters struct {
+	scope    string
+	bucket   *tokenBucket
+	inFlight int
+	max      int
+}
+
+// rateLimiter enforces rateLimitConfig on tools/call requests. Calls over a
+// limit are held or refused with a retry hint; in-flight slots are released
+// when the response passes back to the client.
+type rateLimiter struct {
+	global   *limitCounters
+	rules    []toolRateLimitRule
+	reject   bool
+	maxDelay time.Duration
+
+	mu     sync.Mutex
+	tools  map[string]*limitCounters
+	active map[*pendingCall][]*limitCounters
+	// released is signalled whenever an in-flight slot frees up.
+	released chan struct{}
+}
+
+// newRateLimiter returns nil when no limits are configured.
+func newRateLimiter(cfg rateLimitConfig) *rateLimiter {
+	if cfg.empty() {
+		return nil
+	}
+
+	maxDelay := defaultRateLimitMaxDelay
+	if cfg.MaxDelay != "" {
+		// Validated when the config was loaded.
+		maxDelay, _ = time.ParseDuration(cfg.MaxDelay)
+	}
+
+	return &rateLimiter{
+		global:   newLimitCounters("global", cfg.Global),
+		rules:    cfg.Tools,
+		reject:   cfg.OnLimit == rateLimitOnLimitReject,
+		maxDelay: maxDelay,
+		tools:    make(map[string]*limitCounters),
+		active:   make(map[*pendingCall][]*limitCounters),
+		released: make(chan struct{}),
+	}
+}
+
+func newLimitCounters(scope string, rule rateLimitRule) *limitCounters {
+	return &limitCounters{
+		scope:  scope,
+		bucket: newTokenBucket(rule.PerMinute, rule.Burst, time.Now()),
+		max:    rule.MaxInFlight,
+	}
+}
+
+// limitsFor returns the counters that apply to a tool. Callers hold l.mu.
+func (l *rateLimiter) limitsFor(tool string) []*limitCounters {
+	limits := []*limitCounters{l.global}
+
+	if counters, ok := l.tools[tool]; ok {
+		if counters != nil {
+			limits = append(limits, counters)
+		}
+		return limits
+	}
+
+	var counters *limitCounters
+	for _, rule := range l.rules {
+		if matchesAnyGlob([]string{rule.Name}, tool) {
+			counters = newLimitCounters("tool", rule.rateLimitRule)
+			limits = append(limits, counters)
+			break
+		}
+	}
+	l.tools[tool] = counters
+
+	return limits
+}
+
+func (l *rateLimiter) clientToServer(p *stdioProxy, next proxyHandler) proxyHandler {
+	return func(ctx context.Context, msg *rpcMessage) error {
+		call, ok := msg.toolCall()
+		if !ok {
+			return next(ctx, msg)
+		}
+
+		wait, admitted := l.tryAdmit(msg.call, call.Name)
+		if admitted {
+			return next(ctx, msg)
+		}
+		if l.reject || wait > l.maxDelay {
+			return l.refuse(ctx, p, msg, call.Name, wait)
+		}
+
+		slog.InfoContext(ctx, "Delaying rate-limited tool call", l.logAttrs(call.Name, wait)...)
+		// Keep reading client input while the call waits.
+		go func() {
+			if wait, admitted := l.admit(ctx, msg.call, call.Name); !admitted {
+				_ = l.refuse(ctx, p, msg, call.Name, wait)
+				return
+			}
+			if err := next(ctx, msg); err != nil {
+				slog.WarnContext(ctx, "Failed to forward delayed tool call", "tool", call.Name, "err", err)
+			}
+		}()
+
+		return nil
+	}
+}
+
+func (l *rateLimiter) serverToClient(_ *stdioProxy, next proxyHandler) proxyHandler {
+	return func(ctx context.Context, msg *rpcMessage) error {
+		if msg.isResponse() && msg.call != nil {
+			l.release(msg.call)
+		}
+
+		return next(ctx, msg)
+	}
+}
+
+// tryAdmit takes a token and an in-flight slot from every applicable limit if
+// all have one available. Otherwise it returns how long until the tokens are
+// available; that is zero when only in-flight slots are missing.
+func (l *rateLimiter) tryAdmit(call *pendingCall, tool string) (time.Duration, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	limits := l.limitsFor(tool)
+
+	var wait time.Duration
+	full := false
+	for _, limit := range limits {
+		if limit.bucket != nil {
+			wait = max(wait, limit.bucket.wait(now))
+		}
+		if limit.max > 0 && limit.inFlight >= limit.max {
+			full = true
+		}
+	}
+	if wait > 0 || full {
+		return wait, false
+	}
+
+	for _, limit := range limits {
+		if limit.bucket != nil {
+			limit.bucket.tokens--
+		}
+		limit.inFlight++
+	}
+	l.active[call] = limits
+
+	return 0, true
+}
+
+// admit waits until the call fits within maxDelay, or ctx ends or the call is
+// cancelled. When it gives up it returns the last known wait for tokens.
+func (l *rateLimiter) admit(ctx context.Context, call *pendingCall, tool string) (time.Duration, bool) {
+	deadline := time.NewTimer(l.maxDelay)
+	defer deadline.Stop()
+
+	for {
+		l.mu.Lock()
+		released := l.released
+		l.mu.Unlock()
+
+		wait, admitted := l.tryAdmit(call, tool)
+		if admitted {
+			return 0, true
+		}
+
+		// Wake up when tokens refill or a slot is released, whichever is first.
+		var retry <-chan time.Time
+		var timer *time.Timer
+		if wait > 0 {
+			timer = time.NewTimer(wait)
+			retry = timer.C
+		}
+
+		gaveUp := false
+		select {
+		case <-retry:
+		case <-released:
+		case <-deadline.C:
+			gaveUp = true
+		case <-call.cancelled:
+			gaveUp = true
+		case <-ctx.Done():
+			gaveUp = true
+		}
+		if timer != nil {
+			timer.Stop()
+		}
+		if gaveUp {
+			return wait, false
+		}
+	}
+}
+
+func (l *rateLimiter) release(call *pendingCall) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	limits, ok := l.active[call]
+	if !ok {
+		return
+	}
+	delete(l.active, call)
+
+	for _, limit := range limits {
+		limit.inFlight--
+	}
+	close(l.released)
+	l.released = make(chan struct{})
+}
+
+func (l *rateLimiter) refuse(ctx context.Context, p *stdioProxy, msg *rpcMessage, tool string, wait time.Duration) error {
+	slog.InfoContext(ctx, "Refused rate-limited tool call", l.logAttrs(tool, wait)...)
+
+	reason := "too many tool calls in flight"
+	retryAfter := inFlightRetryAfter
+	if wait > 0 {
+		reason = "tool call rate limit exceeded"
+		retryAfter = wait
+	}
+
+	return p.replyError(ctx, msg, rpcCodePolicyDenied, reason+"; retry later",
+		policyDenial{Policy: policyRateLimit, Tool: tool, Reason: reason, RetryAfterMS: retryAfter.Milliseconds()})
+}
+
+// logAttrs reports the current counters of every limit that applies to tool.
+func (l *rateLimiter) logAttrs(tool string, wait time.Duration) []any {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	attrs := []any{"tool", tool, "wait", wait}
+	now := time.Now()
+	for _, limit := range l.limitsFor(tool) {
+		group := []any{"in_flight", limit.inFlight}
+		if limit.max > 0 {
+			group = append(group, "max_in_flight", limit.max)
+		}
+		if limit.bucket != nil {
+			limit.bucket.refill(now)
+			group = append(group, "tokens", math.Floor(limit.bucket.tokens*100)/100)
+		}
+		attrs = append(attrs, slog.Group(limit.scope, group...))
+	}
+
+	return attrs
+}
@@ -0,0 +1,157 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+func testToolCallLine(id int, name string) []byte {
+	return fmt.Appendf(nil, `{"jsonrpc":"2.0","id":%d,"method":"tools/call","params":{"name":%q}}`, id, name)
+}
+
+func TestRateLimiterRejectsOverRateWithRetryHint(t *testing.T) {
+	limiter := newRateLimiter(rateLimitConfig{
+		Tools:   []toolRateLimitRule{{Name: "create_*", rateLimitRule: rateLimitRule{PerMinute: 1}}},
+		OnLimit: rateLimitOnLimitReject,
+	})
+	proxy, server, client := newTestProxy(t, defaultProxyMaxMessageSize, limiter)
+
+	ctx := context.Background()
+	proxy.handleClientLine(ctx, testToolCallLine(1, "create_issue"))
+	proxy.handleClientLine(ctx, testToolCallLine(2, "create_issue"))
+	// Every tool name has its own bucket.
+	proxy.handleClientLine(ctx, testToolCallLine(3, "create_branch"))
+	proxy.handleClientLine(ctx, testToolCallLine(4, "get_issue"))
+
+	got, _ := server.snapshot()
+	for _, id := range []string{`"id":1`, `"id":3`, `"id":4`} {
+		if !strings.Contains(got, id) {
+			t.Fatalf("server received %q, want call %s forwarded", got, id)
+		}
+	}
+	if strings.Contains(got, `"id":2`) {
+		t.Fatalf("server received the rate-limited call: %q", got)
+	}
+
+	var resp rpcMessage
+	if err := json.Unmarshal([]byte(client.String()), &resp); err != nil {
+		t.Fatalf("client received invalid response %q: %v", client.String(), err)
+	}
+	var denial policyDenial
+	if err := json.Unmarshal(resp.Error.Data, &denial); err != nil {
+		t.Fatalf("invalid denial data %s: %v", resp.Error.Data, err)
+	}
+	if denial.Policy != policyRateLimit || denial.RetryAfterMS <= 0 {
+		t.Fatalf("unexpected denial: %+v", denial)
+	}
+}
+
+func TestRateLimiterRejectsInFlightCallsWithRetryHint(t *testing.T) {
+	limiter := newRateLimiter(rateLimitConfig{
+		Global:  rateLimitRule{MaxInFlight: 1},
+		OnLimit: rateLimitOnLimitReject,
+	})
+	proxy, server, client := newTestProxy(t, defaultProxyMaxMessageSize, limiter)
+
+	ctx := context.Background()
+	proxy.handleClientLine(ctx, testToolCallLine(1, "get_issue"))
+	proxy.handleClientLine(ctx, testToolCallLine(2, "get_me"))
+
+	if got, _ := server.snapshot(); strings.Contains(got, `"id":2`) {
+		t.Fatalf("server received the call refused while another was in flight: %q", got)
+	}
+
+	var resp rpcMessage
+	if err := json.Unmarshal([]byte(client.String()), &resp); err != nil {
+		t.Fatalf("client received invalid response %q: %v", client.String(), err)
+	}
+	var denial policyDenial
+	if err := json.Unmarshal(resp.Error.Data, &denial); err != nil {
+		t.Fatalf("invalid denial data %s: %v", resp.Error.Data, err)
+	}
+	if denial.Policy != policyRateLimit || denial.RetryAfterMS != inFlightRetryAfter.Milliseconds() {
+		t.Fatalf("unexpected denial: %+v", denial)
+	}
+}
+
+func TestRateLimiterDelaysUntilSlotIsReleased(t *testing.T) {
+	limiter := newRateLimiter(rateLimitConfig{Global: rateLimitRule{MaxInFlight: 1}})
+	proxy, server, client := newTestProxy(t, defaultProxyMaxMessageSize, limiter)
+
+	ctx := context.Background()
+	proxy.handleClientLine(ctx, testToolCallLine(1, "get_issue"))
+	proxy.handleClientLine(ctx, testToolCallLine(2, "get_me"))
+
+	if got, _ := server.snapshot(); strings.Contains(got, `"id":2`) {
+		t.Fatalf("second call was forwarded while the first was in flight: %q", got)
+	}
+
+	if err := proxy.handleServerLine(ctx, []byte(`{"jsonrpc":"2.0","id":1,"result":{}}`)); err != nil {
+		t.Fatalf("handleServerLine failed: %v", err)
+	}
+	waitForString(t, serverInput(server), `"id":2`)
+
+	if got := client.String(); strings.Contains(got, "error") {
+		t.Fatalf("client received an error: %q", got)
+	}
+}
+
+func TestRateLimiterRefusesCallsHeldPastMaxDelay(t *testing.T) {
+	limiter := newRateLimiter(rateLimitConfig{Global: rateLimitRule{MaxInFlight: 1}, MaxDelay: "20ms"})
+	proxy, server, client := newTestProxy(t, defaultProxyMaxMessageSize, limiter)
+
+	ctx := context.Background()
+	proxy.handleClientLine(ctx, testToolCallLine(1, "get_issue"))
+	proxy.handleClientLine(ctx, testToolCallLine(2, "get_me"))
+
+	got := waitForString(t, client.String, `"policy":"rate-limit"`)
+	if !strings.Contains(got, "too many tool calls in flight") {
+		t.Fatalf("unexpected denial %q", got)
+	}
+	if got, _ := server.snapshot(); strings.Contains(got, `"id":2`) {
+		t.Fatalf("server received the refused call: %q", got)
+	}
+}
+
+func TestTokenBucketRefills(t *testing.T) {
+	start := time.Now()
+	bucket := newTokenBucket(60, 2, start)
+
+	bucket.tokens -= 2
+	if wait := bucket.wait(start); wait != time.Second {
+		t.Fatalf("wait = %v, want 1s", wait)
+	}
+	if wait := bucket.wait(start.Add(time.Second)); wait != 0 {
+		t.Fatalf("wait after refill = %v, want 0", wait)
+	}
+	if bucket.wait(start.Add(time.Hour)); bucket.tokens != 2 {
+		t.Fatalf("tokens = %v, want capped at burst 2", bucket.tokens)
+	}
+}
+
+func TestParseProxyConfigRateLimit(t *testing.T) {
+	cfg, err := parseProxyConfig([]byte(`{"rateLimit":{"global":{"perMinute":120,"maxInFlight":4},` +
+		`"tools":[{"name":"create_*","perMinute":5,"burst":1}],"onLimit":"reject","maxDelay":"5s"}}`))
+	if err != nil {
+		t.Fatalf("parseProxyConfig failed: %v", err)
+	}
+	if len(cfg.RateLimit.Tools) != 1 || cfg.RateLimit.Tools[0].Name != "create_*" ||
+		cfg.RateLimit.Tools[0].PerMinute != 5 {
+		t.Fatalf("unexpected rate limit config: %+v", cfg.RateLimit)
+	}
+
+	for _, data := range []string{
+		`{"rateLimit":{"onLimit":"drop"}}`,
+		`{"rateLimit":{"global":{"perMinute":-1}}}`,
+		`{"rateLimit":{"tools":[{"name":"[","maxInFlight":1}]}}`,
+		`{"rateLimit":{"maxDelay":"later"}}`,
+	} {
+		if _, err := parseProxyConfig([]byte(data)); err == nil {
+			t.Fatalf("parseProxyConfig(%s) succeeded, want error", data)
+		}
+	}
+}
@@ -0,0 +1,162 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"os"
+	"strconv"
+	"sync"
+)
+
+const (
+	readOnlyEnv    = "GITHUB_READ_ONLY"
+	policyReadOnly = "read-only"
+)
+
+// readOnlyToolOverrides pins the classification of github-mcp-server tools
+// whose effect is known, regardless of the annotations the server reports.
+// Review it when mcpServerVersion changes.
+var readOnlyToolOverrides = map[string]bool{
+	"add_comment_to_pending_review":               false,
+	"add_issue_comment":                           false,
+	"add_sub_issue":                               false,
+	"assign_copilot_to_issue":                     false,
+	"cancel_workflow_run":                         false,
+	"create_and_submit_pull_request_review":       false,
+	"create_branch":                               false,
+	"create_gist":                                 false,
+	"create_issue":                                false,
+	"create_or_update_file":                       false,
+	"create_pending_pull_request_review":          false,
+	"create_pull_request":                         false,
+	"create_repository":                           false,
+	"delete_file":                                 false,
+	"delete_pending_pull_request_review":          false,
+	"delete_workflow_run_logs":                    false,
+	"dismiss_notification":                        false,
+	"fork_repository":                             false,
+	"issue_write":                                 false,
+	"label_write":                                 false,
+	"manage_notification_subscription":            false,
+	"manage_repository_notification_subscription": false,
+	"mark_all_notifications_read":                 false,
+	"merge_pull_request":                          false,
+	"pull_request_review_write":                   false,
+	"push_files":                                  false,
+	"remove_sub_issue":                            false,
+	"reprioritize_sub_issue":                      false,
+	"request_copilot_review":                      false,
+	"rerun_failed_jobs":                           false,
+	"rerun_workflow_run":                          false,
+	"run_workflow":                                false,
+	"star_repository":                             false,
+	"sub_issue_write":                             false,
+	"submit_pending_pull_request_review":          false,
+	"unstar_repository":                           false,
+	"update_gist":                                 false,
+	"update_issue":                                false,
+	"update_pull_request":                         false,
+	"update_pull_request_branch":                  false,
+}
+
+// toolAnnotations is the part of a tool definition used to classify it.
+type toolAnnotations struct {
+	ReadOnlyHint *bool `json:"readOnlyHint"`
+}
+
+// readOnlyRequested reports whether the session must be read-only. Any
+// GITHUB_READ_ONLY value other than a false boolean counts as a request.
+func readOnlyRequested(cfg *proxyConfig) bool {
+	if cfg.ReadOnly {
+		return true
+	}
+
+	value := os.Getenv(readOnlyEnv)
+	if value == "" {
+		return false
+	}
+	enabled, err := strconv.ParseBool(value)
+
+	return err != nil || enabled
+}
+
+// toolClassifier tells read-only tools apart. Tools are classified from
+// readOnlyHint annotations in tools/list responses, with readOnlyToolOverrides
+// taking precedence. The local gh_mcp_* tools are always read-only.
+// Unclassified tools are not read-only.
+type toolClassifier struct {
+	mu       sync.Mutex
+	readOnly map[string]bool
+}
+
+func (c *toolClassifier) isReadOnly(name string) bool {
+	if readOnly, ok := readOnlyToolOverrides[name]; ok {
+		return readOnly
+	}
+	if isLocalTool(name) {
+		return true
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.readOnly[name]
+}
+
+// learn records the readOnlyHint of every tool in a tools/list response.
+func (c *toolClassifier) learn(msg *rpcMessage) {
+	var result struct {
+		Tools []struct {
+			Name        string          `json:"name"`
+			Annotations toolAnnotations `json:"annotations"`
+		} `json:"tools"`
+	}
+	if err := json.Unmarshal(msg.Result, &result); err != nil {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.readOnly == nil {
+		c.readOnly = make(map[string]bool)
+	}
+	for _, tool := range result.Tools {
+		hint := tool.Annotations.ReadOnlyHint
+		c.readOnly[tool.Name] = hint != nil && *hint
+	}
+}
+
+// readOnlyGuard refuses tool calls that are not known to be read-only.
+type readOnlyGuard struct {
+	tools toolClassifier
+}
+
+func newReadOnlyGuard() *readOnlyGuard {
+	return &readOnlyGuard{}
+}
+
+func (g *readOnlyGuard) clientToServer(p *stdioProxy, next proxyHandler) proxyHandler {
+	return func(ctx context.Context, msg *rpcMessage) error {
+		call, ok := msg.toolCall()
+		if !ok || g.tools.isReadOnly(call.Name) {
+			return next(ctx, msg)
+		}
+
+		slog.WarnContext(ctx, "Refused non-read-only tool call", "tool", call.Name, "policy", policyReadOnly)
+		return p.replyError(ctx, msg, rpcCodePolicyDenied,
+			"tool "+call.Name+" is not allowed in read-only mode",
+			policyDenial{Policy: policyReadOnly, Tool: call.Name, Reason: "tool is not classified as read-only"})
+	}
+}
+
+func (g *readOnlyGuard) serverToClient(_ *stdioProxy, next proxyHandler) proxyHandler {
+	return func(ctx context.Context, msg *rpcMessage) error {
+		if isToolListResponse(msg) {
+			g.tools.learn(msg)
+		}
+
+		return next(ctx, msg)
+	}
+}
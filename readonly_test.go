@@ -0,0 +1,67 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestReadOnlyGuardClassifiesTools(t *testing.T) {
+	proxy, server, client := newTestProxy(t, defaultProxyMaxMessageSize, newReadOnlyGuard())
+
+	// handleClientLine keeps the server attached; serveClient would close it at EOF.
+	proxy.handleClientLine(context.Background(), []byte(`{"jsonrpc":"2.0","id":1,"method":"tools/list"}`+"\n"))
+	proxy.serveServer(context.Background(), strings.NewReader(`{"jsonrpc":"2.0","id":1,"result":{"tools":[`+
+		`{"name":"get_issue","annotations":{"readOnlyHint":true}},`+
+		`{"name":"create_issue","annotations":{"readOnlyHint":true}},`+
+		`{"name":"new_tool"}]}}`+"\n"))
+
+	calls := map[string]bool{
+		"get_issue":    true,
+		"create_issue": false, // the override table wins over a wrong hint
+		"new_tool":     false, // no hint: not read-only
+		"unlisted":     false,
+	}
+	for name, wantAllowed := range calls {
+		t.Run(name, func(t *testing.T) {
+			before, _ := server.snapshot()
+			proxy.handleClientLine(context.Background(), []byte(
+				`{"jsonrpc":"2.0","id":"`+name+`","method":"tools/call","params":{"name":"`+name+`"}}`+"\n",
+			))
+			after, _ := server.snapshot()
+
+			if forwarded := after != before; forwarded != wantAllowed {
+				t.Fatalf("forwarded = %v, want %v", forwarded, wantAllowed)
+			}
+			if refused := strings.Contains(client.String(), `"id":"`+name+`"`); refused == wantAllowed {
+				t.Fatalf("refused = %v, want %v (client output %q)", refused, !wantAllowed, client.String())
+			}
+		})
+	}
+}
+
+func TestReadOnlyRequested(t *testing.T) {
+	tests := []struct {
+		value string
+		want  bool
+	}{
+		{value: "", want: false},
+		{value: "0", want: false},
+		{value: "false", want: false},
+		{value: "1", want: true},
+		{value: "true", want: true},
+		{value: "yes", want: true},
+	}
+
+	for _, tt := range tests {
+		t.Setenv(readOnlyEnv, tt.value)
+		if got := readOnlyRequested(&proxyConfig{}); got != tt.want {
+			t.Fatalf("readOnlyRequested with %s=%q = %v, want %v", readOnlyEnv, tt.value, got, tt.want)
+		}
+	}
+
+	t.Setenv(readOnlyEnv, "")
+	if !readOnlyRequested(&proxyConfig{ReadOnly: true}) {
+		t.Fatal("expected readOnly in config to request read-only mode")
+	}
+}
@@ -0,0 +1,406 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	recordEnv = "GH_MCP_RECORD"
+	replayEnv = "GH_MCP_REPLAY"
+
+	recordFromClient = "client"
+	recordFromServer = "server"
+
+	// rpcCodeReplayMismatch is returned in replay mode for requests the
+	// recording has no response to.
+	rpcCodeReplayMismatch = -32004
+
+	// maxReplayCandidates bounds the recorded params listed in a mismatch error.
+	maxReplayCandidates = 5
+)
+
+// errInvalidRecording is returned for replay files that cannot be used.
+var errInvalidRecording = errors.New("invalid recording")
+
+// recordedMessage is one line of a recording.
+type recordedMessage struct {
+	Time time.Time `json:"time"`
+	// From is "client" or "server".
+	From    string          `json:"from"`
+	Message json.RawMessage `json:"message"`
+}
+
+// sessionRecordingFromEnv returns the GH_MCP_RECORD and GH_MCP_REPLAY paths.
+// At most one is set.
+func sessionRecordingFromEnv() (string, string, error) {
+	record := os.Getenv(recordEnv)
+	replay := os.Getenv(replayEnv)
+	if record != "" && replay != "" {
+		return "", "", fmt.Errorf("%w: %s and %s cannot be used together", errInvalidSetting, recordEnv, replayEnv)
+	}
+
+	return record, replay, nil
+}
+
+// sessionRecorder writes every message exchanged with the server to a JSONL
+// file. It is the last middleware, so it records what the server receives and
+// what it sends before any policy applies. Known secret formats are redacted.
+type sessionRecorder struct {
+	mu   sync.Mutex
+	file *os.File
+	enc  *json.Encoder
+}
+
+func newSessionRecorder(path string) (*sessionRecorder, error) {
+	// #nosec G304 -- the recording path is chosen by the user running gh-mcp
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open recording %s: %w", path, err)
+	}
+
+	return &sessionRecorder{file: file, enc: json.NewEncoder(file)}, nil
+}
+
+func (r *sessionRecorder) clientToServer(_ *stdioProxy, next proxyHandler) proxyHandler {
+	return func(ctx context.Context, msg *rpcMessage) error {
+		r.record(ctx, recordFromClient, msg)
+		return next(ctx, msg)
+	}
+}
+
+func (r *sessionRecorder) serverToClient(_ *stdioProxy, next proxyHandler) proxyHandler {
+	return func(ctx context.Context, msg *rpcMessage) error {
+		r.record(ctx, recordFromServer, msg)
+		return next(ctx, msg)
+	}
+}
+
+func (r *sessionRecorder) record(ctx context.Context, from string, msg *rpcMessage) {
+	data, err := msg.bytes()
+	if err != nil {
+		slog.WarnContext(ctx, "Failed to record MCP message", "method", msg.Method, "err", err)
+		return
+	}
+	data, _, err = builtinSecretScanner.redactJSON(bytes.TrimSpace(data))
+	if err != nil {
+		slog.WarnContext(ctx, "Failed to record MCP message", "method", msg.Method, "err", err)
+		return
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if err := r.enc.Encode(recordedMessage{Time: time.Now().UTC(), From: from, Message: data}); err != nil {
+		slog.WarnContext(ctx, "Failed to record MCP message", "method", msg.Method, "err", err)
+	}
+}
+
+func (r *sessionRecorder) close() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if err := r.file.Close(); err != nil {
+		slog.Warn("Failed to close recording", "path", r.file.Name(), "err", err)
+	}
+}
+
+// replayExchange is a recorded client request and the server's response.
+type replayExchange struct {
+	method   string
+	key      string
+	params   json.RawMessage
+	response *rpcMessage
+	used     bool
+}
+
+// replayMismatch is the data of the error returned for an unmatched request.
+type replayMismatch struct {
+	Method string          `json:"method"`
+	Params json.RawMessage `json:"params,omitempty"`
+	// Recorded are the params of recorded requests with the same method.
+	Recorded []json.RawMessage `json:"recorded,omitempty"`
+}
+
+// replayRecording answers requests from a recording in place of the server.
+// Requests match recorded ones by method and params, ignoring key order and
+// _meta; initialize matches by method alone. Unused recorded exchanges are
+// preferred in order, after which the last match is repeated.
+type replayRecording struct {
+	path      string
+	exchanges []*replayExchange
+	last      map[string]*replayExchange
+
+	matched    int
+	mismatched int
+}
+
+func loadReplayRecording(path string) (*replayRecording, error) {
+	// #nosec G304 -- the recording path is chosen by the user running gh-mcp
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s=%q: %w", errInvalidSetting, replayEnv, path, err)
+	}
+	defer func() { _ = file.Close() }()
+
+	r, err := readReplayRecording(file)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s=%q: %w", errInvalidSetting, replayEnv, path, err)
+	}
+	r.path = path
+
+	return r, nil
+}
+
+func readReplayRecording(src io.Reader) (*replayRecording, error) {
+	r := &replayRecording{last: make(map[string]*replayExchange)}
+	open := make(map[string]*replayExchange)
+
+	dec := json.NewDecoder(src)
+	for line := 1; ; line++ {
+		var entry recordedMessage
+		if err := dec.Decode(&entry); errors.Is(err, io.EOF) {
+			break
+		} else if err != nil {
+			return nil, fmt.Errorf("%w: entry %d: %w", errInvalidRecording, line, err)
+		}
+		msg, err := parseRPCMessage(entry.Message)
+		if err != nil {
+			return nil, fmt.Errorf("%w: entry %d: %w", errInvalidRecording, line, err)
+		}
+
+		switch entry.From {
+		case recordFromClient:
+			if !msg.isRequest() {
+				continue
+			}
+			key, err := replayKey(msg)
+			if err != nil {
+				return nil, fmt.Errorf("%w: entry %d: %w", errInvalidRecording, line, err)
+			}
+			ex := &replayExchange{method: msg.Method, key: key, params: msg.Params}
+			open[msg.idKey()] = ex
+			r.exchanges = append(r.exchanges, ex)
+		case recordFromServer:
+			if ex, ok := open[msg.idKey()]; ok && msg.isResponse() {
+				ex.response = msg
+				delete(open, msg.idKey())
+			}
+		default:
+			return nil, fmt.Errorf("%w: entry %d: unknown sender %q", errInvalidRecording, line, entry.From)
+		}
+	}
+
+	// Requests the server never answered, such as cancelled ones, cannot be replayed.
+	answered := r.exchanges[:0]
+	for _, ex := range r.exchanges {
+		if ex.response != nil {
+			answered = append(answered, ex)
+		}
+	}
+	r.exchanges = answered
+	if len(r.exchanges) == 0 {
+		return nil, fmt.Errorf("%w: no answered requests", errInvalidRecording)
+	}
+
+	return r, nil
+}
+
+// replayKey identifies a request by its method and normalized params.
+func replayKey(msg *rpcMessage) (string, error) {
+	if msg.Method == methodInitialize {
+		return msg.Method, nil
+	}
+
+	params := msg.Params
+	var fields map[string]json.RawMessage
+	if json.Unmarshal(params, &fields) == nil && fields != nil {
+		delete(fields, "_meta")
+		var err error
+		if params, err = json.Marshal(fields); err != nil {
+			return "", fmt.Errorf("failed to encode params: %w", err)
+		}
+	}
+
+	canonical, err := canonicalJSON(params)
+	if err != nil {
+		return "", err
+	}
+
+	return msg.Method + "\x00" + canonical, nil
+}
+
+// serve answers the requests read from in on out until in is closed, then
+// closes out. Notifications and responses from the client are dropped.
+func (r *replayRecording) serve(ctx context.Context, in io.Reader, out io.WriteCloser, maxMessageSize int) {
+	defer func() { _ = out.Close() }()
+
+	err := readFrames(in, maxMessageSize, func(line []byte) error {
+		msgs, _, err := parseRPCLine(line)
+		if err != nil {
+			slog.WarnContext(ctx, "Replay ignored input that is not JSON-RPC")
+			return nil
+		}
+		for _, msg := range msgs {
+			if !msg.isRequest() {
+				continue
+			}
+			data, err := r.answer(ctx, msg).bytes()
+			if err != nil {
+				return err
+			}
+			if _, err := out.Write(data); err != nil {
+				return fmt.Errorf("failed to write replayed response: %w", err)
+			}
+		}
+		return nil
+	}, func(_ []byte, last bool) error {
+		if last {
+			slog.WarnContext(ctx, "Replay ignored a message larger than the proxy limit")
+		}
+		return nil
+	})
+	if err != nil {
+		slog.DebugContext(ctx, "Stopped replaying recording", "err", err)
+	}
+}
+
+// answer returns the recorded response to req, or a mismatch error.
+func (r *replayRecording) answer(ctx context.Context, req *rpcMessage) *rpcMessage {
+	key, err := replayKey(req)
+	if err == nil {
+		if ex := r.match(key); ex != nil {
+			r.matched++
+			resp := *ex.response
+			resp.ID = req.ID
+			resp.modified = true
+			return &resp
+		}
+	}
+
+	r.mismatched++
+	mismatch := replayMismatch{Method: req.Method, Params: req.Params}
+	for _, ex := range r.exchanges {
+		if ex.method == req.Method && len(mismatch.Recorded) < maxReplayCandidates {
+			mismatch.Recorded = append(mismatch.Recorded, ex.params)
+		}
+	}
+	slog.WarnContext(ctx, "No recorded response matches request",
+		"method", req.Method, "params", string(req.Params), "candidates", len(mismatch.Recorded))
+
+	resp, err := newRPCErrorResponse(req.ID, rpcCodeReplayMismatch,
+		"gh-mcp replay has no recorded response for this "+req.Method+" request", mismatch)
+	if err != nil {
+		resp, _ = newRPCErrorResponse(req.ID, rpcCodeReplayMismatch, err.Error(), nil)
+	}
+
+	return resp
+}
+
+func (r *replayRecording) match(key string) *replayExchange {
+	for _, ex := range r.exchanges {
+		if !ex.used && ex.key == key {
+			ex.used = true
+			r.last[key] = ex
+			return ex
+		}
+	}
+
+	return r.last[key]
+}
+
+// report logs how well the session followed the recording.
+func (r *replayRecording) report(ctx context.Context) {
+	var unused []string
+	for _, ex := range r.exchanges {
+		if !ex.used {
+			unused = append(unused, ex.method)
+		}
+	}
+
+	attrs := []any{"path", r.path, "matched", r.matched, "mismatched", r.mismatched, "unused", len(unused)}
+	if r.mismatched > 0 || len(unused) > 0 {
+		slog.WarnContext(ctx, "Replay diverged from the recording",
+			append(attrs, "unused_methods", strings.Join(unused, ","))...)
+		return
+	}
+	slog.InfoContext(ctx, "Replay matched the recording", attrs...)
+}
+
+// replayProxyConfig returns cfg without additional servers, whose traffic is
+// not recorded and which must not be started while replaying.
+func replayProxyConfig(cfg *proxyConfig) *proxyConfig {
+	if len(cfg.Servers) == 0 {
+		return cfg
+	}
+
+	slog.Warn("Additional MCP servers are not started while replaying a recording", "servers", len(cfg.Servers))
+	replay := *cfg
+	replay.Servers = nil
+
+	return &replay
+}
+
+// runReplaySession serves the client from a recording instead of the bundled
+// server, through the same proxy and middlewares.
+func runReplaySession(
+	ctx context.Context,
+	recording *replayRecording,
+	streams *ioStreams,
+	maxMessageSize int,
+	middlewares []proxyMiddleware,
+	tracer *protocolTracer,
+	metrics *sessionMetrics,
+) error {
+	ctx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+
+	stdin := newStdinForwarder(func() {
+		cancel(errClientStdinClosed)
+	})
+	proxy := newStdioProxy(stdin, streams.out, maxMessageSize, middlewares...)
+	proxy.observe(tracer, metrics)
+	proxy.endSessionWith(cancel)
+
+	serverIn, clientInput := io.Pipe()
+	serverOutput, serverOut := io.Pipe()
+	stdin.attach(clientInput)
+
+	served := make(chan struct{})
+	go func() {
+		defer close(served)
+		recording.serve(ctx, serverIn, serverOut, maxMessageSize)
+	}()
+	outputDone := make(chan struct{})
+	go func() {
+		defer close(outputDone)
+		proxy.serveServer(ctx, serverOutput)
+	}()
+
+	slog.InfoContext(ctx, "▶️ Replaying recorded MCP session", "path", recording.path)
+	go proxy.serveClient(ctx, streams.in)
+
+	stopParentWatch := watchParentProcess(ctx, func() {
+		cancel(errParentProcessExited)
+	})
+	defer stopParentWatch()
+
+	<-ctx.Done()
+	stdin.detach(clientInput)
+	_ = clientInput.Close()
+	<-served
+	<-outputDone
+	proxy.serverStopped(ctx)
+	recording.report(ctx)
+
+	return proxy.endError()
+}
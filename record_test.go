@@ -0,0 +1,175 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testRecording = `{"time":"2026-01-01T00:00:00Z","from":"client","message":{"jsonrpc":"2.0","id":1,"method":"initialize","params":{"clientInfo":{"name":"a"}}}}
+{"time":"2026-01-01T00:00:00Z","from":"server","message":{"jsonrpc":"2.0","id":1,"result":{"serverInfo":{"name":"github-mcp-server"}}}}
+{"time":"2026-01-01T00:00:00Z","from":"client","message":{"jsonrpc":"2.0","method":"notifications/initialized"}}
+{"time":"2026-01-01T00:00:01Z","from":"client","message":{"jsonrpc":"2.0","id":2,"method":"tools/call","params":{"name":"get_issue","arguments":{"owner":"o","repo":"r","issue_number":1}}}}
+{"time":"2026-01-01T00:00:01Z","from":"server","message":{"jsonrpc":"2.0","id":2,"result":{"content":[{"type":"text","text":"first"}]}}}
+{"time":"2026-01-01T00:00:02Z","from":"client","message":{"jsonrpc":"2.0","id":3,"method":"tools/call","params":{"name":"get_issue","arguments":{"owner":"o","repo":"r","issue_number":1}}}}
+{"time":"2026-01-01T00:00:02Z","from":"server","message":{"jsonrpc":"2.0","id":3,"result":{"content":[{"type":"text","text":"second"}]}}}
+{"time":"2026-01-01T00:00:03Z","from":"client","message":{"jsonrpc":"2.0","id":4,"method":"tools/call","params":{"name":"list_issues","arguments":{}}}}
+`
+
+func TestSessionRecorderWritesBothDirections(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "session.jsonl")
+	recorder, err := newSessionRecorder(path)
+	if err != nil {
+		t.Fatalf("newSessionRecorder failed: %v", err)
+	}
+	proxy, _, _ := newTestProxy(t, defaultProxyMaxMessageSize, recorder)
+
+	ctx := context.Background()
+	proxy.handleClientLine(ctx, testToolCallLine(1, "get_me"))
+	token := "ghp_" + strings.Repeat("a", 36)
+	if err := proxy.handleServerLine(ctx, []byte(`{"jsonrpc":"2.0","id":1,"result":{"token":"`+token+`"}}`+"\n")); err != nil {
+		t.Fatalf("handleServerLine failed: %v", err)
+	}
+	recorder.close()
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("ReadFile failed: %v", err)
+	}
+	if bytes.Contains(data, []byte(token)) {
+		t.Fatalf("recording contains a token: %s", data)
+	}
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("recording has %d lines, want 2: %s", len(lines), data)
+	}
+	for i, want := range []string{recordFromClient, recordFromServer} {
+		var entry recordedMessage
+		if err := json.Unmarshal([]byte(lines[i]), &entry); err != nil || entry.From != want {
+			t.Fatalf("line %d = %s, want a message from the %s: %v", i, lines[i], want, err)
+		}
+	}
+}
+
+func TestReplayRecordingAnswersMatchingRequests(t *testing.T) {
+	recording, err := readReplayRecording(strings.NewReader(testRecording))
+	if err != nil {
+		t.Fatalf("readReplayRecording failed: %v", err)
+	}
+	if len(recording.exchanges) != 3 {
+		t.Fatalf("recording has %d exchanges, want the 3 answered requests", len(recording.exchanges))
+	}
+
+	ctx := context.Background()
+	answer := func(line string) *rpcMessage {
+		t.Helper()
+		msg, err := parseRPCMessage([]byte(line))
+		if err != nil {
+			t.Fatalf("parseRPCMessage failed: %v", err)
+		}
+		return recording.answer(ctx, msg)
+	}
+
+	// initialize matches whatever the client sends.
+	resp := answer(`{"jsonrpc":"2.0","id":"init","method":"initialize","params":{"clientInfo":{"name":"b"}}}`)
+	if string(resp.ID) != `"init"` || !strings.Contains(string(resp.Result), "github-mcp-server") {
+		t.Fatalf("initialize answered with %+v", resp)
+	}
+
+	// Argument order and _meta do not matter; recorded answers are used in
+	// order and the last one repeats.
+	call := `{"jsonrpc":"2.0","id":9,"method":"tools/call","params":{"_meta":{"progressToken":1},` +
+		`"arguments":{"issue_number":1,"repo":"r","owner":"o"},"name":"get_issue"}}`
+	for _, want := range []string{"first", "second", "second"} {
+		resp := answer(call)
+		if string(resp.ID) != "9" || !strings.Contains(string(resp.Result), want) {
+			t.Fatalf("tools/call answered with %s, want %q", resp.Result, want)
+		}
+	}
+
+	resp = answer(`{"jsonrpc":"2.0","id":10,"method":"tools/call","params":{"name":"get_issue","arguments":{"owner":"x"}}}`)
+	if resp.Error == nil || resp.Error.Code != rpcCodeReplayMismatch {
+		t.Fatalf("unmatched request answered with %+v, want a mismatch error", resp)
+	}
+	var mismatch replayMismatch
+	if err := json.Unmarshal(resp.Error.Data, &mismatch); err != nil || len(mismatch.Recorded) != 2 {
+		t.Fatalf("mismatch data = %s, want the 2 answered tools/call params: %v", resp.Error.Data, err)
+	}
+}
+
+func TestReadReplayRecordingRejectsInvalidFiles(t *testing.T) {
+	for _, data := range []string{
+		"",
+		"not json\n",
+		`{"from":"nobody","message":{"jsonrpc":"2.0","id":1,"method":"ping"}}` + "\n",
+		`{"from":"client","message":{"jsonrpc":"2.0","id":1,"method":"ping"}}` + "\n",
+	} {
+		if _, err := readReplayRecording(strings.NewReader(data)); !errors.Is(err, errInvalidRecording) {
+			t.Fatalf("readReplayRecording(%q) error = %v, want errInvalidRecording", data, err)
+		}
+	}
+}
+
+func TestRunReplaySessionServesClient(t *testing.T) {
+	recording, err := readReplayRecording(strings.NewReader(testRecording))
+	if err != nil {
+		t.Fatalf("readReplayRecording failed: %v", err)
+	}
+
+	input := `{"jsonrpc":"2.0","id":1,"method":"initialize","params":{}}` + "\n" +
+		`{"jsonrpc":"2.0","method":"notifications/initialized"}` + "\n" +
+		`{"jsonrpc":"2.0","id":2,"method":"tools/call","params":{"name":"get_issue",` +
+		`"arguments":{"owner":"o","repo":"r","issue_number":1}}}` + "\n"
+	var out lockedBuffer
+	streams := &ioStreams{in: strings.NewReader(input), out: &out, err: &lockedBuffer{}}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- runReplaySession(context.Background(), recording, streams, defaultProxyMaxMessageSize, nil, nil, nil)
+	}()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("runReplaySession failed: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("runReplaySession did not return after client EOF")
+	}
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 2 || !strings.Contains(lines[0], "github-mcp-server") || !strings.Contains(lines[1], `"id":2,"result"`) {
+		t.Fatalf("client received %q, want the recorded initialize and tools/call responses", out.String())
+	}
+}
+
+func TestReplayProxyConfigSkipsAdditionalServers(t *testing.T) {
+	cfg := &proxyConfig{Servers: []extraServerConfig{{Name: "wiki", Command: "does-not-exist"}}}
+
+	middlewares, closeMiddlewares, err := proxyMiddlewaresFromConfig(replayProxyConfig(cfg), proxySession{}, defaultProxyMaxMessageSize)
+	if err != nil {
+		t.Fatalf("proxyMiddlewaresFromConfig failed: %v", err)
+	}
+	defer closeMiddlewares()
+
+	if len(middlewares) != 0 {
+		t.Fatalf("replay middlewares = %v, want none", middlewares)
+	}
+	if len(cfg.Servers) != 1 {
+		t.Fatal("replayProxyConfig modified the loaded config")
+	}
+}
+
+func TestSessionRecordingFromEnvRejectsBothModes(t *testing.T) {
+	t.Setenv(recordEnv, "a.jsonl")
+	t.Setenv(replayEnv, "b.jsonl")
+
+	if _, _, err := sessionRecordingFromEnv(); !errors.Is(err, errInvalidSetting) {
+		t.Fatalf("sessionRecordingFromEnv error = %v, want errInvalidSetting", err)
+	}
+}
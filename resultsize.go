@@ -0,0 +1,301 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"math"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	policyResultSize = "result-size"
+
+	resultSizeOnOversizeTruncate = "truncate"
+	resultSizeOnOversizeReject   = "reject"
+
+	// resultTruncatedMetaKey is the _meta entry describing a truncated result.
+	resultTruncatedMetaKey = "gh-mcp/truncated"
+)
+
+// resultSizeConfig caps the size of tools/call results.
+type resultSizeConfig struct {
+	// MaxSize, like "256KiB", applies to tools without a matching rule.
+	MaxSize string `json:"maxSize"`
+	// OnOversize is "truncate" (default), which shortens text content, or "reject".
+	OnOversize string `json:"onOversize"`
+	// Tools apply to tool names matching their pattern; the first match wins.
+	Tools []toolResultSizeRule `json:"tools"`
+}
+
+// toolResultSizeRule overrides the limit for some tools. Empty fields inherit
+// the global setting.
+type toolResultSizeRule struct {
+	Name       string `json:"name"`
+	MaxSize    string `json:"maxSize"`
+	OnOversize string `json:"onOversize"`
+}
+
+func (c resultSizeConfig) empty() bool {
+	return c.MaxSize == "" && len(c.Tools) == 0
+}
+
+func (c resultSizeConfig) validate() error {
+	if err := validateResultSizeRule("resultSize", c.MaxSize, c.OnOversize); err != nil {
+		return err
+	}
+	for i, rule := range c.Tools {
+		field := fmt.Sprintf("resultSize.tools[%d]", i)
+		if err := validateGlobs(field+".name", []string{rule.Name}); err != nil {
+			return err
+		}
+		if err := validateResultSizeRule(field, rule.MaxSize, rule.OnOversize); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func validateResultSizeRule(field, maxSize, onOversize string) error {
+	if maxSize != "" {
+		if _, err := parseByteSize(maxSize); err != nil {
+			return fmt.Errorf("%s.maxSize: %w", field, err)
+		}
+	}
+
+	switch onOversize {
+	case "", resultSizeOnOversizeTruncate, resultSizeOnOversizeReject:
+		return nil
+	default:
+		return fmt.Errorf("%s.onOversize: unknown action %q", field, onOversize)
+	}
+}
+
+// resultSizeLimit is the limit in effect for one tool. Zero max means unlimited.
+type resultSizeLimit struct {
+	max    int
+	reject bool
+}
+
+// resultLimiter enforces resultSizeConfig on tools/call responses.
+type resultLimiter struct {
+	passthroughMiddleware
+
+	fallback resultSizeLimit
+	rules    []toolResultSizeRule
+	// maxMessageSize is the proxy's message size limit, which caps every
+	// result size limit.
+	maxMessageSize int
+}
+
+// newResultLimiter returns nil when no result size is limited.
+func newResultLimiter(cfg resultSizeConfig, maxMessageSize int) *resultLimiter {
+	if cfg.empty() {
+		return nil
+	}
+
+	return &resultLimiter{
+		fallback:       resolveResultSizeLimit(resultSizeLimit{}, cfg.MaxSize, cfg.OnOversize, maxMessageSize),
+		rules:          cfg.Tools,
+		maxMessageSize: maxMessageSize,
+	}
+}
+
+// resolveResultSizeLimit applies the set fields of a rule to base, capping
+// the size at maxMessageSize. Values were validated when the config was
+// loaded.
+func resolveResultSizeLimit(base resultSizeLimit, maxSize, onOversize string, maxMessageSize int) resultSizeLimit {
+	if maxSize != "" {
+		size, _ := parseByteSize(maxSize)
+		base.max = int(min(size, uint64(maxMessageSize)))
+	}
+	if onOversize != "" {
+		base.reject = onOversize == resultSizeOnOversizeReject
+	}
+
+	return base
+}
+
+func (l *resultLimiter) limitFor(tool string) resultSizeLimit {
+	for _, rule := range l.rules {
+		if matchesAnyGlob([]string{rule.Name}, tool) {
+			return resolveResultSizeLimit(l.fallback, rule.MaxSize, rule.OnOversize, l.maxMessageSize)
+		}
+	}
+
+	return l.fallback
+}
+
+func (l *resultLimiter) serverToClient(p *stdioProxy, next proxyHandler) proxyHandler {
+	return func(ctx context.Context, msg *rpcMessage) error {
+		if !msg.isResponse() || msg.Error != nil || msg.call == nil {
+			return next(ctx, msg)
+		}
+		call, ok := msg.call.request.toolCall()
+		if !ok {
+			return next(ctx, msg)
+		}
+
+		limit := l.limitFor(call.Name)
+		size := len(msg.Result)
+		if limit.max == 0 || size <= limit.max {
+			return next(ctx, msg)
+		}
+
+		if !limit.reject {
+			err := truncateToolResult(msg, limit.max)
+			if err == nil {
+				slog.InfoContext(ctx, "Truncated oversized tool result",
+					"tool", call.Name, "size", size, "max_size", limit.max)
+				return next(ctx, msg)
+			}
+			slog.WarnContext(ctx, "Failed to truncate oversized tool result", "tool", call.Name, "err", err)
+		}
+
+		slog.InfoContext(ctx, "Refused oversized tool result", "tool", call.Name, "size", size, "max_size", limit.max)
+		reason := fmt.Sprintf("result of %d bytes exceeds the %d byte limit", size, limit.max)
+		return p.replyError(ctx, msg.call.request, rpcCodePolicyDenied, "tool "+call.Name+" "+reason,
+			policyDenial{Policy: policyResultSize, Tool: call.Name, Reason: reason})
+	}
+}
+
+// truncateToolResult shortens the text content blocks of a tools/call result
+// so the result fits in maxSize, marking each cut and recording the original
+// size under _meta. Structured content cannot be cut meaningfully and is
+// dropped. It fails when the result would not fit even without text.
+func truncateToolResult(msg *rpcMessage, maxSize int) error {
+	r, err := parseTruncatableResult(msg.Result)
+	if err != nil {
+		return err
+	}
+
+	info, err := json.Marshal(map[string]int{"originalSize": len(msg.Result), "maxSize": maxSize})
+	if err != nil {
+		return fmt.Errorf("failed to encode truncation metadata: %w", err)
+	}
+	r.meta[resultTruncatedMetaKey] = info
+
+	// Find the largest text budget that fits; escaping makes the encoded size
+	// differ from the text byte count.
+	if r.encodedSize(0) > maxSize {
+		return fmt.Errorf("result does not fit in %d bytes", maxSize)
+	}
+	lo, hi := 0, r.textBytes
+	for lo < hi {
+		mid := lo + (hi-lo+1)/2
+		if r.encodedSize(mid) <= maxSize {
+			lo = mid
+		} else {
+			hi = mid - 1
+		}
+	}
+
+	return msg.setResult(r.build(lo))
+}
+
+// truncatableResult is a decoded tools/call result whose text content can be
+// cut to a byte budget.
+type truncatableResult struct {
+	fields    map[string]json.RawMessage
+	content   []map[string]json.RawMessage
+	meta      map[string]json.RawMessage
+	texts     map[int]string
+	textBytes int
+}
+
+func parseTruncatableResult(data json.RawMessage) (*truncatableResult, error) {
+	r := &truncatableResult{texts: make(map[int]string)}
+	if err := json.Unmarshal(data, &r.fields); err != nil {
+		return nil, fmt.Errorf("failed to decode tools/call result: %w", err)
+	}
+	if err := json.Unmarshal(r.fields["content"], &r.content); err != nil {
+		return nil, fmt.Errorf("failed to decode tools/call content: %w", err)
+	}
+	if raw, ok := r.fields["_meta"]; ok {
+		if err := json.Unmarshal(raw, &r.meta); err != nil {
+			return nil, fmt.Errorf("failed to decode tools/call _meta: %w", err)
+		}
+	}
+	if r.meta == nil {
+		r.meta = make(map[string]json.RawMessage)
+	}
+	delete(r.fields, "structuredContent")
+
+	for i, block := range r.content {
+		var kind, text string
+		_ = json.Unmarshal(block["type"], &kind)
+		if kind != "text" || json.Unmarshal(block["text"], &text) != nil {
+			continue
+		}
+		r.texts[i] = text
+		r.textBytes += len(text)
+	}
+
+	return r, nil
+}
+
+func (r *truncatableResult) encodedSize(budget int) int {
+	data, err := json.Marshal(r.build(budget))
+	if err != nil {
+		return math.MaxInt
+	}
+
+	return len(data)
+}
+
+// build returns the result with at most budget bytes of text.
+func (r *truncatableResult) build(budget int) map[string]any {
+	out := make(map[string]any, len(r.fields)+1)
+	for k, v := range r.fields {
+		out[k] = v
+	}
+	out["_meta"] = r.meta
+
+	blocks := make([]map[string]any, len(r.content))
+	remaining := budget
+	for i, block := range r.content {
+		b := make(map[string]any, len(block))
+		for k, v := range block {
+			b[k] = v
+		}
+		blocks[i] = b
+
+		text, ok := r.texts[i]
+		if !ok {
+			continue
+		}
+		if len(text) <= remaining {
+			remaining -= len(text)
+			continue
+		}
+		b["text"] = truncateText(text, remaining) + truncationMarker(remaining, len(text))
+		remaining = 0
+	}
+	out["content"] = blocks
+
+	return out
+}
+
+func truncateText(text string, n int) string {
+	if n >= len(text) {
+		return text
+	}
+	for n > 0 && !utf8.RuneStart(text[n]) {
+		n--
+	}
+
+	return text[:n]
+}
+
+func truncationMarker(kept, original int) string {
+	var b strings.Builder
+	if kept > 0 {
+		b.WriteString("\n\n")
+	}
+	fmt.Fprintf(&b, "[gh-mcp: truncated, showing %d of %d bytes]", kept, original)
+
+	return b.String()
+}
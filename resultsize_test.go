@@ -0,0 +1,132 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func largeToolResult(id int, text string) []byte {
+	result, _ := json.Marshal(map[string]any{
+		"content":           []map[string]any{{"type": "text", "text": text}, {"type": "image", "data": "aW1n"}},
+		"structuredContent": map[string]any{"text": text},
+	})
+
+	return []byte(`{"jsonrpc":"2.0","id":` + strconv.Itoa(id) + `,"result":` + string(result) + `}`)
+}
+
+func TestResultLimiterTruncatesTextContent(t *testing.T) {
+	limiter := newResultLimiter(resultSizeConfig{MaxSize: "1KiB"}, defaultProxyMaxMessageSize)
+	proxy, _, client := newTestProxy(t, defaultProxyMaxMessageSize, limiter)
+
+	ctx := context.Background()
+	proxy.handleClientLine(ctx, testToolCallLine(1, "get_file_contents"))
+	text := strings.Repeat("line \"quoted\" é\n", 500)
+	if err := proxy.handleServerLine(ctx, largeToolResult(1, text)); err != nil {
+		t.Fatalf("handleServerLine failed: %v", err)
+	}
+
+	var resp rpcMessage
+	if err := json.Unmarshal([]byte(client.String()), &resp); err != nil {
+		t.Fatalf("client received invalid response: %v", err)
+	}
+	if len(resp.Result) > 1024 {
+		t.Fatalf("result is %d bytes, want at most 1024", len(resp.Result))
+	}
+
+	var result struct {
+		Content []struct {
+			Type string `json:"type"`
+			Text string `json:"text"`
+			Data string `json:"data"`
+		} `json:"content"`
+		StructuredContent json.RawMessage `json:"structuredContent"`
+		Meta              map[string]struct {
+			OriginalSize int `json:"originalSize"`
+			MaxSize      int `json:"maxSize"`
+		} `json:"_meta"`
+	}
+	if err := json.Unmarshal(resp.Result, &result); err != nil {
+		t.Fatalf("invalid result %s: %v", resp.Result, err)
+	}
+	if len(result.Content) != 2 || result.Content[1].Data != "aW1n" {
+		t.Fatalf("non-text content was not preserved: %+v", result.Content)
+	}
+	got := result.Content[0].Text
+	if !strings.HasPrefix(text, strings.SplitN(got, "\n\n[gh-mcp: truncated", 2)[0]) ||
+		!strings.Contains(got, "[gh-mcp: truncated, showing ") {
+		t.Fatalf("text was not truncated with a marker: %q", got)
+	}
+	if result.StructuredContent != nil {
+		t.Fatalf("structuredContent was kept: %s", result.StructuredContent)
+	}
+	if meta := result.Meta[resultTruncatedMetaKey]; meta.MaxSize != 1024 || meta.OriginalSize <= 1024 {
+		t.Fatalf("unexpected truncation metadata: %+v", result.Meta)
+	}
+}
+
+func TestResultLimiterPerToolOverrides(t *testing.T) {
+	limiter := newResultLimiter(resultSizeConfig{
+		MaxSize: "1KiB",
+		Tools: []toolResultSizeRule{
+			{Name: "search_*", OnOversize: resultSizeOnOversizeReject},
+			{Name: "get_job_logs", MaxSize: "1MiB"},
+		},
+	}, defaultProxyMaxMessageSize)
+	proxy, _, client := newTestProxy(t, defaultProxyMaxMessageSize, limiter)
+
+	ctx := context.Background()
+	text := strings.Repeat("x", 4096)
+	proxy.handleClientLine(ctx, testToolCallLine(1, "search_code"))
+	proxy.handleClientLine(ctx, testToolCallLine(2, "get_job_logs"))
+	for id := 1; id <= 2; id++ {
+		if err := proxy.handleServerLine(ctx, largeToolResult(id, text)); err != nil {
+			t.Fatalf("handleServerLine failed: %v", err)
+		}
+	}
+
+	lines := strings.Split(strings.TrimSpace(client.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("client received %d lines, want 2", len(lines))
+	}
+	if !strings.Contains(lines[0], `"policy":"result-size"`) || !strings.Contains(lines[0], `"id":1`) {
+		t.Fatalf("oversized search result was not rejected: %.200s", lines[0])
+	}
+	if want := string(largeToolResult(2, text)); lines[1] != want {
+		t.Fatalf("result under its tool limit was modified: %.200s", lines[1])
+	}
+}
+
+func TestResultLimiterCapsAtProxyMessageSize(t *testing.T) {
+	limiter := newResultLimiter(resultSizeConfig{MaxSize: "1MiB"}, 2048)
+	proxy, _, client := newTestProxy(t, 2048, limiter)
+
+	ctx := context.Background()
+	proxy.handleClientLine(ctx, testToolCallLine(1, "get_job_logs"))
+	if err := proxy.handleServerLine(ctx, largeToolResult(1, strings.Repeat("x", 1500))); err != nil {
+		t.Fatalf("handleServerLine failed: %v", err)
+	}
+
+	if got := client.String(); !strings.Contains(got, `"maxSize":2048`) || len(got) > 2048+64 {
+		t.Fatalf("result was not limited to the proxy message size: %.200s", got)
+	}
+}
+
+func TestParseProxyConfigResultSize(t *testing.T) {
+	if _, err := parseProxyConfig([]byte(`{"resultSize":{"maxSize":"256KiB",` +
+		`"tools":[{"name":"get_file_contents","maxSize":"1MiB","onOversize":"reject"}]}}`)); err != nil {
+		t.Fatalf("parseProxyConfig failed: %v", err)
+	}
+
+	for _, data := range []string{
+		`{"resultSize":{"maxSize":"big"}}`,
+		`{"resultSize":{"onOversize":"drop"}}`,
+		`{"resultSize":{"tools":[{"name":"[","maxSize":"1KiB"}]}}`,
+	} {
+		if _, err := parseProxyConfig([]byte(data)); err == nil {
+			t.Fatalf("parseProxyConfig(%s) succeeded, want error", data)
+		}
+	}
+}
@@ -0,0 +1,227 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+const (
+	jsonRPCVersion = "2.0"
+
+	rpcCodeParseError     = -32700
+	rpcCodeInvalidRequest = -32600
+	rpcCodeInternalError  = -32603
+
+	methodInitialize  = "initialize"
+	methodInitialized = "notifications/initialized"
+	methodCancelled   = "notifications/cancelled"
+)
+
+// errNotJSONRPC is returned for lines that are not JSON-RPC messages.
+var errNotJSONRPC = errors.New("not a JSON-RPC message")
+
+// rpcMessage is one JSON-RPC request, notification or response. Messages the
+// proxy does not change are written out as the exact bytes that were read.
+type rpcMessage struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id,omitempty"`
+	Method  string          `json:"method,omitempty"`
+	Params  json.RawMessage `json:"params,omitempty"`
+	Result  json.RawMessage `json:"result,omitempty"`
+	Error   *rpcError       `json:"error,omitempty"`
+
+	// raw is the message as read, including its line terminator.
+	raw      []byte
+	modified bool
+	// call is set on client requests and on the responses that answer them.
+	call *pendingCall
+}
+
+// rpcError is a JSON-RPC error object.
+type rpcError struct {
+	Code    int             `json:"code"`
+	Message string          `json:"message"`
+	Data    json.RawMessage `json:"data,omitempty"`
+}
+
+// parseRPCLine parses one line into its messages. batch reports whether the
+// line held a JSON array of messages.
+func parseRPCLine(line []byte) ([]*rpcMessage, bool, error) {
+	trimmed := bytes.TrimSpace(line)
+	if len(trimmed) == 0 {
+		return nil, false, errNotJSONRPC
+	}
+
+	if trimmed[0] != '[' {
+		msg, err := parseRPCMessage(trimmed)
+		if err != nil {
+			return nil, false, err
+		}
+		msg.raw = line
+		return []*rpcMessage{msg}, false, nil
+	}
+
+	var items []json.RawMessage
+	if err := json.Unmarshal(trimmed, &items); err != nil || len(items) == 0 {
+		return nil, false, errNotJSONRPC
+	}
+
+	msgs := make([]*rpcMessage, 0, len(items))
+	for _, item := range items {
+		msg, err := parseRPCMessage(item)
+		if err != nil {
+			return nil, false, err
+		}
+		msg.raw = append(append([]byte(nil), item...), '\n')
+		msgs = append(msgs, msg)
+	}
+
+	return msgs, true, nil
+}
+
+func parseRPCMessage(data []byte) (*rpcMessage, error) {
+	var msg rpcMessage
+	if err := json.Unmarshal(data, &msg); err != nil {
+		return nil, fmt.Errorf("%w: %w", errNotJSONRPC, err)
+	}
+	if msg.JSONRPC != jsonRPCVersion || msg.Method == "" && len(msg.ID) == 0 {
+		return nil, errNotJSONRPC
+	}
+
+	return &msg, nil
+}
+
+// frameID reads the top-level "id" of a JSON-RPC object from the start of a
+// line, or returns nil when it is not found there.
+func frameID(head []byte) json.RawMessage {
+	dec := json.NewDecoder(bytes.NewReader(bytes.TrimSpace(head)))
+	if tok, err := dec.Token(); err != nil || tok != json.Delim('{') {
+		return nil
+	}
+
+	for dec.More() {
+		key, err := dec.Token()
+		if err != nil {
+			return nil
+		}
+		var value json.RawMessage
+		if err := dec.Decode(&value); err != nil {
+			return nil
+		}
+		if key == "id" {
+			return value
+		}
+	}
+
+	return nil
+}
+
+func (m *rpcMessage) isRequest() bool {
+	return m.Method != "" && len(m.ID) > 0
+}
+
+func (m *rpcMessage) isNotification() bool {
+	return m.Method != "" && len(m.ID) == 0
+}
+
+func (m *rpcMessage) isResponse() bool {
+	return m.Method == "" && len(m.ID) > 0
+}
+
+// idKey identifies the message's request ID in pending call maps.
+func (m *rpcMessage) idKey() string {
+	return string(bytes.TrimSpace(m.ID))
+}
+
+// bytes returns the line to write for the message.
+func (m *rpcMessage) bytes() ([]byte, error) {
+	if !m.modified && m.raw != nil {
+		return m.raw, nil
+	}
+
+	data, err := json.Marshal(m)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode JSON-RPC message: %w", err)
+	}
+
+	return append(data, '\n'), nil
+}
+
+// setParams replaces the message params and marks it for re-encoding.
+func (m *rpcMessage) setParams(params any) error {
+	data, err := json.Marshal(params)
+	if err != nil {
+		return fmt.Errorf("failed to encode JSON-RPC params: %w", err)
+	}
+
+	m.Params = data
+	m.modified = true
+
+	return nil
+}
+
+// setResult replaces the message result and marks it for re-encoding.
+func (m *rpcMessage) setResult(result any) error {
+	data, err := json.Marshal(result)
+	if err != nil {
+		return fmt.Errorf("failed to encode JSON-RPC result: %w", err)
+	}
+
+	m.Result = data
+	m.modified = true
+
+	return nil
+}
+
+func newRPCRequest(id json.RawMessage, method string, params any) (*rpcMessage, error) {
+	msg := &rpcMessage{JSONRPC: jsonRPCVersion, ID: id, Method: method}
+	if params == nil {
+		msg.modified = true
+		return msg, nil
+	}
+	if err := msg.setParams(params); err != nil {
+		return nil, err
+	}
+
+	return msg, nil
+}
+
+func newRPCResponse(id, result json.RawMessage) *rpcMessage {
+	return &rpcMessage{JSONRPC: jsonRPCVersion, ID: id, Result: result, modified: true}
+}
+
+func newRPCErrorResponse(id json.RawMessage, code int, message string, data any) (*rpcMessage, error) {
+	rpcErr := &rpcError{Code: code, Message: message}
+	if data != nil {
+		encoded, err := json.Marshal(data)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode JSON-RPC error data: %w", err)
+		}
+		rpcErr.Data = encoded
+	}
+
+	return &rpcMessage{JSONRPC: jsonRPCVersion, ID: id, Error: rpcErr, modified: true}, nil
+}
+
+// canonicalJSON re-encodes a JSON value with object keys sorted and numbers
+// kept as written, so values that differ only in key order encode the same.
+// Empty data encodes as null.
+func canonicalJSON(data json.RawMessage) (string, error) {
+	var value any
+	if len(data) > 0 {
+		dec := json.NewDecoder(bytes.NewReader(data))
+		dec.UseNumber()
+		if err := dec.Decode(&value); err != nil {
+			return "", fmt.Errorf("failed to decode JSON: %w", err)
+		}
+	}
+
+	canonical, err := json.Marshal(value)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode JSON: %w", err)
+	}
+
+	return string(canonical), nil
+}
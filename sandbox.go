@@ -0,0 +1,126 @@
+package main
+
+import (
+	"errors"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+const (
+	sandboxEnv = "GH_MCP_SANDBOX"
+
+	// DNS over TCP is used for large responses and by some resolvers.
+	dnsPort   = 53
+	httpPort  = 80
+	httpsPort = 443
+	socksPort = 1080
+)
+
+// errSandboxUnsupported is returned when the platform or kernel lacks a sandbox feature.
+var errSandboxUnsupported = errors.New("sandbox feature not supported")
+
+// systemCertPaths are the CA bundle locations Go's crypto/x509 checks on Linux.
+var systemCertPaths = []string{
+	"/etc/ssl/certs/ca-certificates.crt",
+	"/etc/pki/tls/certs/ca-bundle.crt",
+	"/etc/ssl/ca-bundle.pem",
+	"/etc/pki/tls/cacert.pem",
+	"/etc/pki/ca-trust/extracted/pem/tls-ca-bundle.pem",
+	"/etc/ssl/cert.pem",
+	"/etc/ssl/certs",
+	"/etc/pki/tls/certs",
+}
+
+// nameResolutionPaths are read by Go's resolver to reach the GitHub host.
+var nameResolutionPaths = []string{
+	"/etc/resolv.conf",
+	"/etc/hosts",
+	"/etc/nsswitch.conf",
+	"/etc/host.conf",
+	"/etc/gai.conf",
+}
+
+// sandboxPolicy is what the sandboxed server may access. Paths that do not
+// exist when the sandbox is applied are skipped.
+type sandboxPolicy struct {
+	execPaths    []string
+	readPaths    []string
+	writePaths   []string
+	connectPorts []uint16
+}
+
+func sandboxEnabledFromEnv() (bool, error) {
+	return boolSettingFromEnv(sandboxEnv, false)
+}
+
+// buildSandboxPolicy allows the extraction directory, CA certificates, name
+// resolution files and temp dirs, and TCP connections to the GitHub host,
+// configured proxies and DNS.
+func buildSandboxPolicy(binaryPath string, serverEnv []string) *sandboxPolicy {
+	policy := &sandboxPolicy{
+		execPaths:  []string{filepath.Dir(binaryPath)},
+		readPaths:  slices.Concat(systemCertPaths, nameResolutionPaths),
+		writePaths: []string{os.TempDir(), os.DevNull},
+	}
+
+	if certFile := os.Getenv("SSL_CERT_FILE"); certFile != "" {
+		policy.readPaths = append(policy.readPaths, certFile)
+	}
+	if certDirs := os.Getenv("SSL_CERT_DIR"); certDirs != "" {
+		for dir := range strings.SplitSeq(certDirs, string(os.PathListSeparator)) {
+			if dir != "" {
+				policy.readPaths = append(policy.readPaths, dir)
+			}
+		}
+	}
+
+	policy.addConnectPort(dnsPort)
+	policy.addConnectURL(serverEnvValue(serverEnv, "GITHUB_HOST"))
+	for _, key := range allowedParentEnvKeys {
+		if strings.HasSuffix(strings.ToUpper(key), "_PROXY") && !strings.EqualFold(key, "NO_PROXY") {
+			policy.addConnectURL(os.Getenv(key))
+		}
+	}
+
+	return policy
+}
+
+func (p *sandboxPolicy) addConnectURL(raw string) {
+	if raw == "" {
+		return
+	}
+	if !strings.Contains(raw, "://") {
+		raw = "https://" + raw
+	}
+
+	u, err := url.Parse(raw)
+	if err != nil {
+		return
+	}
+
+	if port := u.Port(); port != "" {
+		if n, err := strconv.ParseUint(port, 10, 16); err == nil {
+			p.addConnectPort(uint16(n))
+		}
+		return
+	}
+
+	switch strings.ToLower(u.Scheme) {
+	case "http":
+		p.addConnectPort(httpPort)
+	case "socks5", "socks5h":
+		p.addConnectPort(socksPort)
+	default:
+		p.addConnectPort(httpsPort)
+	}
+}
+
+func (p *sandboxPolicy) addConnectPort(port uint16) {
+	if !slices.Contains(p.connectPorts, port) {
+		p.connectPorts = append(p.connectPorts, port)
+	}
+}
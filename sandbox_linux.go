@@ -0,0 +1,309 @@
+//go:build linux
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"runtime"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+const (
+	// sandboxSupported reports whether GH_MCP_SANDBOX has an effect on this platform.
+	sandboxSupported = true
+
+	landlockRuleNetPort = 2
+	landlockABINet      = 4
+
+	landlockFSRightsV1 = unix.LANDLOCK_ACCESS_FS_EXECUTE |
+		unix.LANDLOCK_ACCESS_FS_WRITE_FILE |
+		unix.LANDLOCK_ACCESS_FS_READ_FILE |
+		unix.LANDLOCK_ACCESS_FS_READ_DIR |
+		unix.LANDLOCK_ACCESS_FS_REMOVE_DIR |
+		unix.LANDLOCK_ACCESS_FS_REMOVE_FILE |
+		unix.LANDLOCK_ACCESS_FS_MAKE_CHAR |
+		unix.LANDLOCK_ACCESS_FS_MAKE_DIR |
+		unix.LANDLOCK_ACCESS_FS_MAKE_REG |
+		unix.LANDLOCK_ACCESS_FS_MAKE_SOCK |
+		unix.LANDLOCK_ACCESS_FS_MAKE_FIFO |
+		unix.LANDLOCK_ACCESS_FS_MAKE_BLOCK |
+		unix.LANDLOCK_ACCESS_FS_MAKE_SYM
+
+	// Rights that may be granted on a regular file rather than a directory.
+	landlockFileRights = unix.LANDLOCK_ACCESS_FS_EXECUTE |
+		unix.LANDLOCK_ACCESS_FS_WRITE_FILE |
+		unix.LANDLOCK_ACCESS_FS_READ_FILE |
+		unix.LANDLOCK_ACCESS_FS_TRUNCATE |
+		unix.LANDLOCK_ACCESS_FS_IOCTL_DEV
+
+	landlockReadRights = unix.LANDLOCK_ACCESS_FS_READ_FILE | unix.LANDLOCK_ACCESS_FS_READ_DIR
+	landlockExecRights = landlockReadRights | unix.LANDLOCK_ACCESS_FS_EXECUTE
+
+	seccompDataNROffset   = 0
+	seccompDataArchOffset = 4
+	seccompDataArg0Offset = 16
+)
+
+// landlockNetPortAttr mirrors struct landlock_net_port_attr.
+type landlockNetPortAttr struct {
+	allowedAccess uint64
+	port          uint64
+}
+
+// applySandbox restricts the launcher thread with Landlock and seccomp. The
+// restrictions survive the following exec, so the server starts confined.
+// Missing kernel support is reported on stderr and skipped.
+func applySandbox(policy *sandboxPolicy) error {
+	// Landlock and seccomp apply to the calling thread; it must be the one that execs.
+	runtime.LockOSThread()
+
+	if err := unix.Prctl(unix.PR_SET_NO_NEW_PRIVS, 1, 0, 0, 0); err != nil {
+		return fmt.Errorf("failed to set no_new_privs: %w", err)
+	}
+
+	if err := applyLandlock(policy); err != nil {
+		if !errors.Is(err, errSandboxUnsupported) {
+			return err
+		}
+		fmt.Fprintf(os.Stderr, "gh-mcp: sandbox: %v; continuing without filesystem restrictions\n", err)
+	}
+
+	if err := applySeccomp(); err != nil {
+		if !errors.Is(err, errSandboxUnsupported) {
+			return err
+		}
+		fmt.Fprintf(os.Stderr, "gh-mcp: sandbox: %v; continuing without syscall filter\n", err)
+	}
+
+	return nil
+}
+
+func landlockABI() (int, error) {
+	abi, _, errno := unix.Syscall(
+		unix.SYS_LANDLOCK_CREATE_RULESET,
+		0,
+		0,
+		unix.LANDLOCK_CREATE_RULESET_VERSION,
+	)
+	if errno != 0 {
+		if errno == unix.ENOSYS || errno == unix.EOPNOTSUPP {
+			return 0, fmt.Errorf("%w: landlock: %w", errSandboxUnsupported, errno)
+		}
+		return 0, fmt.Errorf("failed to query landlock ABI: %w", errno)
+	}
+
+	return int(abi), nil
+}
+
+func landlockHandledFSRights(abi int) uint64 {
+	rights := uint64(landlockFSRightsV1)
+	if abi >= 2 {
+		rights |= unix.LANDLOCK_ACCESS_FS_REFER
+	}
+	if abi >= 3 {
+		rights |= unix.LANDLOCK_ACCESS_FS_TRUNCATE
+	}
+	if abi >= 5 {
+		rights |= unix.LANDLOCK_ACCESS_FS_IOCTL_DEV
+	}
+
+	return rights
+}
+
+func applyLandlock(policy *sandboxPolicy) error {
+	abi, err := landlockABI()
+	if err != nil {
+		return err
+	}
+
+	handledFS := landlockHandledFSRights(abi)
+	attr := unix.LandlockRulesetAttr{Access_fs: handledFS}
+	if abi >= landlockABINet {
+		attr.Access_net = unix.LANDLOCK_ACCESS_NET_CONNECT_TCP | unix.LANDLOCK_ACCESS_NET_BIND_TCP
+	}
+
+	// #nosec G103 -- landlock_create_ruleset takes a pointer to the ruleset attributes
+	rulesetFD, _, errno := unix.Syscall(
+		unix.SYS_LANDLOCK_CREATE_RULESET,
+		uintptr(unsafe.Pointer(&attr)),
+		unsafe.Sizeof(attr),
+		0,
+	)
+	if errno != 0 {
+		return fmt.Errorf("failed to create landlock ruleset: %w", errno)
+	}
+	defer func() { _ = unix.Close(int(rulesetFD)) }()
+
+	grants := []struct {
+		paths  []string
+		rights uint64
+	}{
+		{policy.execPaths, landlockExecRights},
+		{policy.readPaths, landlockReadRights},
+		{policy.writePaths, handledFS},
+	}
+	for _, grant := range grants {
+		for _, path := range grant.paths {
+			if err := addLandlockPathRule(int(rulesetFD), path, grant.rights&handledFS); err != nil {
+				return err
+			}
+		}
+	}
+
+	if abi >= landlockABINet {
+		for _, port := range policy.connectPorts {
+			if err := addLandlockPortRule(int(rulesetFD), port); err != nil {
+				return err
+			}
+		}
+	}
+
+	if _, _, errno := unix.Syscall(unix.SYS_LANDLOCK_RESTRICT_SELF, rulesetFD, 0, 0); errno != 0 {
+		return fmt.Errorf("failed to enforce landlock ruleset: %w", errno)
+	}
+
+	return nil
+}
+
+func addLandlockPathRule(rulesetFD int, path string, rights uint64) error {
+	fd, err := unix.Open(path, unix.O_PATH|unix.O_CLOEXEC, 0)
+	if err != nil {
+		if errors.Is(err, unix.ENOENT) || errors.Is(err, unix.ENOTDIR) {
+			return nil
+		}
+		return fmt.Errorf("failed to open sandbox path %q: %w", path, err)
+	}
+	defer func() { _ = unix.Close(fd) }()
+
+	var stat unix.Stat_t
+	if err := unix.Fstat(fd, &stat); err != nil {
+		return fmt.Errorf("failed to stat sandbox path %q: %w", path, err)
+	}
+	if stat.Mode&unix.S_IFMT != unix.S_IFDIR {
+		rights &= landlockFileRights
+	}
+
+	// #nosec G115 -- file descriptors are small non-negative integers
+	attr := unix.LandlockPathBeneathAttr{Allowed_access: rights, Parent_fd: int32(fd)}
+	// #nosec G103 -- landlock_add_rule takes a pointer to the rule attributes
+	if _, _, errno := unix.Syscall6(
+		unix.SYS_LANDLOCK_ADD_RULE,
+		uintptr(rulesetFD),
+		unix.LANDLOCK_RULE_PATH_BENEATH,
+		uintptr(unsafe.Pointer(&attr)),
+		0, 0, 0,
+	); errno != 0 {
+		return fmt.Errorf("failed to add landlock rule for %q: %w", path, errno)
+	}
+
+	return nil
+}
+
+func addLandlockPortRule(rulesetFD int, port uint16) error {
+	attr := landlockNetPortAttr{
+		allowedAccess: unix.LANDLOCK_ACCESS_NET_CONNECT_TCP,
+		port:          uint64(port),
+	}
+	// #nosec G103 -- landlock_add_rule takes a pointer to the rule attributes
+	if _, _, errno := unix.Syscall6(
+		unix.SYS_LANDLOCK_ADD_RULE,
+		uintptr(rulesetFD),
+		landlockRuleNetPort,
+		uintptr(unsafe.Pointer(&attr)),
+		0, 0, 0,
+	); errno != 0 {
+		return fmt.Errorf("failed to add landlock rule for port %d: %w", port, errno)
+	}
+
+	return nil
+}
+
+// seccompFilter builds a BPF program that fails process creation and ptrace
+// with EPERM. Thread creation (clone with CLONE_THREAD) stays allowed, and
+// clone3 reports ENOSYS so runtimes fall back to clone.
+func seccompFilter() []unix.SockFilter {
+	const (
+		ldAbs = unix.BPF_LD | unix.BPF_W | unix.BPF_ABS
+		jeq   = unix.BPF_JMP | unix.BPF_JEQ | unix.BPF_K
+		jge   = unix.BPF_JMP | unix.BPF_JGE | unix.BPF_K
+		jset  = unix.BPF_JMP | unix.BPF_JSET | unix.BPF_K
+		ret   = unix.BPF_RET | unix.BPF_K
+
+		retAllow  = unix.SECCOMP_RET_ALLOW
+		retEPERM  = unix.SECCOMP_RET_ERRNO | uint32(unix.EPERM)
+		retENOSYS = unix.SECCOMP_RET_ERRNO | uint32(unix.ENOSYS)
+	)
+
+	stmt := func(code uint16, k uint32) unix.SockFilter {
+		return unix.SockFilter{Code: code, K: k}
+	}
+	jump := func(code uint16, k uint32, jt, jf uint8) unix.SockFilter {
+		return unix.SockFilter{Code: code, Jt: jt, Jf: jf, K: k}
+	}
+
+	filter := []unix.SockFilter{
+		// Refuse syscalls from a foreign ABI, whose numbers differ.
+		stmt(ldAbs, seccompDataArchOffset),
+		jump(jeq, seccompAuditArch, 1, 0),
+		stmt(ret, retEPERM),
+		stmt(ldAbs, seccompDataNROffset),
+	}
+	if seccompForeignNRBase != 0 {
+		filter = append(filter,
+			jump(jge, seccompForeignNRBase, 0, 1),
+			stmt(ret, retEPERM),
+		)
+	}
+
+	filter = append(filter,
+		jump(jeq, unix.SYS_CLONE3, 0, 1),
+		stmt(ret, retENOSYS),
+		jump(jeq, unix.SYS_CLONE, 0, 4),
+		stmt(ldAbs, seccompDataArg0Offset),
+		jump(jset, unix.CLONE_THREAD, 0, 1),
+		stmt(ret, retAllow),
+		stmt(ret, retEPERM),
+	)
+	for _, nr := range seccompBlockedSyscalls {
+		filter = append(filter,
+			jump(jeq, nr, 0, 1),
+			stmt(ret, retEPERM),
+		)
+	}
+
+	return append(filter, stmt(ret, retAllow))
+}
+
+func applySeccomp() error {
+	if seccompAuditArch == 0 {
+		return fmt.Errorf("%w: seccomp filter is not available on %s", errSandboxUnsupported, runtime.GOARCH)
+	}
+
+	filter := seccompFilter()
+	prog := unix.SockFprog{
+		// #nosec G115 -- the filter has a few dozen instructions
+		Len:    uint16(len(filter)),
+		Filter: &filter[0],
+	}
+
+	// #nosec G103 -- PR_SET_SECCOMP takes a pointer to the filter program
+	err := unix.Prctl(
+		unix.PR_SET_SECCOMP,
+		unix.SECCOMP_MODE_FILTER,
+		uintptr(unsafe.Pointer(&prog)),
+		0,
+		0,
+	)
+	if err != nil {
+		if errors.Is(err, unix.EINVAL) {
+			return fmt.Errorf("%w: seccomp: %w", errSandboxUnsupported, err)
+		}
+		return fmt.Errorf("failed to install seccomp filter: %w", err)
+	}
+
+	return nil
+}
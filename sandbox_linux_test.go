@@ -0,0 +1,52 @@
+//go:build linux
+
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"golang.org/x/sys/unix"
+)
+
+func TestServerLauncherAppliesSandbox(t *testing.T) {
+	cmd := newServerTestHelperCommand(t, "launch-sandbox")
+	cmd.Env = append(cmd.Env, "GH_MCP_TEST_SANDBOX_DIR="+t.TempDir())
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+
+	out, err := cmd.Output()
+	if err != nil {
+		t.Fatalf("sandboxed launcher failed: %v (stderr: %s)", err, stderr.String())
+	}
+
+	// Kernels without Landlock or seccomp run the server unconfined after a warning.
+	if !strings.Contains(stderr.String(), "without syscall filter") &&
+		!strings.Contains(string(out), "spawn blocked") {
+		t.Fatalf("expected process creation to be blocked, got %q", out)
+	}
+	if !strings.Contains(stderr.String(), "without filesystem restrictions") &&
+		!strings.Contains(string(out), "write blocked") {
+		t.Fatalf("expected writes outside the policy to be blocked, got %q", out)
+	}
+}
+
+func TestSeccompFilterIsWellFormed(t *testing.T) {
+	const bpfClassMask = 0x07
+	filter := seccompFilter()
+
+	for i, ins := range filter {
+		if ins.Code&bpfClassMask != unix.BPF_JMP {
+			continue
+		}
+		if int(ins.Jt)+i+1 >= len(filter) || int(ins.Jf)+i+1 >= len(filter) {
+			t.Fatalf("instruction %d jumps past the end of the filter", i)
+		}
+	}
+
+	last := filter[len(filter)-1]
+	if last.Code != unix.BPF_RET|unix.BPF_K || last.K != unix.SECCOMP_RET_ALLOW {
+		t.Fatalf("filter must end by allowing the syscall, got %+v", last)
+	}
+}
@@ -0,0 +1,12 @@
+//go:build !linux
+
+package main
+
+import "fmt"
+
+// sandboxSupported reports whether GH_MCP_SANDBOX has an effect on this platform.
+const sandboxSupported = false
+
+func applySandbox(_ *sandboxPolicy) error {
+	return fmt.Errorf("%w: sandbox requires Linux", errSandboxUnsupported)
+}
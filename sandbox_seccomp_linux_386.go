@@ -0,0 +1,12 @@
+//go:build linux && 386
+
+package main
+
+import "golang.org/x/sys/unix"
+
+const (
+	seccompAuditArch     = unix.AUDIT_ARCH_I386
+	seccompForeignNRBase = 0
+)
+
+var seccompBlockedSyscalls = []uint32{unix.SYS_FORK, unix.SYS_VFORK, unix.SYS_PTRACE}
@@ -0,0 +1,13 @@
+//go:build linux && amd64
+
+package main
+
+import "golang.org/x/sys/unix"
+
+const (
+	seccompAuditArch = unix.AUDIT_ARCH_X86_64
+	// x32 syscalls share the x86-64 audit arch but set bit 30 in the number.
+	seccompForeignNRBase = 0x40000000
+)
+
+var seccompBlockedSyscalls = []uint32{unix.SYS_FORK, unix.SYS_VFORK, unix.SYS_PTRACE}
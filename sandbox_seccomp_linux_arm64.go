@@ -0,0 +1,13 @@
+//go:build linux && arm64
+
+package main
+
+import "golang.org/x/sys/unix"
+
+const (
+	seccompAuditArch     = unix.AUDIT_ARCH_AARCH64
+	seccompForeignNRBase = 0
+)
+
+// arm64 has no fork or vfork syscalls; both go through clone.
+var seccompBlockedSyscalls = []uint32{unix.SYS_PTRACE}
@@ -0,0 +1,11 @@
+//go:build linux && !amd64 && !arm64 && !386
+
+package main
+
+const (
+	// No syscall table is maintained for this architecture; applySeccomp skips the filter.
+	seccompAuditArch     = 0
+	seccompForeignNRBase = 0
+)
+
+var seccompBlockedSyscalls []uint32
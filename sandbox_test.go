@@ -0,0 +1,50 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestBuildSandboxPolicy(t *testing.T) {
+	for _, key := range allowedParentEnvKeys {
+		t.Setenv(key, "")
+	}
+	t.Setenv("HTTPS_PROXY", "http://proxy.example.com:3128")
+	t.Setenv("ALL_PROXY", "socks5://proxy.example.com")
+	t.Setenv("SSL_CERT_FILE", "/opt/certs/ca.pem")
+
+	binaryPath := filepath.Join(os.TempDir(), "gh-mcp-test", "github-mcp-server")
+	policy := buildSandboxPolicy(binaryPath, []string{"GITHUB_HOST=https://ghe.example.com:8443"})
+
+	if !slices.Equal(policy.execPaths, []string{filepath.Dir(binaryPath)}) {
+		t.Fatalf("execPaths = %v, want only the extraction directory", policy.execPaths)
+	}
+	if !slices.Contains(policy.readPaths, "/opt/certs/ca.pem") {
+		t.Fatalf("readPaths = %v, want SSL_CERT_FILE included", policy.readPaths)
+	}
+	if !slices.Contains(policy.writePaths, os.TempDir()) {
+		t.Fatalf("writePaths = %v, want temp dir included", policy.writePaths)
+	}
+
+	for _, port := range []uint16{dnsPort, 8443, 3128, socksPort} {
+		if !slices.Contains(policy.connectPorts, port) {
+			t.Fatalf("connectPorts = %v, want %d included", policy.connectPorts, port)
+		}
+	}
+	if slices.Contains(policy.connectPorts, httpPort) {
+		t.Fatalf("connectPorts = %v, want no port 80 without a plain HTTP endpoint", policy.connectPorts)
+	}
+}
+
+func TestSandboxPolicyDefaultsToHTTPS(t *testing.T) {
+	var policy sandboxPolicy
+	policy.addConnectURL("github.com")
+	policy.addConnectURL("https://github.com")
+	policy.addConnectURL("http://proxy.internal")
+
+	if !slices.Equal(policy.connectPorts, []uint16{httpsPort, httpPort}) {
+		t.Fatalf("connectPorts = %v, want [443 80]", policy.connectPorts)
+	}
+}
@@ -0,0 +1,280 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+)
+
+const (
+	policyScope = "scope"
+
+	searchToolPrefix = "search_"
+)
+
+// Argument names that identify the owner or repository a tool call targets.
+var (
+	scopeOwnerArguments = []string{"owner", "org", "organization"}
+	scopeRepoArguments  = []string{"repo", "repository"}
+	scopeQueryArguments = []string{"query", "q"}
+)
+
+// scopePolicyConfig restricts tool calls to owners and repositories, by glob
+// pattern. Matching is case-insensitive, as GitHub names are.
+type scopePolicyConfig struct {
+	Owners globListConfig `json:"owners"`
+	// Repos patterns have the form "owner/repo".
+	Repos globListConfig `json:"repos"`
+	// RequireScopedSearch refuses search tools whose query has no repo:, org: or user: qualifier.
+	RequireScopedSearch bool `json:"requireScopedSearch"`
+}
+
+func (c scopePolicyConfig) empty() bool {
+	return c.Owners.empty() && c.Repos.empty() && !c.RequireScopedSearch
+}
+
+func (c scopePolicyConfig) validate() error {
+	if err := c.Owners.validate("scope.owners"); err != nil {
+		return err
+	}
+	if err := c.Repos.validate("scope.repos"); err != nil {
+		return err
+	}
+
+	for _, pattern := range append(append([]string(nil), c.Repos.Allow...), c.Repos.Deny...) {
+		if !strings.Contains(pattern, "/") {
+			return fmt.Errorf("scope.repos: pattern %q must have the form owner/repo", pattern)
+		}
+	}
+
+	return nil
+}
+
+// scopeTarget is an owner and optional repository named by a tool call.
+type scopeTarget struct {
+	owner string
+	repo  string
+	// excluded marks a negated search qualifier, which does not scope a search.
+	excluded bool
+}
+
+func (t scopeTarget) fullName() string {
+	return t.owner + "/" + t.repo
+}
+
+// scopeGuard refuses tool calls whose arguments name an owner or repository
+// outside the configured scope.
+type scopeGuard struct {
+	passthroughMiddleware
+
+	owners              globListConfig
+	repos               globListConfig
+	requireScopedSearch bool
+}
+
+// newScopeGuard returns nil when the scope policy is empty.
+func newScopeGuard(cfg scopePolicyConfig) *scopeGuard {
+	if cfg.empty() {
+		return nil
+	}
+
+	return &scopeGuard{
+		owners:              lowerGlobList(cfg.Owners),
+		repos:               lowerGlobList(cfg.Repos),
+		requireScopedSearch: cfg.RequireScopedSearch,
+	}
+}
+
+func lowerGlobList(c globListConfig) globListConfig {
+	lower := func(patterns []string) []string {
+		out := make([]string, len(patterns))
+		for i, p := range patterns {
+			out[i] = strings.ToLower(p)
+		}
+		return out
+	}
+
+	return globListConfig{Allow: lower(c.Allow), Deny: lower(c.Deny)}
+}
+
+func (g *scopeGuard) clientToServer(p *stdioProxy, next proxyHandler) proxyHandler {
+	return func(ctx context.Context, msg *rpcMessage) error {
+		call, ok := msg.toolCall()
+		if !ok {
+			return next(ctx, msg)
+		}
+
+		denial, denied := g.check(call)
+		if !denied {
+			return next(ctx, msg)
+		}
+
+		slog.InfoContext(ctx, "Refused out-of-scope tool call",
+			"tool", call.Name, "owner", denial.Owner, "repo", denial.Repo)
+		return p.replyError(ctx, msg, rpcCodePolicyDenied,
+			"tool call is outside the gh-mcp repository scope: "+denial.Reason, denial)
+	}
+}
+
+// check returns the denial for a call that leaves the scope.
+func (g *scopeGuard) check(call toolCallParams) (policyDenial, bool) {
+	var args map[string]json.RawMessage
+	_ = json.Unmarshal(call.Arguments, &args)
+
+	targets, err := scopeTargetsFromArguments(args)
+	if err != nil {
+		return policyDenial{Policy: policyScope, Tool: call.Name, Reason: err.Error()}, true
+	}
+	if strings.HasPrefix(call.Name, searchToolPrefix) {
+		queries, err := stringArguments(args, scopeQueryArguments)
+		if err != nil {
+			return policyDenial{Policy: policyScope, Tool: call.Name, Reason: err.Error()}, true
+		}
+		var queryTargets []scopeTarget
+		for _, query := range queries {
+			queryTargets = append(queryTargets, scopeTargetsFromQuery(query)...)
+		}
+		if g.requireScopedSearch && len(targets) == 0 && !slices.ContainsFunc(queryTargets, func(t scopeTarget) bool {
+			return !t.excluded
+		}) {
+			return policyDenial{
+				Policy: policyScope,
+				Tool:   call.Name,
+				Reason: "search query has no repo:, org: or user: qualifier",
+			}, true
+		}
+		targets = append(targets, queryTargets...)
+	}
+
+	for _, target := range targets {
+		denial := policyDenial{Policy: policyScope, Tool: call.Name, Owner: target.owner, Repo: target.repo}
+
+		if target.owner != "" && !g.owners.permits(strings.ToLower(target.owner)) {
+			denial.Reason = "owner " + target.owner + " is not allowed"
+			return denial, true
+		}
+		if target.repo != "" && !g.repos.permits(strings.ToLower(target.fullName())) {
+			denial.Reason = "repository " + target.fullName() + " is not allowed"
+			return denial, true
+		}
+	}
+
+	return policyDenial{}, false
+}
+
+// scopeTargetsFromArguments reads every owner and repository argument. A
+// repository argument may also be a full "owner/repo" name. Arguments that
+// name different owners or repositories are an error, as the server may use
+// any of them.
+func scopeTargetsFromArguments(args map[string]json.RawMessage) ([]scopeTarget, error) {
+	owners, err := stringArguments(args, scopeOwnerArguments)
+	if err != nil {
+		return nil, err
+	}
+	repos, err := stringArguments(args, scopeRepoArguments)
+	if err != nil {
+		return nil, err
+	}
+
+	var target scopeTarget
+	for _, owner := range owners {
+		if target.owner != "" && !strings.EqualFold(owner, target.owner) {
+			return nil, fmt.Errorf("arguments name different owners %s and %s", target.owner, owner)
+		}
+		target.owner = owner
+	}
+	for _, repo := range repos {
+		owner, name, ok := strings.Cut(repo, "/")
+		if !ok {
+			owner, name = "", repo
+		}
+		if owner != "" {
+			if target.owner != "" && !strings.EqualFold(owner, target.owner) {
+				return nil, fmt.Errorf("arguments name different owners %s and %s", target.owner, owner)
+			}
+			target.owner = owner
+		}
+		if target.repo != "" && !strings.EqualFold(name, target.repo) {
+			return nil, fmt.Errorf("arguments name different repositories %s and %s", target.repo, name)
+		}
+		target.repo = name
+	}
+
+	if target == (scopeTarget{}) {
+		return nil, nil
+	}
+
+	return []scopeTarget{target}, nil
+}
+
+// scopeTargetsFromQuery reads repo:, org: and user: qualifiers from a GitHub
+// search query, ignoring the grouping parentheses, quotes and operators around
+// them. Excluded qualifiers are returned too, so a query never names an owner
+// outside the scope.
+func scopeTargetsFromQuery(query string) []scopeTarget {
+	var targets []scopeTarget
+	excluded := false
+	for _, term := range strings.Fields(query) {
+		term = strings.TrimLeft(term, "(")
+		if strings.EqualFold(term, "NOT") {
+			excluded = true
+			continue
+		}
+		negated := excluded || strings.HasPrefix(term, "-")
+		excluded = false
+
+		qualifier, value, ok := strings.Cut(strings.TrimLeft(term, "-+("), ":")
+		if !ok {
+			continue
+		}
+		value = strings.Trim(strings.TrimRight(value, "),;"), `"'`)
+		if value == "" {
+			continue
+		}
+
+		switch strings.ToLower(qualifier) {
+		case "repo":
+			owner, repo, _ := strings.Cut(value, "/")
+			targets = append(targets, scopeTarget{owner: owner, repo: repo, excluded: negated})
+		case "org", "user":
+			targets = append(targets, scopeTarget{owner: value, excluded: negated})
+		}
+	}
+
+	return targets
+}
+
+// stringArguments returns the non-empty values of the named arguments. An
+// argument that is present but not a string is an error.
+func stringArguments(args map[string]json.RawMessage, names []string) ([]string, error) {
+	var values []string
+	for _, name := range names {
+		raw, ok := args[name]
+		if !ok || string(raw) == "null" {
+			continue
+		}
+		var value string
+		if err := json.Unmarshal(raw, &value); err != nil {
+			return nil, fmt.Errorf("argument %s is not a string", name)
+		}
+		if value != "" {
+			values = append(values, value)
+		}
+	}
+
+	return values, nil
+}
+
+func firstStringArgument(args map[string]json.RawMessage, names []string) string {
+	for _, name := range names {
+		var value string
+		if err := json.Unmarshal(args[name], &value); err == nil && value != "" {
+			return value
+		}
+	}
+
+	return ""
+}
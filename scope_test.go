@@ -0,0 +1,69 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestScopeGuardCheck(t *testing.T) {
+	guard := newScopeGuard(scopePolicyConfig{
+		Owners:              globListConfig{Allow: []string{"My-Org", "octo-*"}},
+		Repos:               globListConfig{Deny: []string{"my-org/secrets"}},
+		RequireScopedSearch: true,
+	})
+
+	tests := []struct {
+		name       string
+		tool       string
+		arguments  string
+		wantDenied bool
+	}{
+		{name: "allowed owner", tool: "get_issue", arguments: `{"owner":"my-org","repo":"app"}`},
+		{name: "owner glob", tool: "get_issue", arguments: `{"owner":"octo-cat","repo":"app"}`},
+		{name: "other owner", tool: "get_issue", arguments: `{"owner":"evil","repo":"app"}`, wantDenied: true},
+		{name: "denied repo", tool: "get_file_contents", arguments: `{"owner":"MY-ORG","repo":"Secrets"}`, wantDenied: true},
+		{name: "full name", tool: "list_commits", arguments: `{"repository":"evil/app"}`, wantDenied: true},
+		{name: "mismatched owner", tool: "get_issue", arguments: `{"owner":"evil","repo":"my-org/app"}`, wantDenied: true},
+		{name: "org argument", tool: "list_teams", arguments: `{"org":"evil"}`, wantDenied: true},
+		{name: "no target", tool: "get_me", arguments: `{}`},
+		{name: "scoped search", tool: "search_code", arguments: `{"query":"foo repo:my-org/app"}`},
+		{name: "search other repo", tool: "search_code", arguments: `{"query":"foo repo:evil/app"}`, wantDenied: true},
+		{name: "search other org", tool: "search_issues", arguments: `{"query":"is:open org:evil"}`, wantDenied: true},
+		{name: "negated qualifier", tool: "search_code", arguments: `{"query":"foo org:my-org -org:evil"}`, wantDenied: true},
+		{name: "negated allowed qualifier", tool: "search_code", arguments: `{"query":"foo org:my-org -repo:my-org/old"}`},
+		{name: "only negated qualifier", tool: "search_code", arguments: `{"query":"foo -org:my-org"}`, wantDenied: true},
+		{name: "grouped qualifier", tool: "search_code", arguments: `{"query":"foo (repo:evil/x OR repo:my-org/app)"}`, wantDenied: true},
+		{name: "quoted qualifier", tool: "search_code", arguments: `{"query":"foo repo:\"evil/x\""}`, wantDenied: true},
+		{name: "NOT qualifier", tool: "search_code", arguments: `{"query":"org:my-org NOT org:evil"}`, wantDenied: true},
+		{name: "second query alias", tool: "search_code", arguments: `{"query":"repo:my-org/app","q":"repo:evil/x"}`, wantDenied: true},
+		{name: "unscoped search", tool: "search_code", arguments: `{"query":"foo"}`, wantDenied: true},
+		{name: "second owner alias", tool: "list_teams", arguments: `{"owner":"my-org","org":"evil"}`, wantDenied: true},
+		{name: "agreeing aliases", tool: "get_issue", arguments: `{"owner":"my-org","organization":"MY-ORG","repo":"app","repository":"my-org/app"}`},
+		{name: "second repo alias", tool: "get_issue", arguments: `{"owner":"my-org","repo":"app","repository":"secrets"}`, wantDenied: true},
+		{name: "non-string owner", tool: "get_issue", arguments: `{"owner":["evil"],"repo":"app"}`, wantDenied: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			denial, denied := guard.check(toolCallParams{Name: tt.tool, Arguments: json.RawMessage(tt.arguments)})
+			if denied != tt.wantDenied {
+				t.Fatalf("denied = %v (%+v), want %v", denied, denial, tt.wantDenied)
+			}
+			if denied && (denial.Policy != policyScope || denial.Reason == "") {
+				t.Fatalf("unexpected denial: %+v", denial)
+			}
+		})
+	}
+}
+
+func TestScopePolicyValidation(t *testing.T) {
+	if _, err := parseProxyConfig([]byte(`{"scope":{"repos":{"allow":["my-org"]}}}`)); err == nil {
+		t.Fatal("expected repo pattern without owner to be rejected")
+	}
+	if _, err := parseProxyConfig([]byte(`{"scope":{"owners":{"deny":["["]}}}`)); err == nil {
+		t.Fatal("expected invalid owner pattern to be rejected")
+	}
+	if newScopeGuard(scopePolicyConfig{}) != nil {
+		t.Fatal("expected no guard for an empty scope policy")
+	}
+}
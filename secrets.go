@@ -0,0 +1,224 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"regexp"
+)
+
+// secretPattern is a secret format redacted from tool results.
+type secretPattern struct {
+	name string
+	re   *regexp.Regexp
+}
+
+// builtinSecretPatterns are always scanned for when secret redaction is on.
+var builtinSecretPatterns = []secretPattern{
+	{"github-token", regexp.MustCompile(`\b(?:ghp|gho|ghu|ghs|ghr)_[A-Za-z0-9]{36,255}\b`)},
+	{"github-fine-grained-token", regexp.MustCompile(`\bgithub_pat_[A-Za-z0-9_]{22,255}\b`)},
+	{"aws-access-key-id", regexp.MustCompile(`\b(?:AKIA|ASIA|ABIA|ACCA)[A-Z0-9]{16}\b`)},
+	{"aws-secret-access-key", regexp.MustCompile(
+		`(?i)\baws_?secret_?access_?key\b["']?\s*[:=]\s*["']?[A-Za-z0-9/+=]{40}\b`)},
+	{"private-key", regexp.MustCompile(
+		`-----BEGIN[A-Z0-9 ]* PRIVATE KEY(?: BLOCK)?-----[\s\S]*?-----END[A-Z0-9 ]* PRIVATE KEY(?: BLOCK)?-----`)},
+}
+
+// builtinSecretScanner redacts the built-in formats, for example from audit records.
+var builtinSecretScanner = &secretScanner{patterns: builtinSecretPatterns}
+
+// secretRedactionConfig enables redaction of secrets in tool results.
+type secretRedactionConfig struct {
+	Enabled bool `json:"enabled"`
+	// Patterns are additional secret formats. Setting them enables redaction.
+	Patterns []secretPatternConfig `json:"patterns"`
+}
+
+type secretPatternConfig struct {
+	Name string `json:"name"`
+	// Regex uses Go RE2 syntax.
+	Regex string `json:"regex"`
+}
+
+func (c secretRedactionConfig) enabled() bool {
+	return c.Enabled || len(c.Patterns) > 0
+}
+
+func (c secretRedactionConfig) validate() error {
+	_, err := c.compile()
+	return err
+}
+
+func (c secretRedactionConfig) compile() ([]secretPattern, error) {
+	patterns := append([]secretPattern(nil), builtinSecretPatterns...)
+	for i, p := range c.Patterns {
+		field := fmt.Sprintf("redactSecrets.patterns[%d]", i)
+		if p.Name == "" {
+			return nil, fmt.Errorf("%s.name: must not be empty", field)
+		}
+		re, err := regexp.Compile(p.Regex)
+		if err != nil {
+			return nil, fmt.Errorf("%s.regex: %w", field, err)
+		}
+		if re.MatchString("") {
+			return nil, fmt.Errorf("%s.regex: %q matches the empty string", field, p.Regex)
+		}
+		patterns = append(patterns, secretPattern{name: p.Name, re: re})
+	}
+
+	return patterns, nil
+}
+
+// secretScanner replaces secrets with a marker naming their format.
+type secretScanner struct {
+	patterns []secretPattern
+}
+
+// redactString redacts text, adding the number of secrets of each format to
+// counts when it is not nil.
+func (s *secretScanner) redactString(text string, counts map[string]int) string {
+	for _, p := range s.patterns {
+		text = p.re.ReplaceAllStringFunc(text, func(string) string {
+			if counts != nil {
+				counts[p.name]++
+			}
+			return "[REDACTED:" + p.name + "]"
+		})
+	}
+
+	return text
+}
+
+// redactJSON redacts every string in a JSON value. It returns data itself
+// when nothing was found.
+func (s *secretScanner) redactJSON(data json.RawMessage) (json.RawMessage, map[string]int, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var value any
+	if err := dec.Decode(&value); err != nil {
+		return nil, nil, fmt.Errorf("failed to decode JSON for secret scanning: %w", err)
+	}
+
+	counts := make(map[string]int)
+	value = s.redactValue(value, counts)
+	if len(counts) == 0 {
+		return data, nil, nil
+	}
+
+	redacted, err := json.Marshal(value)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to encode redacted JSON: %w", err)
+	}
+
+	return redacted, counts, nil
+}
+
+func (s *secretScanner) redactValue(value any, counts map[string]int) any {
+	switch v := value.(type) {
+	case map[string]any:
+		for key, item := range v {
+			v[key] = s.redactValue(item, counts)
+		}
+		return v
+	case []any:
+		for i, item := range v {
+			v[i] = s.redactValue(item, counts)
+		}
+		return v
+	case string:
+		return s.redactString(v, counts)
+	default:
+		return v
+	}
+}
+
+// secretRedactor removes secrets from every message sent to the client, and
+// so the model, whether it comes from github-mcp-server, an additional server
+// or gh-mcp itself.
+type secretRedactor struct {
+	passthroughMiddleware
+
+	scanner *secretScanner
+}
+
+// newSecretRedactor returns nil when redaction is disabled.
+func newSecretRedactor(cfg secretRedactionConfig) *secretRedactor {
+	if !cfg.enabled() {
+		return nil
+	}
+
+	// Validated when the config was loaded.
+	patterns, _ := cfg.compile()
+
+	return &secretRedactor{scanner: &secretScanner{patterns: patterns}}
+}
+
+func (r *secretRedactor) serverToClient(p *stdioProxy, next proxyHandler) proxyHandler {
+	return func(ctx context.Context, msg *rpcMessage) error {
+		method := msg.Method
+		if msg.call != nil {
+			method = msg.call.request.Method
+		}
+
+		counts, err := r.redact(msg)
+		if err != nil {
+			// Fail closed rather than pass on a message that was not scanned.
+			slog.WarnContext(ctx, "Failed to scan message for secrets", "method", method, "err", err)
+			if msg.isResponse() && msg.call != nil {
+				return p.replyError(ctx, msg.call.request, rpcCodeInternalError, "gh-mcp could not scan the response", nil)
+			}
+			return nil
+		}
+		if len(counts) == 0 {
+			return next(ctx, msg)
+		}
+
+		attrs := []any{"method", method, "redactions", counts}
+		if msg.call != nil {
+			if tool, ok := msg.call.request.toolCall(); ok {
+				attrs = append(attrs, "tool", tool.Name)
+			}
+		}
+		slog.WarnContext(ctx, "Redacted secrets from message to the client", attrs...)
+
+		return next(ctx, msg)
+	}
+}
+
+// redact scans the params, result and error of msg, replacing the parts that
+// held secrets.
+func (r *secretRedactor) redact(msg *rpcMessage) (map[string]int, error) {
+	fields := []*json.RawMessage{&msg.Params, &msg.Result}
+	if msg.Error != nil {
+		fields = append(fields, &msg.Error.Data)
+	}
+
+	counts := make(map[string]int)
+	for _, field := range fields {
+		if len(*field) == 0 {
+			continue
+		}
+		redacted, found, err := r.scanner.redactJSON(*field)
+		if err != nil {
+			return nil, err
+		}
+		for name, n := range found {
+			counts[name] += n
+		}
+		if found != nil {
+			*field = redacted
+			msg.modified = true
+		}
+	}
+
+	if msg.Error != nil {
+		if message := r.scanner.redactString(msg.Error.Message, counts); message != msg.Error.Message {
+			msg.Error.Message = message
+			msg.modified = true
+		}
+	}
+
+	return counts, nil
+}
@@ -4,19 +4,19 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"io"
 	"log/slog"
 	"os"
 	"os/exec"
 	"path/filepath"
 	"runtime"
 	"strings"
+	"syscall"
 	"time"
 )
 
-const (
-	// Wait this long after SIGINT before force-killing the bundled server process.
-	serverGracefulShutdownTimeout = 3 * time.Second
-)
+// serverOutputDrainTimeout bounds how long output is read after the server exits.
+const serverOutputDrainTimeout = 2 * time.Second
 
 var allowedParentEnvKeys = []string{
 	// Basic runtime environment.
@@ -44,8 +44,87 @@ var allowedParentEnvKeys = []string{
 	"SSL_CERT_DIR",
 }
 
-func runBundledServer(ctx context.Context, env []string, streams *ioStreams) error {
-	binaryPath, cleanup, err := materializeBundledServerBinary()
+func runBundledServer(ctx context.Context, env []string, streams *ioStreams, telemetry *sessionTelemetry) error {
+	policy, err := shutdownPolicyFromEnv()
+	if err != nil {
+		return err
+	}
+	signalConfig, err := signalRelayConfigFromEnv()
+	if err != nil {
+		return err
+	}
+	policy.processGroup = signalConfig.processGroup
+	limits, err := resourceLimitsFromEnv()
+	if err != nil {
+		return err
+	}
+	sandboxEnabled, err := sandboxEnabledFromEnv()
+	if err != nil {
+		return err
+	}
+	maxMessageSize, err := proxyMaxMessageSizeFromEnv()
+	if err != nil {
+		return err
+	}
+	proxyCfg, err := loadProxyConfig()
+	if err != nil {
+		return err
+	}
+	recordPath, replayPath, err := sessionRecordingFromEnv()
+	if err != nil {
+		return err
+	}
+	var recording *replayRecording
+	if replayPath != "" {
+		if recording, err = loadReplayRecording(replayPath); err != nil {
+			return err
+		}
+	}
+	if recording != nil {
+		proxyCfg = replayProxyConfig(proxyCfg)
+	}
+	if sandboxEnabled && !sandboxSupported {
+		slog.WarnContext(ctx, "Sandbox is only supported on Linux; ignoring", "env", sandboxEnv)
+		sandboxEnabled = false
+	}
+
+	session, err := newProxySession(env)
+	if err != nil {
+		return err
+	}
+	middlewares, closeMiddlewares, err := proxyMiddlewaresFromConfig(proxyCfg, session, maxMessageSize)
+	if err != nil {
+		return err
+	}
+	defer closeMiddlewares()
+	if telemetry.metrics != nil || telemetry.spans != nil {
+		// First, so it reports the answers the client receives.
+		middlewares = append([]proxyMiddleware{&toolCallTelemetry{telemetry: telemetry}}, middlewares...)
+	}
+
+	var tracer *protocolTracer
+	if dest := os.Getenv(traceEnv); dest != "" {
+		tracer, err = newProtocolTracer(dest, serverEnvValue(env, "GITHUB_PERSONAL_ACCESS_TOKEN"))
+		if err != nil {
+			return err
+		}
+		defer tracer.close()
+	}
+
+	if recording != nil {
+		return runReplaySession(ctx, recording, streams, maxMessageSize, middlewares, tracer, telemetry.sessionMetrics())
+	}
+	if recordPath != "" {
+		recorder, err := newSessionRecorder(recordPath)
+		if err != nil {
+			return err
+		}
+		defer recorder.close()
+		// Last, so it sees the messages the server exchanges.
+		middlewares = append(middlewares, recorder)
+	}
+
+	binaryPath, cleanup, err := materializeBundledServerBinary(telemetry)
 	if err != nil {
 		return err
 	}
@@ -55,65 +134,207 @@ func runBundledServer(ctx context.Context, env []string, streams *ioStreams) err
 		return nil
 	}
 
+	var sandbox *sandboxPolicy
+	if sandboxEnabled {
+		sandbox = buildSandboxPolicy(binaryPath, env)
+	}
+
+	relay := startSignalRelay(signalConfig)
+	defer relay.stop()
+
+	// Either trigger ends the session through the regular shutdown sequence.
+	ctx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+
+	stdin := newStdinForwarder(func() {
+		cancel(errClientStdinClosed)
+	})
+	proxy := newStdioProxy(stdin, streams.out, maxMessageSize, middlewares...)
+	proxy.observe(tracer, telemetry.sessionMetrics())
+	proxy.endSessionWith(cancel)
+	go proxy.serveClient(ctx, streams.in)
+
+	stopParentWatch := watchParentProcess(ctx, func() {
+		cancel(errParentProcessExited)
+	})
+	defer stopParentWatch()
+
+	for {
+		err := runServerProcess(ctx, binaryPath, env, streams, proxy, policy, relay, limits, sandbox, telemetry)
+		if ended := proxy.endError(); ended != nil {
+			return ended
+		}
+		if !errors.Is(err, errServerRestartRequested) {
+			return err
+		}
+		telemetry.serverRestarted()
+		slog.InfoContext(ctx, "🔄 Restarting bundled github-mcp-server")
+	}
+}
+
+// runServerProcess starts one server process and waits until it exits or is stopped.
+func runServerProcess(
+	ctx context.Context,
+	binaryPath string,
+	env []string,
+	streams *ioStreams,
+	proxy *stdioProxy,
+	policy shutdownPolicy,
+	relay *signalRelay,
+	limits resourceLimits,
+	sandbox *sandboxPolicy,
+	telemetry *sessionTelemetry,
+) error {
+	guard := newResourceGuard(limits)
+	defer guard.close()
+
 	// Keep lifecycle ownership in waitForServerExit for graceful interrupt handling.
 	cmd := exec.CommandContext(context.Background(), binaryPath, "stdio")
-	cmd.Stdin = streams.in
-	cmd.Stdout = streams.out
 	cmd.Stderr = streams.err
 	cmd.Env = buildChildProcessEnv(env)
+	configureServerProcessGroup(cmd, relay.config)
+	configureServerParentDeathSignal(cmd)
+	if err := guard.prepare(cmd); err != nil {
+		return err
+	}
+	launch := launcherOptions{limits: launcherResourceLimits(limits), sandbox: sandbox}
+	if err := wrapServerCommand(cmd, launch); err != nil {
+		return err
+	}
+
+	// Own the child's stdin so shutdown can close it before resorting to signals.
+	serverStdin, err := cmd.StdinPipe()
+	if err != nil {
+		return fmt.Errorf("failed to create stdin pipe for github-mcp-server: %w", err)
+	}
+	// A plain pipe rather than StdoutPipe: the proxy keeps reading after Wait
+	// returns, until the last buffered message is forwarded.
+	serverStdout, serverStdoutWriter, err := os.Pipe()
+	if err != nil {
+		return fmt.Errorf("failed to create stdout pipe for github-mcp-server: %w", err)
+	}
+	defer func() { _ = serverStdout.Close() }()
+	cmd.Stdout = serverStdoutWriter
 
 	slog.InfoContext(ctx, "🚀 Starting bundled github-mcp-server", "version", mcpServerVersion)
 
-	if err := cmd.Start(); err != nil {
+	endSpawn := telemetry.phase(phaseSpawn)
+	err = cmd.Start()
+	endSpawn(err)
+	_ = serverStdoutWriter.Close()
+	if err != nil {
 		return fmt.Errorf("failed to start bundled github-mcp-server: %w", err)
 	}
-
-	if err := waitForServerExit(ctx, cmd); err != nil {
+	if err := guard.attach(cmd); err != nil {
+		_ = cmd.Process.Kill()
+		_ = cmd.Wait()
 		return err
 	}
 
-	return nil
+	outputDone := make(chan struct{})
+	go func() {
+		defer close(outputDone)
+		proxy.serveServer(ctx, serverStdout)
+	}()
+	defer func() {
+		drainServerOutput(serverStdout, outputDone)
+		proxy.serverStopped(ctx)
+	}()
+
+	if err := proxy.replaySession(serverStdin); err != nil {
+		slog.WarnContext(ctx, "Failed to restore MCP session on restarted server", "err", err)
+	}
+
+	stdin := proxy.stdin
+	stdin.attach(serverStdin)
+	defer stdin.detach(serverStdin)
+
+	// Detach before closing so client input arriving during shutdown waits for
+	// a restarted server instead of hitting a closed pipe.
+	closeServerStdin := closerFunc(func() error {
+		stdin.detach(serverStdin)
+		return serverStdin.Close()
+	})
+
+	err = waitForServerExit(ctx, cmd, closeServerStdin, policy, relay)
+	return guard.classify(err, cmd.ProcessState)
+}
+
+// drainServerOutput waits for the proxy to forward the remaining server output.
+// A descendant still holding the pipe open cannot stall shutdown past the timeout.
+func drainServerOutput(stdout io.Closer, done <-chan struct{}) {
+	select {
+	case <-done:
+	case <-time.After(serverOutputDrainTimeout):
+		_ = stdout.Close()
+		<-done
+	}
 }
 
-func waitForServerExit(ctx context.Context, cmd *exec.Cmd) error {
+func waitForServerExit(
+	ctx context.Context,
+	cmd *exec.Cmd,
+	stdin io.Closer,
+	policy shutdownPolicy,
+	relay *signalRelay,
+) error {
 	waitCh := make(chan error, 1)
 	go func() {
 		waitCh <- cmd.Wait()
 	}()
 
-	select {
-	case waitErr := <-waitCh:
-		// Prefer clean shutdown semantics if the caller has already canceled.
+	for {
 		select {
+		case waitErr := <-waitCh:
+			// Prefer clean shutdown semantics if the caller has already canceled.
+			select {
+			case <-ctx.Done():
+				return nil
+			default:
+				return normalizeServerExit(waitErr)
+			}
 		case <-ctx.Done():
+			slog.InfoContext(ctx, "Stopping github-mcp-server", "reason", context.Cause(ctx))
+			// The client is gone, and whether the server exited on its own just
+			// before is a race, so how it stops is only logged.
+			if err := stopServer(ctx, cmd, stdin, waitCh, policy); err != nil {
+				slog.WarnContext(ctx, "github-mcp-server failed while stopping", "err", err)
+			}
 			return nil
-		default:
-			return normalizeServerExit(waitErr)
+		case sig := <-relay.channel():
+			switch relay.action(sig) {
+			case signalActionForward:
+				slog.InfoContext(ctx, "Forwarding signal to github-mcp-server", "signal", sig)
+				if err := forwardSignalToServer(cmd, sig, relay.config); err != nil {
+					slog.WarnContext(ctx, "Failed to forward signal", "signal", sig, "err", err)
+				}
+			case signalActionRestart:
+				slog.InfoContext(ctx, "Restart requested", "signal", sig)
+				if err := stopServer(ctx, cmd, stdin, waitCh, policy); err != nil {
+					slog.WarnContext(ctx, "github-mcp-server failed while stopping for restart", "err", err)
+				}
+				return errServerRestartRequested
+			case signalActionShutdown, signalActionNone:
+				slog.InfoContext(ctx, "Stopping github-mcp-server", "signal", sig)
+				if err := stopServer(ctx, cmd, stdin, waitCh, policy); err != nil {
+					// The crash decides the exit code, not the interrupt.
+					return errors.Join(err, &interruptError{signal: sig})
+				}
+				return &interruptError{signal: sig}
+			}
 		}
-	case <-ctx.Done():
-		stopServerProcess(cmd, waitCh)
-		return nil
 	}
 }
 
-func stopServerProcess(cmd *exec.Cmd, waitCh <-chan error) {
-	proc := cmd.Process
-	if proc == nil {
-		return
-	}
-
-	// Prefer graceful interrupt on Unix, then force-kill after timeout.
-	if runtime.GOOS != "windows" {
-		_ = proc.Signal(os.Interrupt)
-		select {
-		case <-waitCh:
-			return
-		case <-time.After(serverGracefulShutdownTimeout):
-		}
+// stopServer stops a server gh-mcp decided to stop, and returns an error
+// wrapping errServerFailedWhileStopping when it crashed instead of exiting
+// cleanly.
+func stopServer(ctx context.Context, cmd *exec.Cmd, stdin io.Closer, waitCh <-chan error, policy shutdownPolicy) error {
+	if err := stopServerProcess(ctx, cmd, stdin, waitCh, policy); err != nil {
+		return fmt.Errorf("%w: %w", errServerFailedWhileStopping, err)
 	}
 
-	_ = proc.Kill()
-	<-waitCh
+	return nil
 }
 
 func normalizeServerExit(err error) error {
@@ -123,13 +344,17 @@ func normalizeServerExit(err error) error {
 
 	var exitErr *exec.ExitError
 	if errors.As(err, &exitErr) {
-		return fmt.Errorf("%w: %d", errServerNonZeroExit, exitErr.ExitCode())
+		if status, ok := exitErr.Sys().(syscall.WaitStatus); ok && status.Signaled() {
+			return &serverExitError{signal: status.Signal()}
+		}
+
+		return &serverExitError{code: exitErr.ExitCode()}
 	}
 
 	return fmt.Errorf("failed waiting for github-mcp-server process: %w", err)
 }
 
-func materializeBundledServerBinary() (string, func(), error) {
+func materializeBundledServerBinary(telemetry *sessionTelemetry) (string, func(), error) {
 	if bundledMCPArchiveName == "" || bundledMCPExecutableName == "" ||
 		len(bundledMCPArchive) == 0 {
 		return "", func() {}, fmt.Errorf(
@@ -140,10 +365,26 @@ func materializeBundledServerBinary() (string, func(), error) {
 		)
 	}
 
-	if err := verifyBundledArchiveChecksum(bundledMCPArchive, bundledMCPArchiveSHA256); err != nil {
+	endChecksum := telemetry.phase(phaseChecksum)
+	err := verifyBundledArchiveChecksum(bundledMCPArchive, bundledMCPArchiveSHA256)
+	endChecksum(err)
+	if err != nil {
 		return "", func() {}, err
 	}
 
+	endExtraction := telemetry.phase(phaseExtraction)
+	binaryPath, cleanup, err := extractBundledServerBinary()
+	endExtraction(err)
+	if err != nil {
+		return "", func() {}, err
+	}
+
+	return binaryPath, cleanup, nil
+}
+
+// extractBundledServerBinary writes the bundled executable to a new private
+// temporary directory.
+func extractBundledServerBinary() (string, func(), error) {
 	tmpDir, cleanup, err := createTempDirWithFallback(bundledServerTempParentDirs())
 	if err != nil {
 		return "", func() {}, err
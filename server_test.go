@@ -3,6 +3,7 @@ package main
 import (
 	"archive/tar"
 	"archive/zip"
+	"bufio"
 	"bytes"
 	"compress/gzip"
 	"context"
@@ -11,11 +12,14 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"log/slog"
 	"os"
 	"os/exec"
+	"os/signal"
 	"path/filepath"
 	"runtime"
 	"strings"
+	"syscall"
 	"testing"
 	"time"
 )
@@ -388,7 +392,7 @@ func TestWaitForServerExit(t *testing.T) {
 			t.Fatalf("failed to start helper process: %v", err)
 		}
 
-		if err := waitForServerExit(context.Background(), cmd); err != nil {
+		if err := waitForServerExit(context.Background(), cmd, nil, defaultShutdownPolicy(), nil); err != nil {
 			t.Fatalf("waitForServerExit returned error: %v", err)
 		}
 	})
@@ -399,7 +403,7 @@ func TestWaitForServerExit(t *testing.T) {
 			t.Fatalf("failed to start helper process: %v", err)
 		}
 
-		err := waitForServerExit(context.Background(), cmd)
+		err := waitForServerExit(context.Background(), cmd, nil, defaultShutdownPolicy(), nil)
 		if err == nil {
 			t.Fatal("expected waitForServerExit to return non-zero exit error")
 		}
@@ -420,7 +424,7 @@ func TestWaitForServerExit(t *testing.T) {
 		}
 
 		cancel()
-		if err := waitForServerExit(ctx, cmd); err != nil {
+		if err := waitForServerExit(ctx, cmd, nil, defaultShutdownPolicy(), nil); err != nil {
 			t.Fatalf("expected nil error on canceled context, got: %v", err)
 		}
 	})
@@ -438,15 +442,69 @@ func TestWaitForServerExitCanceledContextSuppressesExitError(t *testing.T) {
 		cancel()
 		time.Sleep(20 * time.Millisecond)
 
-		if err := waitForServerExit(ctx, cmd); err != nil {
+		if err := waitForServerExit(ctx, cmd, nil, defaultShutdownPolicy(), nil); err != nil {
 			t.Fatalf("expected nil error at iteration %d, got: %v", i, err)
 		}
 	}
 }
 
+func TestWaitForServerExitReportsCrashWhileStopping(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("graceful interrupt is not delivered on Windows")
+	}
+
+	cmd := newServerTestHelperCommand(t, "terminate-exit-3")
+	if err := cmd.Start(); err != nil {
+		t.Fatalf("failed to start helper process: %v", err)
+	}
+
+	// Give the helper time to install its signal handler.
+	time.Sleep(200 * time.Millisecond)
+	relay := &signalRelay{signals: make(chan os.Signal, 1)}
+	relay.signals <- os.Interrupt
+
+	err := waitForServerExit(context.Background(), cmd, nil, defaultShutdownPolicy(), relay)
+	if !errors.Is(err, errServerFailedWhileStopping) {
+		t.Fatalf("expected errServerFailedWhileStopping, got: %v", err)
+	}
+	var interrupted *interruptError
+	if !errors.As(err, &interrupted) {
+		t.Fatalf("expected interruptError, got: %v", err)
+	}
+	if got, want := exitCodeForError(err), exitCodeServerExitBase+3; got != want {
+		t.Fatalf("exit code = %d, want %d", got, want)
+	}
+}
+
+func TestWaitForServerExitStopsCleanlyAfterCancel(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("graceful interrupt is not delivered on Windows")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cmd := newServerTestHelperCommand(t, "terminate-exit-3")
+	if err := cmd.Start(); err != nil {
+		cancel()
+		t.Fatalf("failed to start helper process: %v", err)
+	}
+
+	time.Sleep(200 * time.Millisecond)
+	cancel()
+
+	if err := waitForServerExit(ctx, cmd, nil, defaultShutdownPolicy(), nil); err != nil {
+		t.Fatalf("expected nil error after cancellation, got: %v", err)
+	}
+}
+
 func TestStopServerProcess(t *testing.T) {
 	t.Run("nil process", func(_ *testing.T) {
-		stopServerProcess(exec.Command("definitely-not-started"), make(chan error))
+		_ = stopServerProcess(
+			context.Background(),
+			exec.Command("definitely-not-started"),
+			nil,
+			make(chan error),
+			defaultShutdownPolicy(),
+		)
 	})
 
 	t.Run("running process", func(t *testing.T) {
@@ -460,7 +518,15 @@ func TestStopServerProcess(t *testing.T) {
 			waitCh <- cmd.Wait()
 		}()
 
-		stopServerProcess(cmd, waitCh)
+		if err := stopServerProcess(
+			context.Background(),
+			cmd,
+			nil,
+			waitCh,
+			defaultShutdownPolicy(),
+		); err != nil {
+			t.Fatalf("expected interrupted process to stop cleanly, got: %v", err)
+		}
 
 		if cmd.ProcessState == nil {
 			t.Fatal("expected process state after stopServerProcess")
@@ -468,6 +534,199 @@ func TestStopServerProcess(t *testing.T) {
 	})
 }
 
+func TestShutdownServerProcessStages(t *testing.T) {
+	tests := []struct {
+		name      string
+		mode      string
+		policy    shutdownPolicy
+		wantStage shutdownStage
+		unixOnly  bool
+	}{
+		{
+			name: "server exits when stdin closes",
+			mode: "exit-on-stdin-eof",
+			policy: shutdownPolicy{
+				stdinTimeout:     10 * time.Second,
+				terminateTimeout: 10 * time.Second,
+			},
+			wantStage: shutdownStageStdinClose,
+		},
+		{
+			name: "server ignores stdin close but honors SIGTERM",
+			mode: "sleep",
+			policy: shutdownPolicy{
+				stdinTimeout:     100 * time.Millisecond,
+				terminateTimeout: 10 * time.Second,
+			},
+			wantStage: shutdownStageTerminate,
+			unixOnly:  true,
+		},
+		{
+			name: "server ignores SIGTERM but honors SIGINT",
+			mode: "ignore-sigterm",
+			policy: shutdownPolicy{
+				stdinTimeout:     100 * time.Millisecond,
+				terminateTimeout: 200 * time.Millisecond,
+				interruptTimeout: 10 * time.Second,
+			},
+			wantStage: shutdownStageInterrupt,
+			unixOnly:  true,
+		},
+		{
+			name: "server ignores SIGTERM and SIGINT and is killed",
+			mode: "ignore-shutdown-signals",
+			policy: shutdownPolicy{
+				stdinTimeout:     100 * time.Millisecond,
+				terminateTimeout: 200 * time.Millisecond,
+				interruptTimeout: 200 * time.Millisecond,
+			},
+			wantStage: shutdownStageKill,
+			unixOnly:  true,
+		},
+		{
+			name:      "zero timeouts skip straight to kill",
+			mode:      "exit-on-stdin-eof",
+			policy:    shutdownPolicy{},
+			wantStage: shutdownStageKill,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.unixOnly && runtime.GOOS == "windows" {
+				t.Skip("SIGTERM is not available on Windows")
+			}
+
+			cmd := newServerTestHelperCommand(t, tt.mode)
+			stdin, err := cmd.StdinPipe()
+			if err != nil {
+				t.Fatalf("failed to create stdin pipe: %v", err)
+			}
+			stdout, err := cmd.StdoutPipe()
+			if err != nil {
+				t.Fatalf("failed to create stdout pipe: %v", err)
+			}
+			if err := cmd.Start(); err != nil {
+				t.Fatalf("failed to start helper process: %v", err)
+			}
+			waitForHelperReady(t, stdout, tt.mode)
+
+			waitCh := make(chan error, 1)
+			go func() {
+				waitCh <- cmd.Wait()
+			}()
+
+			stage, err := shutdownServerProcess(t.Context(), cmd, stdin, waitCh, tt.policy)
+			if err != nil {
+				t.Fatalf("shutdownServerProcess returned error: %v", err)
+			}
+			if stage != tt.wantStage {
+				t.Fatalf("shutdown stage = %q, want %q", stage, tt.wantStage)
+			}
+		})
+	}
+}
+
+func TestShutdownServerProcessLogsStagesInOrder(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("SIGTERM and SIGINT are not available on Windows")
+	}
+
+	var logs lockedBuffer
+	previous := slog.Default()
+	slog.SetDefault(slog.New(slog.NewTextHandler(&logs, nil)))
+	t.Cleanup(func() { slog.SetDefault(previous) })
+
+	cmd := newServerTestHelperCommand(t, "ignore-shutdown-signals")
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		t.Fatalf("failed to create stdin pipe: %v", err)
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		t.Fatalf("failed to create stdout pipe: %v", err)
+	}
+	if err := cmd.Start(); err != nil {
+		t.Fatalf("failed to start helper process: %v", err)
+	}
+	waitForHelperReady(t, stdout, "ignore-shutdown-signals")
+
+	waitCh := make(chan error, 1)
+	go func() {
+		waitCh <- cmd.Wait()
+	}()
+
+	policy := shutdownPolicy{
+		stdinTimeout:     100 * time.Millisecond,
+		terminateTimeout: 100 * time.Millisecond,
+		interruptTimeout: 100 * time.Millisecond,
+	}
+	stage, err := shutdownServerProcess(t.Context(), cmd, stdin, waitCh, policy)
+	if err != nil {
+		t.Fatalf("shutdownServerProcess returned error: %v", err)
+	}
+	if stage != shutdownStageKill {
+		t.Fatalf("shutdown stage = %q, want %q", stage, shutdownStageKill)
+	}
+
+	output := logs.String()
+	offset := 0
+	for _, want := range []string{
+		"Closing github-mcp-server stdin",
+		"Sending SIGTERM to github-mcp-server",
+		"Sending SIGINT to github-mcp-server",
+		"Force-killing github-mcp-server",
+	} {
+		index := strings.Index(output[offset:], want)
+		if index < 0 {
+			t.Fatalf("expected %q to be logged after the previous stage, got:\n%s", want, output)
+		}
+		offset += index + len(want)
+	}
+}
+
+func TestShutdownPolicyFromEnv(t *testing.T) {
+	t.Run("defaults", func(t *testing.T) {
+		t.Setenv(shutdownStdinTimeoutEnv, "")
+		t.Setenv(shutdownTerminateTimeoutEnv, "")
+		t.Setenv(shutdownInterruptTimeoutEnv, "")
+
+		policy, err := shutdownPolicyFromEnv()
+		if err != nil {
+			t.Fatalf("shutdownPolicyFromEnv returned error: %v", err)
+		}
+		if policy != defaultShutdownPolicy() {
+			t.Fatalf("expected default policy, got %+v", policy)
+		}
+	})
+
+	t.Run("overrides", func(t *testing.T) {
+		t.Setenv(shutdownStdinTimeoutEnv, "500ms")
+		t.Setenv(shutdownTerminateTimeoutEnv, "0")
+		t.Setenv(shutdownInterruptTimeoutEnv, "1s")
+
+		policy, err := shutdownPolicyFromEnv()
+		if err != nil {
+			t.Fatalf("shutdownPolicyFromEnv returned error: %v", err)
+		}
+		want := shutdownPolicy{stdinTimeout: 500 * time.Millisecond, interruptTimeout: time.Second}
+		if policy != want {
+			t.Fatalf("policy = %+v, want %+v", policy, want)
+		}
+	})
+
+	for _, value := range []string{"soon", "-1s"} {
+		t.Run("invalid "+value, func(t *testing.T) {
+			t.Setenv(shutdownTerminateTimeoutEnv, value)
+
+			_, err := shutdownPolicyFromEnv()
+			if !errors.Is(err, errInvalidSetting) {
+				t.Fatalf("expected errInvalidSetting, got: %v", err)
+			}
+		})
+	}
+}
+
 func TestNormalizeServerExit(t *testing.T) {
 	if err := normalizeServerExit(nil); err != nil {
 		t.Fatalf("expected nil error for nil input, got: %v", err)
@@ -580,7 +839,9 @@ func newServerTestHelperCommand(t *testing.T, mode string) *exec.Cmd {
 
 	validMode := ""
 	switch mode {
-	case "exit-0", "exit-7", "exit-9", "sleep", "sleep-then-exit-5":
+	case "exit-0", "exit-7", "exit-9", "sleep", "sleep-then-exit-5", "terminate-exit-3",
+		"exit-on-stdin-eof", "ignore-sigterm", "ignore-shutdown-signals", "wait-sigusr1",
+		"launch-open-files", "launch-cpu-spin", "launch-sandbox":
 		validMode = mode
 	default:
 		t.Fatalf("unsupported helper mode: %q", mode)
@@ -593,6 +854,22 @@ func newServerTestHelperCommand(t *testing.T, mode string) *exec.Cmd {
 	return cmd
 }
 
+// waitForHelperReady blocks until a helper that announces readiness has set itself up.
+func waitForHelperReady(t *testing.T, stdout io.Reader, mode string) {
+	t.Helper()
+
+	switch mode {
+	case "exit-on-stdin-eof", "ignore-sigterm", "ignore-shutdown-signals", "wait-sigusr1":
+	default:
+		return
+	}
+
+	line, err := bufio.NewReader(stdout).ReadString('\n')
+	if err != nil || strings.TrimSpace(line) != "ready" {
+		t.Fatalf("helper did not report readiness: line=%q err=%v", line, err)
+	}
+}
+
 func TestServerProcessHelper(*testing.T) {
 	if os.Getenv("GO_WANT_SERVER_PROCESS_HELPER") != "1" {
 		return
@@ -619,6 +896,37 @@ func TestServerProcessHelper(*testing.T) {
 	case "sleep-then-exit-5":
 		time.Sleep(10 * time.Millisecond)
 		os.Exit(5)
+	case "terminate-exit-3":
+		sigCh := make(chan os.Signal, 1)
+		signal.Notify(sigCh, os.Interrupt, syscall.SIGTERM)
+		select {
+		case <-sigCh:
+			os.Exit(3)
+		case <-time.After(30 * time.Second):
+			os.Exit(0)
+		}
+	case "exit-on-stdin-eof":
+		fmt.Println("ready")
+		_, _ = io.Copy(io.Discard, os.Stdin)
+		os.Exit(0)
+	case "wait-sigusr1":
+		waitForHelperSignal()
+	case "launch-open-files", "launch-cpu-spin", "launch-sandbox", "sandbox-probe":
+		runHelperLauncher(mode)
+	case "spin":
+		for {
+			_ = time.Now()
+		}
+	case "ignore-sigterm":
+		signal.Ignore(syscall.SIGTERM)
+		fmt.Println("ready")
+		time.Sleep(30 * time.Second)
+		os.Exit(0)
+	case "ignore-shutdown-signals":
+		signal.Ignore(syscall.SIGTERM, syscall.SIGINT)
+		fmt.Println("ready")
+		time.Sleep(30 * time.Second)
+		os.Exit(0)
 	default:
 		os.Exit(2)
 	}
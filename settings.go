@@ -0,0 +1,66 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// errInvalidSetting is returned when a GH_MCP_* setting cannot be parsed.
+var errInvalidSetting = errors.New("invalid gh-mcp setting")
+
+// durationSettingFromEnv reads a non-negative Go duration (for example "1500ms")
+// from key, returning fallback when the variable is unset or empty.
+func durationSettingFromEnv(key string, fallback time.Duration) (time.Duration, error) {
+	value := os.Getenv(key)
+	if value == "" {
+		return fallback, nil
+	}
+
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, fmt.Errorf("%w: %s=%q is not a duration: %w", errInvalidSetting, key, value, err)
+	}
+	if d < 0 {
+		return 0, fmt.Errorf("%w: %s=%q must not be negative", errInvalidSetting, key, value)
+	}
+
+	return d, nil
+}
+
+// boolSettingFromEnv reads a boolean ("1", "true", "0", "false", ...) from key,
+// returning fallback when the variable is unset or empty.
+func boolSettingFromEnv(key string, fallback bool) (bool, error) {
+	value := os.Getenv(key)
+	if value == "" {
+		return fallback, nil
+	}
+
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		return false, fmt.Errorf("%w: %s=%q is not a boolean: %w", errInvalidSetting, key, value, err)
+	}
+
+	return b, nil
+}
+
+// listSettingFromEnv splits a comma-separated value from key into trimmed,
+// non-empty items. ok is false when the variable is unset or empty.
+func listSettingFromEnv(key string) ([]string, bool) {
+	value := os.Getenv(key)
+	if value == "" {
+		return nil, false
+	}
+
+	var items []string
+	for item := range strings.SplitSeq(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+
+	return items, true
+}
@@ -0,0 +1,179 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"os/exec"
+	"runtime"
+	"syscall"
+	"time"
+)
+
+const (
+	// Wait this long after closing the server's stdin before signaling it.
+	defaultShutdownStdinTimeout = 2 * time.Second
+	// Wait this long after SIGTERM before sending SIGINT.
+	defaultShutdownTerminateTimeout = 3 * time.Second
+	// Wait this long after SIGINT before force-killing the bundled server process.
+	defaultShutdownInterruptTimeout = 2 * time.Second
+
+	shutdownStdinTimeoutEnv     = "GH_MCP_SHUTDOWN_STDIN_TIMEOUT"
+	shutdownTerminateTimeoutEnv = "GH_MCP_SHUTDOWN_TERM_TIMEOUT"
+	shutdownInterruptTimeoutEnv = "GH_MCP_SHUTDOWN_INT_TIMEOUT"
+)
+
+// shutdownPolicy controls how long each stage of stopServerProcess waits for
+// the server to exit. A zero timeout skips the stage. With processGroup set,
+// signals go to the server's whole process group, as forwarded signals do.
+type shutdownPolicy struct {
+	stdinTimeout     time.Duration
+	terminateTimeout time.Duration
+	interruptTimeout time.Duration
+	processGroup     bool
+}
+
+// shutdownStage identifies the step of the shutdown sequence that ended the server.
+type shutdownStage string
+
+const (
+	shutdownStageNone       shutdownStage = "none"
+	shutdownStageStdinClose shutdownStage = "stdin-close"
+	shutdownStageTerminate  shutdownStage = "terminate"
+	shutdownStageInterrupt  shutdownStage = "interrupt"
+	shutdownStageKill       shutdownStage = "kill"
+)
+
+func defaultShutdownPolicy() shutdownPolicy {
+	return shutdownPolicy{
+		stdinTimeout:     defaultShutdownStdinTimeout,
+		terminateTimeout: defaultShutdownTerminateTimeout,
+		interruptTimeout: defaultShutdownInterruptTimeout,
+	}
+}
+
+func shutdownPolicyFromEnv() (shutdownPolicy, error) {
+	policy := defaultShutdownPolicy()
+
+	var err error
+	policy.stdinTimeout, err = durationSettingFromEnv(shutdownStdinTimeoutEnv, policy.stdinTimeout)
+	if err != nil {
+		return shutdownPolicy{}, err
+	}
+	policy.terminateTimeout, err = durationSettingFromEnv(
+		shutdownTerminateTimeoutEnv,
+		policy.terminateTimeout,
+	)
+	if err != nil {
+		return shutdownPolicy{}, err
+	}
+	policy.interruptTimeout, err = durationSettingFromEnv(
+		shutdownInterruptTimeoutEnv,
+		policy.interruptTimeout,
+	)
+	if err != nil {
+		return shutdownPolicy{}, err
+	}
+
+	return policy, nil
+}
+
+// stopServerProcess shuts the server down and reports a crash that happened while stopping.
+// Exiting because of a shutdown signal, or being force-killed, is not an error.
+func stopServerProcess(
+	ctx context.Context,
+	cmd *exec.Cmd,
+	stdin io.Closer,
+	waitCh <-chan error,
+	policy shutdownPolicy,
+) error {
+	_, err := shutdownServerProcess(ctx, cmd, stdin, waitCh, policy)
+	return err
+}
+
+// shutdownServerProcess runs the shutdown sequence: close stdin (the MCP way to
+// end a stdio server), then SIGTERM, then SIGINT, then kill. It returns the stage
+// that ended the process.
+func shutdownServerProcess(
+	ctx context.Context,
+	cmd *exec.Cmd,
+	stdin io.Closer,
+	waitCh <-chan error,
+	policy shutdownPolicy,
+) (shutdownStage, error) {
+	proc := cmd.Process
+	if proc == nil {
+		return shutdownStageNone, nil
+	}
+
+	if stdin != nil && policy.stdinTimeout > 0 {
+		slog.InfoContext(ctx, "Closing github-mcp-server stdin", "timeout", policy.stdinTimeout)
+		_ = stdin.Close()
+		if exited, waitErr := waitForExitWithin(waitCh, policy.stdinTimeout); exited {
+			return shutdownStageStdinClose, normalizeShutdownServerExit(waitErr)
+		}
+	}
+
+	// Windows has no SIGTERM or SIGINT equivalent for console processes.
+	if runtime.GOOS != "windows" {
+		signalStages := []struct {
+			message string
+			signal  syscall.Signal
+			timeout time.Duration
+			stage   shutdownStage
+		}{
+			{"Sending SIGTERM to github-mcp-server", syscall.SIGTERM, policy.terminateTimeout, shutdownStageTerminate},
+			{"Sending SIGINT to github-mcp-server", syscall.SIGINT, policy.interruptTimeout, shutdownStageInterrupt},
+		}
+		config := signalRelayConfig{processGroup: policy.processGroup}
+		for _, s := range signalStages {
+			if s.timeout <= 0 {
+				continue
+			}
+			slog.InfoContext(ctx, s.message, "timeout", s.timeout)
+			if err := forwardSignalToServer(cmd, s.signal, config); err != nil {
+				slog.WarnContext(ctx, "Failed to signal github-mcp-server", "signal", s.signal, "err", err)
+			}
+			if exited, waitErr := waitForExitWithin(waitCh, s.timeout); exited {
+				return s.stage, normalizeShutdownServerExit(waitErr)
+			}
+		}
+	}
+
+	slog.WarnContext(ctx, "Force-killing github-mcp-server")
+	_ = proc.Kill()
+	<-waitCh
+	return shutdownStageKill, nil
+}
+
+func waitForExitWithin(waitCh <-chan error, timeout time.Duration) (bool, error) {
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	select {
+	case waitErr := <-waitCh:
+		return true, waitErr
+	case <-timer.C:
+		return false, nil
+	}
+}
+
+// normalizeShutdownServerExit treats exits caused by a termination signal,
+// whether sent by gh-mcp or delivered to the whole process group, as clean.
+func normalizeShutdownServerExit(err error) error {
+	normalized := normalizeServerExit(err)
+
+	var serverExit *serverExitError
+	if !errors.As(normalized, &serverExit) {
+		return normalized
+	}
+
+	for _, sig := range []syscall.Signal{syscall.SIGINT, syscall.SIGTERM} {
+		if serverExit.signal == sig || serverExit.code == exitCodeForSignal(sig) {
+			return nil
+		}
+	}
+
+	return normalized
+}
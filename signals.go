@@ -0,0 +1,152 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"slices"
+	"strings"
+)
+
+const (
+	forwardSignalsEnv     = "GH_MCP_FORWARD_SIGNALS"
+	signalProcessGroupEnv = "GH_MCP_SIGNAL_PROCESS_GROUP"
+	defaultForwardSignals = "SIGQUIT,SIGUSR1,SIGUSR2"
+
+	// SIGHUP restarts the server unless it is listed in GH_MCP_FORWARD_SIGNALS.
+	restartSignalName      = "SIGHUP"
+	signalNamePrefix       = "SIG"
+	signalRelayChannelSize = 4
+)
+
+const (
+	signalActionNone signalAction = iota
+	signalActionForward
+	signalActionRestart
+	signalActionShutdown
+)
+
+var (
+	// errServerRestartRequested is returned by waitForServerExit after the server
+	// was stopped in response to a restart request (SIGHUP).
+	errServerRestartRequested = errors.New("server restart requested")
+	// errSignalNotForwardable is returned when a signal cannot be delivered to the server.
+	errSignalNotForwardable = errors.New("signal cannot be forwarded")
+)
+
+// signalAction is what the relay does with a received signal.
+type signalAction int
+
+// signalRelayConfig selects which signals are forwarded to the server.
+type signalRelayConfig struct {
+	forward      []os.Signal
+	processGroup bool
+}
+
+// signalRelay receives the non-shutdown signals gh-mcp handles while the server
+// runs. Every relayable signal is subscribed so none of them can terminate
+// gh-mcp abruptly and orphan the server.
+type signalRelay struct {
+	config  signalRelayConfig
+	signals chan os.Signal
+}
+
+func signalRelayConfigFromEnv() (signalRelayConfig, error) {
+	var config signalRelayConfig
+
+	var err error
+	config.processGroup, err = boolSettingFromEnv(signalProcessGroupEnv, false)
+	if err != nil {
+		return signalRelayConfig{}, err
+	}
+
+	names, ok := listSettingFromEnv(forwardSignalsEnv)
+	if !ok {
+		names = strings.Split(defaultForwardSignals, ",")
+	}
+
+	for _, name := range names {
+		sig, err := parseRelayableSignal(name)
+		if err != nil {
+			return signalRelayConfig{}, err
+		}
+		// Unsupported on this platform; nothing to forward.
+		if sig == nil {
+			continue
+		}
+		if !slices.Contains(config.forward, sig) {
+			config.forward = append(config.forward, sig)
+		}
+	}
+
+	return config, nil
+}
+
+// parseRelayableSignal resolves names like "usr1" or "SIGUSR1". It returns a
+// nil signal for valid names the current platform cannot deliver.
+func parseRelayableSignal(name string) (os.Signal, error) {
+	normalized := strings.ToUpper(strings.TrimSpace(name))
+	if !strings.HasPrefix(normalized, signalNamePrefix) {
+		normalized = signalNamePrefix + normalized
+	}
+
+	if !slices.Contains(relayableSignalNames, normalized) {
+		return nil, fmt.Errorf(
+			"%w: %s contains unsupported signal %q (allowed: %s)",
+			errInvalidSetting,
+			forwardSignalsEnv,
+			name,
+			strings.Join(relayableSignalNames, ", "),
+		)
+	}
+
+	return relayableSignals[normalized], nil
+}
+
+func startSignalRelay(config signalRelayConfig) *signalRelay {
+	relay := &signalRelay{
+		config:  config,
+		signals: make(chan os.Signal, signalRelayChannelSize),
+	}
+
+	var handled []os.Signal
+	for _, sig := range relayableSignals {
+		handled = append(handled, sig)
+	}
+	if len(handled) > 0 {
+		signal.Notify(relay.signals, handled...)
+	}
+
+	return relay
+}
+
+func (r *signalRelay) stop() {
+	if r == nil {
+		return
+	}
+
+	signal.Stop(r.signals)
+}
+
+// channel returns the received signals; a nil relay never delivers any.
+func (r *signalRelay) channel() <-chan os.Signal {
+	if r == nil {
+		return nil
+	}
+
+	return r.signals
+}
+
+func (r *signalRelay) action(sig os.Signal) signalAction {
+	switch {
+	case r == nil:
+		return signalActionNone
+	case slices.Contains(r.config.forward, sig):
+		return signalActionForward
+	case sig == relayableSignals[restartSignalName]:
+		return signalActionRestart
+	default:
+		return signalActionShutdown
+	}
+}
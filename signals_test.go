@@ -0,0 +1,96 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"runtime"
+	"testing"
+)
+
+func TestSignalRelayConfigFromEnv(t *testing.T) {
+	t.Run("defaults", func(t *testing.T) {
+		t.Setenv(forwardSignalsEnv, "")
+		t.Setenv(signalProcessGroupEnv, "")
+
+		config, err := signalRelayConfigFromEnv()
+		if err != nil {
+			t.Fatalf("signalRelayConfigFromEnv returned error: %v", err)
+		}
+		if config.processGroup {
+			t.Fatal("expected process group forwarding to be disabled by default")
+		}
+
+		wantForwarded := 3
+		if runtime.GOOS == "windows" {
+			wantForwarded = 0
+		}
+		if len(config.forward) != wantForwarded {
+			t.Fatalf("expected %d forwarded signals, got %v", wantForwarded, config.forward)
+		}
+	})
+
+	t.Run("custom list accepts short names", func(t *testing.T) {
+		t.Setenv(forwardSignalsEnv, "hup, usr1,SIGUSR1")
+		t.Setenv(signalProcessGroupEnv, "true")
+
+		config, err := signalRelayConfigFromEnv()
+		if err != nil {
+			t.Fatalf("signalRelayConfigFromEnv returned error: %v", err)
+		}
+		if !config.processGroup {
+			t.Fatal("expected process group forwarding to be enabled")
+		}
+		if runtime.GOOS != "windows" && len(config.forward) != 2 {
+			t.Fatalf("expected duplicate signals to collapse, got %v", config.forward)
+		}
+	})
+
+	t.Run("unsupported signal", func(t *testing.T) {
+		t.Setenv(forwardSignalsEnv, "SIGKILL")
+
+		_, err := signalRelayConfigFromEnv()
+		if !errors.Is(err, errInvalidSetting) {
+			t.Fatalf("expected errInvalidSetting, got: %v", err)
+		}
+	})
+
+	t.Run("invalid process group flag", func(t *testing.T) {
+		t.Setenv(signalProcessGroupEnv, "sometimes")
+
+		_, err := signalRelayConfigFromEnv()
+		if !errors.Is(err, errInvalidSetting) {
+			t.Fatalf("expected errInvalidSetting, got: %v", err)
+		}
+	})
+}
+
+func TestSignalRelayAction(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("relayable signals are not available on Windows")
+	}
+
+	hup := relayableSignals["SIGHUP"]
+	quit := relayableSignals["SIGQUIT"]
+	usr2 := relayableSignals["SIGUSR2"]
+
+	relay := &signalRelay{config: signalRelayConfig{forward: []os.Signal{quit}}}
+	if got := relay.action(quit); got != signalActionForward {
+		t.Fatalf("action(SIGQUIT) = %v, want forward", got)
+	}
+	if got := relay.action(hup); got != signalActionRestart {
+		t.Fatalf("action(SIGHUP) = %v, want restart", got)
+	}
+	if got := relay.action(usr2); got != signalActionShutdown {
+		t.Fatalf("action(SIGUSR2) = %v, want shutdown", got)
+	}
+
+	relay.config.forward = append(relay.config.forward, hup)
+	if got := relay.action(hup); got != signalActionForward {
+		t.Fatalf("action(SIGHUP) = %v, want forward when configured", got)
+	}
+
+	var nilRelay *signalRelay
+	if got := nilRelay.action(hup); got != signalActionNone {
+		t.Fatalf("nil relay action = %v, want none", got)
+	}
+}
@@ -0,0 +1,56 @@
+//go:build !windows
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"syscall"
+)
+
+// relayableSignalNames lists, in display order, the signals gh-mcp relays.
+var relayableSignalNames = []string{"SIGHUP", "SIGQUIT", "SIGUSR1", "SIGUSR2"}
+
+var relayableSignals = map[string]os.Signal{
+	"SIGHUP":  syscall.SIGHUP,
+	"SIGQUIT": syscall.SIGQUIT,
+	"SIGUSR1": syscall.SIGUSR1,
+	"SIGUSR2": syscall.SIGUSR2,
+}
+
+func configureServerProcessGroup(cmd *exec.Cmd, config signalRelayConfig) {
+	if !config.processGroup {
+		return
+	}
+
+	if cmd.SysProcAttr == nil {
+		cmd.SysProcAttr = &syscall.SysProcAttr{}
+	}
+	cmd.SysProcAttr.Setpgid = true
+}
+
+// forwardSignalToServer delivers sig to the server, or to its whole process
+// group when the server was started in its own group.
+func forwardSignalToServer(cmd *exec.Cmd, sig os.Signal, config signalRelayConfig) error {
+	if cmd.Process == nil {
+		return nil
+	}
+
+	if config.processGroup {
+		unixSig, ok := sig.(syscall.Signal)
+		if !ok {
+			return fmt.Errorf("%w: %v", errSignalNotForwardable, sig)
+		}
+		if err := syscall.Kill(-cmd.Process.Pid, unixSig); err != nil {
+			return fmt.Errorf("failed to forward %v to github-mcp-server process group: %w", sig, err)
+		}
+		return nil
+	}
+
+	if err := cmd.Process.Signal(sig); err != nil {
+		return fmt.Errorf("failed to forward %v to github-mcp-server: %w", sig, err)
+	}
+
+	return nil
+}
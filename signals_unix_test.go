@@ -0,0 +1,130 @@
+//go:build !windows
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"os/signal"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// waitForHelperSignal exits 0 once SIGUSR1 arrives, or 4 if it never does.
+func waitForHelperSignal() {
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, syscall.SIGUSR1)
+	fmt.Println("ready")
+
+	select {
+	case <-sigCh:
+		os.Exit(0)
+	case <-time.After(30 * time.Second):
+		os.Exit(4)
+	}
+}
+
+func TestWaitForServerExitForwardsSignal(t *testing.T) {
+	relay := startSignalRelay(signalRelayConfig{forward: []os.Signal{syscall.SIGUSR1}})
+	defer relay.stop()
+
+	cmd, stdin := startReadyHelper(t, "wait-sigusr1")
+
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- waitForServerExit(t.Context(), cmd, stdin, defaultShutdownPolicy(), relay)
+	}()
+
+	if err := syscall.Kill(os.Getpid(), syscall.SIGUSR1); err != nil {
+		t.Fatalf("failed to signal test process: %v", err)
+	}
+
+	select {
+	case err := <-errCh:
+		if err != nil {
+			t.Fatalf("expected helper to exit cleanly after receiving SIGUSR1, got: %v", err)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("timed out waiting for forwarded signal to stop helper")
+	}
+}
+
+func TestWaitForServerExitRestartsOnSIGHUP(t *testing.T) {
+	relay := startSignalRelay(signalRelayConfig{})
+	defer relay.stop()
+
+	cmd, stdin := startReadyHelper(t, "exit-on-stdin-eof")
+
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- waitForServerExit(t.Context(), cmd, stdin, defaultShutdownPolicy(), relay)
+	}()
+
+	if err := syscall.Kill(os.Getpid(), syscall.SIGHUP); err != nil {
+		t.Fatalf("failed to signal test process: %v", err)
+	}
+
+	select {
+	case err := <-errCh:
+		if !errors.Is(err, errServerRestartRequested) {
+			t.Fatalf("expected errServerRestartRequested, got: %v", err)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("timed out waiting for restart")
+	}
+	if cmd.ProcessState == nil {
+		t.Fatal("expected server to be stopped before restart")
+	}
+}
+
+func TestWaitForServerExitStopsOnUnforwardedSignal(t *testing.T) {
+	relay := startSignalRelay(signalRelayConfig{})
+	defer relay.stop()
+
+	cmd, stdin := startReadyHelper(t, "exit-on-stdin-eof")
+
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- waitForServerExit(t.Context(), cmd, stdin, defaultShutdownPolicy(), relay)
+	}()
+
+	if err := syscall.Kill(os.Getpid(), syscall.SIGUSR2); err != nil {
+		t.Fatalf("failed to signal test process: %v", err)
+	}
+
+	select {
+	case err := <-errCh:
+		if got := exitCodeForError(err); got != exitCodeSignalBase+int(syscall.SIGUSR2) {
+			t.Fatalf("expected interrupt exit code for SIGUSR2, got %d (err=%v)", got, err)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("timed out waiting for shutdown")
+	}
+}
+
+func startReadyHelper(t *testing.T, mode string) (*exec.Cmd, io.WriteCloser) {
+	t.Helper()
+
+	cmd := newServerTestHelperCommand(t, mode)
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		t.Fatalf("failed to create stdin pipe: %v", err)
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		t.Fatalf("failed to create stdout pipe: %v", err)
+	}
+	if err := cmd.Start(); err != nil {
+		t.Fatalf("failed to start helper process: %v", err)
+	}
+	t.Cleanup(func() {
+		_ = cmd.Process.Kill()
+	})
+	waitForHelperReady(t, stdout, mode)
+
+	return cmd, stdin
+}
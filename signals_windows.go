@@ -0,0 +1,20 @@
+//go:build windows
+
+package main
+
+import (
+	"os"
+	"os/exec"
+)
+
+// relayableSignalNames lists the signal names accepted in GH_MCP_FORWARD_SIGNALS.
+// Windows cannot deliver them, so they parse but are never relayed.
+var relayableSignalNames = []string{"SIGHUP", "SIGQUIT", "SIGUSR1", "SIGUSR2"}
+
+var relayableSignals = map[string]os.Signal{}
+
+func configureServerProcessGroup(_ *exec.Cmd, _ signalRelayConfig) {}
+
+func forwardSignalToServer(_ *exec.Cmd, _ os.Signal, _ signalRelayConfig) error {
+	return nil
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"io"
+	"log/slog"
+	"sync"
+)
+
+// stdinForwarder writes client input to whichever server process is currently
+// attached, so the client's stdin survives server restarts. Closing a server's
+// stdin during shutdown does not end forwarding; only client EOF does.
+type stdinForwarder struct {
+	onClose func()
+
+	mu     sync.Mutex
+	cond   *sync.Cond
+	dst    io.WriteCloser
+	closed bool
+}
+
+// newStdinForwarder returns a forwarder whose onClose, if set, runs once the
+// client closes its stdin.
+func newStdinForwarder(onClose func()) *stdinForwarder {
+	f := &stdinForwarder{onClose: onClose}
+	f.cond = sync.NewCond(&f.mu)
+	return f
+}
+
+// Write forwards p to the attached server. Input for a server that stopped
+// reading is dropped; it never fails, so one dead server cannot end the session.
+func (f *stdinForwarder) Write(p []byte) (int, error) {
+	f.mu.Lock()
+	// Hold input while a restarted server is being started.
+	for f.dst == nil && !f.closed {
+		f.cond.Wait()
+	}
+	dst := f.dst
+	f.mu.Unlock()
+
+	if dst == nil {
+		return len(p), nil
+	}
+
+	// Write outside the lock so detach can close a server that stopped reading.
+	if _, err := dst.Write(p); err != nil {
+		slog.Debug("Dropped client input for stopped github-mcp-server", "err", err)
+	}
+
+	return len(p), nil
+}
+
+// finish closes the attached server's stdin after client EOF.
+func (f *stdinForwarder) finish() {
+	f.mu.Lock()
+	f.closed = true
+	if f.dst != nil {
+		_ = f.dst.Close()
+	}
+	f.cond.Broadcast()
+	f.mu.Unlock()
+
+	if f.onClose != nil {
+		f.onClose()
+	}
+}
+
+// attach routes subsequent input to dst. If the client already closed its
+// stdin, dst is closed immediately so the server sees EOF.
+func (f *stdinForwarder) attach(dst io.WriteCloser) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.closed {
+		_ = dst.Close()
+		return
+	}
+
+	f.dst = dst
+	f.cond.Broadcast()
+}
+
+// detach stops routing input to dst if it is still attached.
+func (f *stdinForwarder) detach(dst io.WriteCloser) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.dst == dst {
+		f.dst = nil
+	}
+}
+
+// closerFunc adapts a function to io.Closer.
+type closerFunc func() error
+
+func (f closerFunc) Close() error {
+	return f()
+}
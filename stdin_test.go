@@ -0,0 +1,126 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"sync"
+	"testing"
+	"time"
+)
+
+// recordingWriteCloser captures writes and whether Close was called.
+type recordingWriteCloser struct {
+	mu     sync.Mutex
+	buf    bytes.Buffer
+	closed bool
+}
+
+func (w *recordingWriteCloser) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.closed {
+		return 0, io.ErrClosedPipe
+	}
+	return w.buf.Write(p)
+}
+
+func (w *recordingWriteCloser) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.closed = true
+	return nil
+}
+
+func (w *recordingWriteCloser) snapshot() (string, bool) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	return w.buf.String(), w.closed
+}
+
+func TestStdinForwarderClosesServerOnClientEOF(t *testing.T) {
+	closedCh := make(chan struct{})
+	forwarder := newStdinForwarder(func() {
+		close(closedCh)
+	})
+	proxy := newStdioProxy(forwarder, io.Discard, defaultProxyMaxMessageSize)
+
+	dst := &recordingWriteCloser{}
+	forwarder.attach(dst)
+	go proxy.serveClient(context.Background(), bytes.NewBufferString("hello\n"))
+
+	select {
+	case <-closedCh:
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected onClose to run after client EOF")
+	}
+
+	got, closed := dst.snapshot()
+	if got != "hello\n" {
+		t.Fatalf("forwarded input = %q, want %q", got, "hello\n")
+	}
+	if !closed {
+		t.Fatal("expected server stdin to be closed after client EOF")
+	}
+
+	// A server attached after the client closed its stdin sees EOF immediately.
+	restarted := &recordingWriteCloser{}
+	forwarder.attach(restarted)
+	if _, closed := restarted.snapshot(); !closed {
+		t.Fatal("expected restarted server stdin to be closed immediately")
+	}
+}
+
+func TestStdinForwarderHoldsInputUntilAttached(t *testing.T) {
+	src, srcWriter := io.Pipe()
+	forwarder := newStdinForwarder(nil)
+	proxy := newStdioProxy(forwarder, io.Discard, defaultProxyMaxMessageSize)
+	go proxy.serveClient(context.Background(), src)
+
+	first := &recordingWriteCloser{}
+	forwarder.attach(first)
+	if _, err := srcWriter.Write([]byte("one\n")); err != nil {
+		t.Fatalf("failed to write client input: %v", err)
+	}
+	waitForForwardedInput(t, first, "one\n", false)
+
+	// Simulate a restart: the old server is detached before its stdin closes.
+	forwarder.detach(first)
+	_ = first.Close()
+
+	written := make(chan struct{})
+	go func() {
+		_, _ = srcWriter.Write([]byte("two\n"))
+		close(written)
+	}()
+	<-written
+
+	second := &recordingWriteCloser{}
+	forwarder.attach(second)
+	_ = srcWriter.Close()
+
+	waitForForwardedInput(t, second, "two\n", true)
+
+	if got, _ := first.snapshot(); got != "one\n" {
+		t.Fatalf("first server received %q, want %q", got, "one\n")
+	}
+}
+
+func waitForForwardedInput(t *testing.T, w *recordingWriteCloser, want string, wantClosed bool) {
+	t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		got, closed := w.snapshot()
+		if got == want && closed == wantClosed {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("server received %q (closed=%v), want %q (closed=%v)", got, closed, want, wantClosed)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
@@ -0,0 +1,223 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"time"
+)
+
+// sessionTelemetry reports on one gh-mcp session through the metrics endpoint
+// and the OTLP trace exporter, either of which may be nil. Startup phases are
+// traced as children of a single startup span. A nil *sessionTelemetry
+// reports nothing.
+type sessionTelemetry struct {
+	metrics *sessionMetrics
+	spans   *spanExporter
+
+	startup        spanContext
+	startupStarted time.Time
+
+	mu            sync.Mutex
+	startupFailed string
+	startupEnded  bool
+}
+
+// startTelemetryFromEnv starts the telemetry configured through the
+// environment. The returned function ends the startup span if the session
+// never got that far and flushes what is left.
+func startTelemetryFromEnv(ctx context.Context) (*sessionTelemetry, func(), error) {
+	metrics, stopMetrics, err := startMetricsFromEnv(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	spans, stopSpans, err := startSpanExporterFromEnv()
+	if err != nil {
+		stopMetrics()
+		return nil, nil, err
+	}
+
+	t := newSessionTelemetry(metrics, spans)
+
+	return t, func() {
+		t.endStartup()
+		stopSpans()
+		stopMetrics()
+	}, nil
+}
+
+func newSessionTelemetry(metrics *sessionMetrics, spans *spanExporter) *sessionTelemetry {
+	return &sessionTelemetry{
+		metrics:        metrics,
+		spans:          spans,
+		startup:        spanContext{}.child(),
+		startupStarted: time.Now(),
+	}
+}
+
+// sessionMetrics returns the metrics, or nil when they are not served.
+func (t *sessionTelemetry) sessionMetrics() *sessionMetrics {
+	if t == nil {
+		return nil
+	}
+
+	return t.metrics
+}
+
+// phase starts timing a startup phase. The returned function records it with
+// the phase's outcome. Spawning the server completes startup.
+func (t *sessionTelemetry) phase(name string) func(error) {
+	started := time.Now()
+
+	return func(err error) {
+		if t == nil {
+			return
+		}
+
+		ended := time.Now()
+		rec := spanRecord{
+			name:       "gh-mcp " + name,
+			kind:       spanKindInternal,
+			parent:     t.startup,
+			start:      started,
+			end:        ended,
+			attributes: []otlpAttribute{stringAttribute("gh_mcp.startup.phase", name)},
+		}
+		if err != nil {
+			rec.failure = err.Error()
+			t.mu.Lock()
+			if t.startupFailed == "" {
+				t.startupFailed = name + " failed"
+			}
+			t.mu.Unlock()
+		} else {
+			t.metrics.observePhase(name, ended.Sub(started))
+		}
+		t.spans.record(t.startup.child(), rec)
+
+		if name == phaseSpawn {
+			t.endStartup()
+		}
+	}
+}
+
+// endStartup records the startup span the first time it is called.
+func (t *sessionTelemetry) endStartup() {
+	t.mu.Lock()
+	if t.startupEnded {
+		t.mu.Unlock()
+		return
+	}
+	t.startupEnded = true
+	failure := t.startupFailed
+	t.mu.Unlock()
+
+	t.spans.record(t.startup, spanRecord{
+		name:    "gh-mcp startup",
+		kind:    spanKindInternal,
+		start:   t.startupStarted,
+		end:     time.Now(),
+		failure: failure,
+	})
+}
+
+func (t *sessionTelemetry) serverRestarted() {
+	if t == nil {
+		return
+	}
+
+	t.metrics.serverRestarted()
+}
+
+// toolsListed records the tools a tools/list response offers the client, so
+// metrics can tell them from names the client made up.
+func (t *sessionTelemetry) toolsListed(resp *rpcMessage) {
+	if t == nil {
+		return
+	}
+
+	var result struct {
+		Tools []struct {
+			Name string `json:"name"`
+		} `json:"tools"`
+	}
+	if err := json.Unmarshal(resp.Result, &result); err != nil {
+		return
+	}
+	names := make([]string, 0, len(result.Tools))
+	for _, tool := range result.Tools {
+		names = append(names, tool.Name)
+	}
+	t.metrics.listTools(names)
+}
+
+// observeToolCall counts an answered tool call and traces its round trip
+// through gh-mcp, under the client's trace when the request carries one.
+func (t *sessionTelemetry) observeToolCall(req *rpcMessage, tool string, resp *rpcMessage, started time.Time) {
+	if t == nil {
+		return
+	}
+
+	ended := time.Now()
+	outcome, code := toolCallOutcome(resp)
+	t.metrics.observeToolCall(tool, outcome, ended.Sub(started))
+
+	if t.spans == nil {
+		return
+	}
+	parent := traceContextFromParams(req.Params)
+	rec := spanRecord{
+		name:   methodToolsCall + " " + tool,
+		kind:   spanKindServer,
+		parent: parent,
+		start:  started,
+		end:    ended,
+		attributes: []otlpAttribute{
+			stringAttribute("mcp.method.name", methodToolsCall),
+			stringAttribute("gen_ai.tool.name", tool),
+			stringAttribute("jsonrpc.request.id", string(req.ID)),
+			stringAttribute("gh_mcp.outcome", outcome),
+		},
+	}
+	if code != 0 {
+		rec.attributes = append(rec.attributes, intAttribute("rpc.jsonrpc.error_code", int64(code)))
+	}
+	if outcome != auditStatusOK {
+		rec.failure = outcome
+	}
+	t.spans.record(parent.child(), rec)
+}
+
+// traceContextFromParams reads the W3C trace context a client propagates in
+// the request's _meta, as MCP clients do for distributed tracing.
+func traceContextFromParams(params json.RawMessage) spanContext {
+	var fields struct {
+		Meta struct {
+			Traceparent string `json:"traceparent"`
+			Tracestate  string `json:"tracestate"`
+		} `json:"_meta"`
+	}
+	if json.Unmarshal(params, &fields) != nil {
+		return spanContext{}
+	}
+	sc, _ := parseTraceparent(fields.Meta.Traceparent, fields.Meta.Tracestate)
+
+	return sc
+}
+
+// toolCallTelemetry reports tool calls as the client receives their answers.
+type toolCallTelemetry struct {
+	passthroughMiddleware
+
+	telemetry *sessionTelemetry
+}
+
+func (r *toolCallTelemetry) observeAnswer(_ context.Context, resp *rpcMessage) {
+	if isToolListResponse(resp) {
+		r.telemetry.toolsListed(resp)
+		return
+	}
+	if call, ok := resp.call.request.toolCall(); ok {
+		r.telemetry.observeToolCall(resp.call.request, call.Name, resp, resp.call.started)
+	}
+}
@@ -0,0 +1,191 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+const (
+	policyTimeout = "timeout"
+
+	// rpcCodeRequestTimeout is returned when gh-mcp gives up waiting for a tool call.
+	rpcCodeRequestTimeout = -32003
+)
+
+// toolTimeoutConfig sets deadlines for tool calls, counted from when they
+// reach the server.
+type toolTimeoutConfig struct {
+	// Default, like "2m", applies to tools without a matching rule. Empty or "0s" means none.
+	Default string `json:"default"`
+	// Tools apply to tool names matching their pattern; the first match wins.
+	Tools []toolTimeoutRule `json:"tools"`
+}
+
+type toolTimeoutRule struct {
+	Name    string `json:"name"`
+	Timeout string `json:"timeout"`
+}
+
+func (c toolTimeoutConfig) empty() bool {
+	// Validated when the config was loaded.
+	fallback, _ := parseTimeoutSetting("", c.Default)
+	return fallback == 0 && len(c.Tools) == 0
+}
+
+func (c toolTimeoutConfig) validate() error {
+	if _, err := parseTimeoutSetting("timeouts.default", c.Default); err != nil {
+		return err
+	}
+	for i, rule := range c.Tools {
+		field := fmt.Sprintf("timeouts.tools[%d]", i)
+		if err := validateGlobs(field+".name", []string{rule.Name}); err != nil {
+			return err
+		}
+		if _, err := parseTimeoutSetting(field+".timeout", rule.Timeout); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func parseTimeoutSetting(field, value string) (time.Duration, error) {
+	if value == "" {
+		return 0, nil
+	}
+
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", field, err)
+	}
+	if d < 0 {
+		return 0, fmt.Errorf("%s: %q must not be negative", field, value)
+	}
+
+	return d, nil
+}
+
+// cancelledParams are the params of notifications/cancelled.
+type cancelledParams struct {
+	RequestID json.RawMessage `json:"requestId"`
+	Reason    string          `json:"reason,omitempty"`
+}
+
+// toolTimeouts answers tool calls the server takes too long with, telling the
+// server to cancel them. The server's late response is dropped.
+type toolTimeouts struct {
+	fallback time.Duration
+	rules    []toolTimeoutRule
+
+	mu     sync.Mutex
+	timers map[*pendingCall]*time.Timer
+}
+
+// newToolTimeouts returns nil when no tool has a deadline.
+func newToolTimeouts(cfg toolTimeoutConfig) *toolTimeouts {
+	if cfg.empty() {
+		return nil
+	}
+	// Validated when the config was loaded.
+	fallback, _ := parseTimeoutSetting("", cfg.Default)
+
+	return &toolTimeouts{
+		fallback: fallback,
+		rules:    cfg.Tools,
+		timers:   make(map[*pendingCall]*time.Timer),
+	}
+}
+
+func (t *toolTimeouts) timeoutFor(tool string) time.Duration {
+	for _, rule := range t.rules {
+		if matchesAnyGlob([]string{rule.Name}, tool) {
+			d, _ := parseTimeoutSetting("", rule.Timeout)
+			return d
+		}
+	}
+
+	return t.fallback
+}
+
+func (t *toolTimeouts) clientToServer(p *stdioProxy, next proxyHandler) proxyHandler {
+	return func(ctx context.Context, msg *rpcMessage) error {
+		call, ok := msg.toolCall()
+		if !ok {
+			return next(ctx, msg)
+		}
+		timeout := t.timeoutFor(call.Name)
+		if timeout == 0 {
+			return next(ctx, msg)
+		}
+
+		if err := next(ctx, msg); err != nil {
+			return err
+		}
+
+		pending := msg.call
+		timer := time.AfterFunc(timeout, func() {
+			t.expire(ctx, p, pending, call.Name, timeout)
+		})
+
+		t.mu.Lock()
+		t.timers[pending] = timer
+		t.mu.Unlock()
+
+		return nil
+	}
+}
+
+func (t *toolTimeouts) serverToClient(_ *stdioProxy, next proxyHandler) proxyHandler {
+	return func(ctx context.Context, msg *rpcMessage) error {
+		if msg.isResponse() && msg.call != nil {
+			t.mu.Lock()
+			if timer, ok := t.timers[msg.call]; ok {
+				timer.Stop()
+				delete(t.timers, msg.call)
+			}
+			t.mu.Unlock()
+		}
+
+		return next(ctx, msg)
+	}
+}
+
+func (t *toolTimeouts) expire(ctx context.Context, p *stdioProxy, call *pendingCall, tool string, timeout time.Duration) {
+	t.mu.Lock()
+	delete(t.timers, call)
+	t.mu.Unlock()
+
+	if call.answered() || call.withdrawn.Load() {
+		return
+	}
+
+	reason := fmt.Sprintf("no response from github-mcp-server within %s", timeout)
+	resp, err := newRPCErrorResponse(call.request.ID, rpcCodeRequestTimeout, "tool call "+tool+" timed out",
+		policyDenial{Policy: policyTimeout, Tool: tool, Reason: reason})
+	if err != nil {
+		slog.DebugContext(ctx, "Failed to answer timed-out tool call", "tool", tool, "err", err)
+		return
+	}
+	resp.call = call
+	if err := p.toClient(ctx, resp); err != nil {
+		slog.DebugContext(ctx, "Failed to answer timed-out tool call", "tool", tool, "err", err)
+	}
+	// The server's response may have settled the call first.
+	if call.response.Load() != resp {
+		return
+	}
+	slog.WarnContext(ctx, "Tool call timed out", "tool", tool, "timeout", timeout)
+
+	// Calls routed to an additional server are cancelled through the call.
+	if call.forwarded.Load() {
+		err = p.notifyServer(methodCancelled, cancelledParams{RequestID: call.request.ID, Reason: reason})
+		if err != nil {
+			slog.DebugContext(ctx, "Failed to cancel timed-out tool call", "tool", tool, "err", err)
+		}
+	}
+	call.cancel()
+}
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestToolTimeoutsAnswerAndCancelSlowCalls(t *testing.T) {
+	timeouts := newToolTimeouts(toolTimeoutConfig{
+		Default: "1h",
+		Tools:   []toolTimeoutRule{{Name: "search_*", Timeout: "20ms"}},
+	})
+	proxy, server, client := newTestProxy(t, defaultProxyMaxMessageSize, timeouts)
+
+	ctx := context.Background()
+	proxy.handleClientLine(ctx, testToolCallLine(7, "search_code"))
+
+	got := waitForString(t, client.String, `"code":-32003`)
+	var resp rpcMessage
+	if err := json.Unmarshal([]byte(got), &resp); err != nil || string(resp.ID) != "7" {
+		t.Fatalf("client received unexpected timeout response %q: %v", got, err)
+	}
+	waitForString(t, serverInput(server), `"method":"notifications/cancelled","params":{"requestId":7`)
+
+	// The server's late answer must not reach the client a second time.
+	if err := proxy.handleServerLine(ctx, []byte(`{"jsonrpc":"2.0","id":7,"result":{}}`)); err != nil {
+		t.Fatalf("handleServerLine failed: %v", err)
+	}
+	if got := client.String(); strings.Count(got, "\n") != 1 {
+		t.Fatalf("client received %q, want only the timeout error", got)
+	}
+}
+
+func TestToolTimeoutsDoNotCancelAggregatedCallsOnServer(t *testing.T) {
+	timeouts := newToolTimeouts(toolTimeoutConfig{Default: "20ms"})
+	proxy, server, client := newTestProxy(t, defaultProxyMaxMessageSize, timeouts, newTestServerAggregator(t))
+
+	// The additional server is never initialized, so the call waits for it.
+	proxy.handleClientLine(context.Background(), testToolCallLine(1, "helper__echo"))
+	waitForString(t, client.String, `"code":-32003`)
+
+	time.Sleep(50 * time.Millisecond)
+	if got, _ := server.snapshot(); got != "" {
+		t.Fatalf("bundled server received %q for a call it was never sent", got)
+	}
+}
+
+func TestToolTimeoutsLeaveTimelyCallsAlone(t *testing.T) {
+	timeouts := newToolTimeouts(toolTimeoutConfig{Default: "50ms"})
+	proxy, server, client := newTestProxy(t, defaultProxyMaxMessageSize, timeouts)
+
+	ctx := context.Background()
+	proxy.handleClientLine(ctx, testToolCallLine(1, "get_issue"))
+	response := `{"jsonrpc":"2.0","id":1,"result":{}}`
+	if err := proxy.handleServerLine(ctx, []byte(response)); err != nil {
+		t.Fatalf("handleServerLine failed: %v", err)
+	}
+
+	time.Sleep(100 * time.Millisecond)
+	if got := client.String(); got != response {
+		t.Fatalf("client received %q, want only the server response", got)
+	}
+	if got, _ := server.snapshot(); strings.Contains(got, methodCancelled) {
+		t.Fatalf("server received a cancellation for a timely call: %q", got)
+	}
+}
+
+func TestStdioProxyDropsResponsesToCancelledRequests(t *testing.T) {
+	observer := &observingMiddleware{}
+	proxy, server, client := newTestProxy(t, defaultProxyMaxMessageSize, observer)
+
+	ctx := context.Background()
+	cancel := `{"jsonrpc":"2.0","method":"notifications/cancelled","params":{"requestId":1,"reason":"user"}}`
+	proxy.handleClientLine(ctx, testToolCallLine(1, "get_issue"))
+	proxy.handleClientLine(ctx, []byte(cancel))
+
+	if got, _ := server.snapshot(); !strings.HasSuffix(got, cancel) {
+		t.Fatalf("server received %q, want the cancellation forwarded", got)
+	}
+
+	if err := proxy.handleServerLine(ctx, []byte(`{"jsonrpc":"2.0","id":1,"result":{}}`)); err != nil {
+		t.Fatalf("handleServerLine failed: %v", err)
+	}
+	if got := client.String(); got != "" {
+		t.Fatalf("client received %q for a cancelled request", got)
+	}
+}
+
+func TestRateLimiterDropsCancelledHeldCalls(t *testing.T) {
+	limiter := newRateLimiter(rateLimitConfig{Global: rateLimitRule{MaxInFlight: 1}})
+	proxy, server, client := newTestProxy(t, defaultProxyMaxMessageSize, limiter)
+
+	ctx := context.Background()
+	proxy.handleClientLine(ctx, testToolCallLine(1, "get_issue"))
+	proxy.handleClientLine(ctx, testToolCallLine(2, "get_me"))
+	proxy.handleClientLine(ctx, []byte(`{"jsonrpc":"2.0","method":"notifications/cancelled","params":{"requestId":2}}`))
+
+	if err := proxy.handleServerLine(ctx, []byte(`{"jsonrpc":"2.0","id":1,"result":{}}`)); err != nil {
+		t.Fatalf("handleServerLine failed: %v", err)
+	}
+
+	time.Sleep(50 * time.Millisecond)
+	if got, _ := server.snapshot(); strings.Contains(got, `"get_me"`) {
+		t.Fatalf("cancelled call reached the server: %q", got)
+	}
+	if got := client.String(); strings.Contains(got, `"id":2`) {
+		t.Fatalf("client received a response to its cancelled call: %q", got)
+	}
+}
+
+func TestParseProxyConfigTimeouts(t *testing.T) {
+	if _, err := parseProxyConfig([]byte(`{"timeouts":{"default":"2m","tools":[{"name":"search_*","timeout":"30s"}]}}`)); err != nil {
+		t.Fatalf("parseProxyConfig failed: %v", err)
+	}
+
+	for _, data := range []string{
+		`{"timeouts":{"default":"forever"}}`,
+		`{"timeouts":{"default":"-1s"}}`,
+		`{"timeouts":{"tools":[{"name":"[","timeout":"1s"}]}}`,
+	} {
+		if _, err := parseProxyConfig([]byte(data)); err == nil {
+			t.Fatalf("parseProxyConfig(%s) succeeded, want error", data)
+		}
+	}
+}
@@ -0,0 +1,144 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+)
+
+const (
+	methodToolsList = "tools/list"
+	methodToolsCall = "tools/call"
+
+	// rpcCodePolicyDenied is returned when a gh-mcp policy refuses a request.
+	rpcCodePolicyDenied = -32001
+
+	policyTools = "tools"
+)
+
+// toolCallParams is the part of tools/call params the proxy inspects.
+type toolCallParams struct {
+	Name      string          `json:"name"`
+	Arguments json.RawMessage `json:"arguments,omitempty"`
+}
+
+// policyDenial is the data of a JSON-RPC error for a refused request.
+type policyDenial struct {
+	Policy string `json:"policy"`
+	Tool   string `json:"tool,omitempty"`
+	Owner  string `json:"owner,omitempty"`
+	Repo   string `json:"repo,omitempty"`
+	Reason string `json:"reason"`
+	// RetryAfterMS hints when a refused call may succeed.
+	RetryAfterMS int64 `json:"retryAfterMs,omitempty"`
+}
+
+// toolCall returns the params of a tools/call request.
+func (m *rpcMessage) toolCall() (toolCallParams, bool) {
+	if !m.isRequest() || m.Method != methodToolsCall {
+		return toolCallParams{}, false
+	}
+
+	var params toolCallParams
+	if err := json.Unmarshal(m.Params, &params); err != nil {
+		return toolCallParams{}, false
+	}
+
+	return params, true
+}
+
+// rewriteToolList applies keep to each tool of a tools/list result. keep may
+// modify the tool in place; tools it rejects are removed. Other result fields
+// are preserved.
+func rewriteToolList(msg *rpcMessage, keep func(tool map[string]json.RawMessage) bool) error {
+	var result map[string]json.RawMessage
+	if err := json.Unmarshal(msg.Result, &result); err != nil {
+		return fmt.Errorf("failed to decode tools/list result: %w", err)
+	}
+
+	var tools []map[string]json.RawMessage
+	if err := json.Unmarshal(result["tools"], &tools); err != nil {
+		return fmt.Errorf("failed to decode tools/list result: %w", err)
+	}
+
+	kept := make([]map[string]json.RawMessage, 0, len(tools))
+	for _, tool := range tools {
+		if keep(tool) {
+			kept = append(kept, tool)
+		}
+	}
+
+	encoded, err := json.Marshal(kept)
+	if err != nil {
+		return fmt.Errorf("failed to encode tools/list result: %w", err)
+	}
+	result["tools"] = encoded
+
+	return msg.setResult(result)
+}
+
+// toolName returns the name field of a tool definition.
+func toolName(tool map[string]json.RawMessage) string {
+	var name string
+	_ = json.Unmarshal(tool["name"], &name)
+	return name
+}
+
+// isToolListResponse reports whether msg answers a client's tools/list request.
+func isToolListResponse(msg *rpcMessage) bool {
+	return msg.isResponse() && msg.Error == nil && msg.call != nil &&
+		msg.call.request.Method == methodToolsList
+}
+
+// toolFilter enforces the tools allow and deny lists. Disallowed tools are
+// removed from tools/list and calls to them are refused.
+type toolFilter struct {
+	policy globListConfig
+}
+
+// newToolFilter returns nil when the policy allows every tool.
+func newToolFilter(policy globListConfig) *toolFilter {
+	if policy.empty() {
+		return nil
+	}
+
+	return &toolFilter{policy: policy}
+}
+
+func (f *toolFilter) allowed(name string) bool {
+	return f.policy.permits(name)
+}
+
+func (f *toolFilter) clientToServer(p *stdioProxy, next proxyHandler) proxyHandler {
+	return func(ctx context.Context, msg *rpcMessage) error {
+		call, ok := msg.toolCall()
+		if !ok || f.allowed(call.Name) {
+			return next(ctx, msg)
+		}
+
+		slog.InfoContext(ctx, "Refused tool call", "tool", call.Name, "policy", policyTools)
+		return p.replyError(ctx, msg, rpcCodePolicyDenied,
+			"tool "+call.Name+" is not allowed by gh-mcp policy",
+			policyDenial{Policy: policyTools, Tool: call.Name, Reason: "tool is not in the allowed set"})
+	}
+}
+
+func (f *toolFilter) serverToClient(p *stdioProxy, next proxyHandler) proxyHandler {
+	return func(ctx context.Context, msg *rpcMessage) error {
+		if !isToolListResponse(msg) {
+			return next(ctx, msg)
+		}
+
+		err := rewriteToolList(msg, func(tool map[string]json.RawMessage) bool {
+			return f.allowed(toolName(tool))
+		})
+		if err != nil {
+			// Fail closed rather than leak tools the policy hides.
+			slog.WarnContext(ctx, "Failed to filter tools/list", "err", err)
+			return p.replyError(ctx, msg.call.request, rpcCodeInternalError, "gh-mcp could not filter tools/list", nil)
+		}
+
+		return next(ctx, msg)
+	}
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+const testToolList = `{"jsonrpc":"2.0","id":1,"result":{"nextCursor":"c","tools":[` +
+	`{"name":"get_issue","description":"d"},{"name":"get_secret"},{"name":"delete_repo"}]}}` + "\n"
+
+func TestToolFilterHidesDisallowedTools(t *testing.T) {
+	filter := newToolFilter(globListConfig{Allow: []string{"get_*"}, Deny: []string{"get_secret"}})
+	proxy, _, client := newTestProxy(t, defaultProxyMaxMessageSize, filter)
+
+	proxy.serveClient(context.Background(), strings.NewReader(
+		`{"jsonrpc":"2.0","id":1,"method":"tools/list"}`+"\n",
+	))
+	proxy.serveServer(context.Background(), strings.NewReader(testToolList))
+
+	var resp struct {
+		Result struct {
+			NextCursor string `json:"nextCursor"`
+			Tools      []struct {
+				Name        string `json:"name"`
+				Description string `json:"description"`
+			} `json:"tools"`
+		} `json:"result"`
+	}
+	if err := json.Unmarshal([]byte(client.String()), &resp); err != nil {
+		t.Fatalf("client received invalid response %q: %v", client.String(), err)
+	}
+	if len(resp.Result.Tools) != 1 || resp.Result.Tools[0].Name != "get_issue" ||
+		resp.Result.Tools[0].Description != "d" {
+		t.Fatalf("unexpected filtered tools: %+v", resp.Result.Tools)
+	}
+	if resp.Result.NextCursor != "c" {
+		t.Fatalf("nextCursor = %q, want it preserved", resp.Result.NextCursor)
+	}
+}
+
+func TestToolFilterRefusesDisallowedCalls(t *testing.T) {
+	filter := newToolFilter(globListConfig{Deny: []string{"delete_*"}})
+	proxy, server, client := newTestProxy(t, defaultProxyMaxMessageSize, filter)
+
+	allowed := `{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"get_issue"}}` + "\n"
+	denied := `{"jsonrpc":"2.0","id":2,"method":"tools/call","params":{"name":"delete_repo"}}` + "\n"
+	proxy.serveClient(context.Background(), strings.NewReader(allowed+denied))
+
+	if got, _ := server.snapshot(); got != allowed {
+		t.Fatalf("server received %q, want only the allowed call", got)
+	}
+
+	var resp rpcMessage
+	if err := json.Unmarshal([]byte(client.String()), &resp); err != nil {
+		t.Fatalf("client received invalid response %q: %v", client.String(), err)
+	}
+	if string(resp.ID) != "2" || resp.Error == nil || resp.Error.Code != rpcCodePolicyDenied {
+		t.Fatalf("unexpected denial: %q", client.String())
+	}
+
+	var denial policyDenial
+	if err := json.Unmarshal(resp.Error.Data, &denial); err != nil || denial.Tool != "delete_repo" {
+		t.Fatalf("unexpected denial data: %s", resp.Error.Data)
+	}
+}
+
+func TestNewToolFilterWithoutPatterns(t *testing.T) {
+	if newToolFilter(globListConfig{}) != nil {
+		t.Fatal("expected no filter when no patterns are configured")
+	}
+}
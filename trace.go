@@ -0,0 +1,142 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	traceEnv = "GH_MCP_TRACE"
+	// traceFDPrefix selects an inherited file descriptor, as in "fd:3".
+	traceFDPrefix = "fd:"
+
+	traceFromClient = "client->gh-mcp"
+	traceToServer   = "gh-mcp->server"
+	traceFromServer = "server->gh-mcp"
+	traceToClient   = "gh-mcp->client"
+)
+
+// protocolTracer writes a readable copy of every framed message the proxy
+// reads or writes. The token and known secret formats are redacted. Tracing
+// never alters or fails the proxied streams. A nil tracer traces nothing.
+type protocolTracer struct {
+	token string
+
+	mu sync.Mutex
+	w  io.Writer
+	// closer is nil for inherited file descriptors.
+	closer io.Closer
+	// streamed counts the bytes of oversized lines in progress per direction.
+	streamed map[string]int
+	failed   bool
+}
+
+// newProtocolTracer opens a GH_MCP_TRACE destination: a file path, appended
+// to, or "fd:N" for an inherited file descriptor, which is left open.
+func newProtocolTracer(dest, token string) (*protocolTracer, error) {
+	t := &protocolTracer{token: token, streamed: make(map[string]int)}
+
+	if fdValue, ok := strings.CutPrefix(dest, traceFDPrefix); ok {
+		fd, err := strconv.ParseUint(fdValue, 10, 0)
+		// Stdin and stdout carry the protocol.
+		if err != nil || fd < 2 {
+			return nil, fmt.Errorf("%w: %s=%q is not a usable file descriptor", errInvalidSetting, traceEnv, dest)
+		}
+		if fd == 2 {
+			t.w = os.Stderr
+		} else {
+			t.w = os.NewFile(uintptr(fd), traceEnv)
+		}
+		return t, nil
+	}
+
+	// #nosec G304 -- the trace path is chosen by the user running gh-mcp
+	file, err := os.OpenFile(dest, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s=%q: %w", errInvalidSetting, traceEnv, dest, err)
+	}
+	t.w = file
+	t.closer = file
+
+	return t, nil
+}
+
+// line traces one complete frame.
+func (t *protocolTracer) line(direction string, data []byte) {
+	if t == nil {
+		return
+	}
+
+	t.write(direction, len(data), t.format(data))
+}
+
+// chunk traces a frame too long to buffer, once its last chunk has passed.
+// Its content is not traced.
+func (t *protocolTracer) chunk(direction string, data []byte, last bool) {
+	if t == nil {
+		return
+	}
+
+	t.mu.Lock()
+	t.streamed[direction] += len(data)
+	size := t.streamed[direction]
+	if last {
+		delete(t.streamed, direction)
+	}
+	t.mu.Unlock()
+
+	if last {
+		t.write(direction, size, "(streamed message, content not traced)\n")
+	}
+}
+
+// format pretty-prints a JSON frame and quotes anything else.
+func (t *protocolTracer) format(data []byte) string {
+	text := string(bytes.TrimRight(data, "\r\n"))
+	if t.token != "" {
+		text = strings.ReplaceAll(text, t.token, "[REDACTED:token]")
+	}
+	text = builtinSecretScanner.redactString(text, nil)
+
+	var pretty bytes.Buffer
+	if err := json.Indent(&pretty, []byte(text), "", "  "); err != nil {
+		return strconv.Quote(text) + "\n"
+	}
+
+	return pretty.String() + "\n"
+}
+
+func (t *protocolTracer) write(direction string, size int, body string) {
+	entry := fmt.Sprintf("--- %s %s %d bytes\n%s",
+		time.Now().UTC().Format(time.RFC3339Nano), direction, size, body)
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if _, err := io.WriteString(t.w, entry); err != nil && !t.failed {
+		// Report once; the session goes on without a complete trace.
+		t.failed = true
+		slog.Warn("Failed to write protocol trace", "err", err)
+	}
+}
+
+func (t *protocolTracer) close() {
+	if t == nil {
+		return
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.closer != nil {
+		_ = t.closer.Close()
+	}
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestProtocolTracerTracesWithoutAlteringStreams(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "trace.log")
+	token := "gho_" + strings.Repeat("t", 36)
+	tracer, err := newProtocolTracer(path, token)
+	if err != nil {
+		t.Fatalf("newProtocolTracer failed: %v", err)
+	}
+	proxy, server, client := newTestProxy(t, 120)
+	proxy.observe(tracer, nil)
+
+	clientInput := `{"jsonrpc":"2.0","id":1,"method":"ping","params":{"auth":"` + token + `"}}` + "\n" +
+		"not json\n" +
+		`{"jsonrpc":"2.0","id":2,"method":"tools/call","params":{"name":"` + strings.Repeat("x", 64) + `"}}` + "\n"
+	proxy.serveClient(context.Background(), strings.NewReader(clientInput))
+	serverOutput := `{"jsonrpc":"2.0","id":1,"result":{}}` + "\n"
+	proxy.serveServer(context.Background(), strings.NewReader(serverOutput))
+	tracer.close()
+
+	if got, _ := server.snapshot(); got != clientInput {
+		t.Fatalf("server received %q, want %q", got, clientInput)
+	}
+	if got := client.String(); got != serverOutput {
+		t.Fatalf("client received %q, want %q", got, serverOutput)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("ReadFile failed: %v", err)
+	}
+	trace := string(data)
+	if strings.Contains(trace, token) || !strings.Contains(trace, "[REDACTED:token]") {
+		t.Fatalf("trace does not redact the token:\n%s", trace)
+	}
+	for _, want := range []string{
+		" client->gh-mcp 102 bytes\n{\n  \"jsonrpc\": \"2.0\",\n",
+		" gh-mcp->server 9 bytes\n\"not json\"\n",
+		" gh-mcp->server 132 bytes\n(streamed message, content not traced)\n",
+		" server->gh-mcp 37 bytes\n",
+		" gh-mcp->client 37 bytes\n",
+	} {
+		if !strings.Contains(trace, want) {
+			t.Fatalf("trace is missing %q:\n%s", want, trace)
+		}
+	}
+}
+
+func TestNewProtocolTracerRejectsProtocolDescriptors(t *testing.T) {
+	for _, dest := range []string{"fd:0", "fd:1", "fd:x"} {
+		if _, err := newProtocolTracer(dest, ""); !errors.Is(err, errInvalidSetting) {
+			t.Fatalf("newProtocolTracer(%q) error = %v, want errInvalidSetting", dest, err)
+		}
+	}
+}